@@ -19,13 +19,17 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net"
 	"os"
+	"path/filepath"
 
 	"huawei.com/npu-exporter/v5/common-utils/hwlog"
 	"huawei.com/npu-exporter/v5/devmanager"
 
+	"Ascend-device-plugin/pkg/admin"
 	"Ascend-device-plugin/pkg/common"
 	"Ascend-device-plugin/pkg/server"
+	"Ascend-device-plugin/pkg/simulator"
 )
 
 const (
@@ -73,11 +77,39 @@ var (
 		"computing power splitting function, only support Ascend910 and Ascend310P")
 	use310PMixedInsert = flag.Bool("use310PMixedInsert", false, "Whether to use mixed insert "+
 		"ascend310P-V, ascend310P-VPro, ascend310P-IPro card mode")
-	hotReset      = flag.Int("hotReset", -1, "set hot reset mode: -1-close, 0-infer, 1-train")
+	hotReset         = flag.Int("hotReset", -1, "set hot reset mode: -1-close, 0-infer, 1-train")
+	resetBudgetTimes = flag.Int("resetBudgetTimes", common.DefaultResetBudgetTimes, "The failed hot reset "+
+		"times of a chip in resetBudgetWindow before it is escalated to ManuallySeparateNPU, range [1, 10]")
+	resetBudgetWindow = flag.Int64("resetBudgetWindow", common.DefaultResetBudgetWindow, "The window in "+
+		"which the failed hot reset of a chip is counted, unit second, range [60, 86400]")
+	hotResetDryRun = flag.Bool("hotResetDryRun", false, "Whether to only plan the grace tolerance of training "+
+		"task without resetting chip or writing the configmap of task, the plan is logged and dumped by the debug "+
+		"http server (default false)")
+	writeGracePlanCM = flag.Bool("writeGracePlanCM", false, "Whether to write the grace tolerance plan of "+
+		"hotResetDryRun into configmap "+common.GracePlanCMNamePrefix+"<nodeName> (default false)")
 	shareDevCount = flag.Uint("shareDevCount", 1, "share device function, enable the func by setting "+
 		"a value greater than 1, range is [1, 100], only support 310B")
 	linkdownTimeout = flag.Int64("linkdownTimeout", defaultLinkdownTimeout, "linkdown timeout duration, "+
 		", range [1, 30]")
+	useCDI = flag.Bool("cdi", false, "Whether to write cdi spec and return cdi device references when "+
+		"allocate, the container runtime should support cdi (default false)")
+	cdiSpecDir = flag.String("cdiSpecDir", common.DefaultCDISpecDir, "The dir of cdi spec file")
+	sysfsRoot  = flag.String("sysfsRoot", common.DefaultSysfsRoot, "The root path of sysfs, used to find "+
+		"the numa node of device")
+	httpAddr = flag.String("httpAddr", "", "The listen address of http server which serves /metrics, "+
+		"/healthz and /readyz, such as :8080, empty means the http server is disabled (default empty)")
+	debugAddr = flag.String("debugAddr", "", "The loopback listen address of debug http server which dumps "+
+		"the internal caches, such as 127.0.0.1:8081, empty means the debug server is disabled (default empty)")
+	adminSocket = flag.String("adminSocket", common.DefaultAdminSocketPath, "The path of admin unix socket "+
+		"which only root can access, used by the admin sub command, empty means the admin api is disabled")
+	faultHistoryFile = flag.String("faultHistoryFile", common.DefaultFaultHistoryFile, "The file of fault "+
+		"history which keeps the latest fault raise and recover events, empty means fault history is disabled")
+	enablePprof = flag.Bool("enablePprof", false, "Whether to serve pprof on the debug http server "+
+		"(default false)")
+	simulate = flag.String("simulate", "", "The scenario yaml file of simulated npu, the plugin runs on "+
+		"simulated chips instead of dcmi when it is set, only used for test (default empty)")
+	separateTaintNum = flag.Int("separateTaintNum", 0, "Add NoSchedule taint to node when the number of "+
+		"SeparateNPU and ManuallySeparateNPU chips reaches it, range [0, 100], 0 means never taint (default 0)")
 )
 
 var (
@@ -143,6 +175,19 @@ func checkParam() bool {
 		hwlog.RunLog.Error("hot reset mode param invalid")
 		return false
 	}
+	if *resetBudgetTimes < 1 || *resetBudgetTimes > common.MaxResetBudgetTimes ||
+		*resetBudgetWindow < common.MinResetBudgetWindow || *resetBudgetWindow > common.MaxResetBudgetWindow {
+		hwlog.RunLog.Error("reset budget times or window out of range")
+		return false
+	}
+	if *hotResetDryRun && *hotReset == common.HotResetInfer {
+		hwlog.RunLog.Error("hotResetDryRun only support train hot reset, hotReset should not be 0")
+		return false
+	}
+	if *writeGracePlanCM && !*hotResetDryRun {
+		hwlog.RunLog.Error("writeGracePlanCM should be used with hotResetDryRun")
+		return false
+	}
 	if BuildScene != common.EdgeScene && BuildScene != common.CenterScene {
 		hwlog.RunLog.Error("unSupport build scene, only support edge and center")
 		return false
@@ -152,10 +197,59 @@ func checkParam() bool {
 		hwlog.RunLog.Warn("linkdown timeout duration out of range")
 		return false
 	}
+	if !filepath.IsAbs(*sysfsRoot) {
+		hwlog.RunLog.Error("sysfs root should be an absolute path")
+		return false
+	}
+	if *useCDI && !filepath.IsAbs(*cdiSpecDir) {
+		hwlog.RunLog.Error("cdi spec dir should be an absolute path")
+		return false
+	}
+	if !checkHTTPParam() {
+		return false
+	}
+	if *separateTaintNum < 0 || *separateTaintNum > common.MaxDevicesNum {
+		hwlog.RunLog.Error("separate taint num out of range")
+		return false
+	}
 
 	return checkShareDevCount()
 }
 
+func checkHTTPParam() bool {
+	if *httpAddr != "" {
+		if _, _, err := net.SplitHostPort(*httpAddr); err != nil {
+			hwlog.RunLog.Errorf("http listen address is invalid, %v", err)
+			return false
+		}
+	}
+	if *faultHistoryFile != "" && !filepath.IsAbs(*faultHistoryFile) {
+		hwlog.RunLog.Error("fault history file should be an absolute path")
+		return false
+	}
+	if *simulate != "" && !filepath.IsAbs(*simulate) {
+		hwlog.RunLog.Error("simulate scenario file should be an absolute path")
+		return false
+	}
+	if *adminSocket != "" && !filepath.IsAbs(*adminSocket) {
+		hwlog.RunLog.Error("admin socket should be an absolute path")
+		return false
+	}
+	if *debugAddr == "" {
+		return true
+	}
+	host, _, err := net.SplitHostPort(*debugAddr)
+	if err != nil {
+		hwlog.RunLog.Errorf("debug listen address is invalid, %v", err)
+		return false
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		hwlog.RunLog.Error("debug listen address should be a loopback address")
+		return false
+	}
+	return true
+}
+
 func checkShareDevCount() bool {
 	if *shareDevCount < 1 || *shareDevCount > common.MaxShareDevCount {
 		hwlog.RunLog.Error("share device function params invalid")
@@ -165,6 +259,9 @@ func checkShareDevCount() bool {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == common.AdminSubCommand {
+		os.Exit(admin.Run(os.Args[2:], os.Stdout, os.Stderr))
+	}
 	flag.Parse()
 	if *version {
 		fmt.Printf("%s version: %s\n", BuildName, BuildVersion)
@@ -180,18 +277,30 @@ func main() {
 	hwlog.RunLog.Infof("ascend device plugin starting and the version is %s", BuildVersion)
 	hwlog.RunLog.Infof("ascend device plugin starting scene is %s", BuildScene)
 	setParameters()
+	if err := common.LoadFaultHistory(); err != nil {
+		hwlog.RunLog.Warnf("load fault history failed, the history before restart is lost, %v", err)
+	}
 	hdm, err := InitFunction()
 	if err != nil {
 		return
 	}
 	setUseAscendDocker()
 	go hdm.ListenDevice(ctx)
+	if *httpAddr != "" {
+		go hdm.StartHTTPServer(ctx, *httpAddr)
+	}
+	if *debugAddr != "" {
+		go hdm.StartDebugServer(ctx, *debugAddr, *enablePprof)
+	}
+	if *adminSocket != "" {
+		go hdm.StartAdminServer(ctx, *adminSocket)
+	}
 	hdm.SignCatch(cancel)
 }
 
 // InitFunction init function
 func InitFunction() (*server.HwDevManager, error) {
-	devM, err := devmanager.AutoInit("")
+	devM, err := initDeviceInterface()
 	if err != nil {
 		hwlog.RunLog.Errorf("init devmanager failed, err: %v", err)
 		return nil, err
@@ -205,6 +314,23 @@ func InitFunction() (*server.HwDevManager, error) {
 	return hdm, nil
 }
 
+func initDeviceInterface() (devmanager.DeviceInterface, error) {
+	if *simulate == "" {
+		return devmanager.AutoInit("")
+	}
+	scenario, err := simulator.LoadScenario(*simulate)
+	if err != nil {
+		return nil, err
+	}
+	sim, err := simulator.NewSimulator(scenario)
+	if err != nil {
+		return nil, err
+	}
+	hwlog.RunLog.Warnf("device plugin runs on simulated npu of scenario %s", *simulate)
+	sim.Start()
+	return sim, nil
+}
+
 func setParameters() {
 	common.ParamOption = common.Option{
 		GetFdFlag:          *fdFlag,
@@ -215,9 +341,18 @@ func setParameters() {
 		PresetVDevice:      *presetVirtualDevice,
 		Use310PMixedInsert: *use310PMixedInsert,
 		HotReset:           *hotReset,
+		ResetBudgetTimes:   *resetBudgetTimes,
+		ResetBudgetWindow:  *resetBudgetWindow,
+		HotResetDryRun:     *hotResetDryRun,
+		WriteGracePlanCM:   *writeGracePlanCM,
 		BuildScene:         BuildScene,
 		ShareCount:         *shareDevCount,
 		LinkdownTimeout:    *linkdownTimeout,
+		SysfsRoot:          *sysfsRoot,
+		UseCDI:             *useCDI,
+		CDISpecDir:         *cdiSpecDir,
+		SeparateTaintNum:   *separateTaintNum,
+		FaultHistoryFile:   *faultHistoryFile,
 	}
 }
 
@@ -0,0 +1,167 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package admin implements the client and command line of the admin api served on unix socket by device plugin
+package admin
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+const (
+	cliName = "npu-admin"
+	usage   = `Usage: %s [-socket path] <command> [args]
+
+Commands:
+  list                           list all npu on node
+  explain <device>               show why the npu is unhealthy
+  separate <device> <reason>     manually separate the npu
+  unseparate <device>            remove the manual separation of the npu
+  reset <device>                 hot reset the idle inference npu
+  history [-file path] [-phyID n]
+                                 export the fault history of npu in json, device plugin is not required
+
+Options:
+`
+	tabPadding = 2
+	// separateArgsNum the device name and at least one word of reason
+	separateArgsNum = 2
+)
+
+// Run run the admin command line with args, return the exit code
+func Run(args []string, stdout, stderr io.Writer) int {
+	flagSet := flag.NewFlagSet(cliName, flag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+	socketPath := flagSet.String("socket", common.DefaultAdminSocketPath, "The path of admin unix socket")
+	flagSet.Usage = func() {
+		fmt.Fprintf(stderr, usage, cliName)
+		flagSet.PrintDefaults()
+	}
+	if err := flagSet.Parse(args); err != nil {
+		return 1
+	}
+	if flagSet.NArg() == 0 {
+		flagSet.Usage()
+		return 1
+	}
+	if err := runCommand(NewClient(*socketPath), flagSet.Args(), stdout); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", cliName, err)
+		return 1
+	}
+	return 0
+}
+
+func runCommand(client *Client, args []string, stdout io.Writer) error {
+	command, cmdArgs := args[0], args[1:]
+	switch command {
+	case "list":
+		devInfos, err := client.ListDevices()
+		if err != nil {
+			return err
+		}
+		printDevices(stdout, devInfos)
+		return nil
+	case "explain":
+		if len(cmdArgs) != 1 {
+			return fmt.Errorf("usage: explain <device>")
+		}
+		devInfo, err := client.ExplainDevice(cmdArgs[0])
+		if err != nil {
+			return err
+		}
+		printExplanation(stdout, devInfo)
+		return nil
+	case "separate":
+		if len(cmdArgs) < separateArgsNum {
+			return fmt.Errorf("usage: separate <device> <reason>")
+		}
+		return printMessage(stdout)(client.SeparateDevice(cmdArgs[0], strings.Join(cmdArgs[1:], " ")))
+	case "unseparate":
+		if len(cmdArgs) != 1 {
+			return fmt.Errorf("usage: unseparate <device>")
+		}
+		return printMessage(stdout)(client.UnseparateDevice(cmdArgs[0]))
+	case "history":
+		return exportFaultHistory(cmdArgs, stdout)
+	case "reset":
+		if len(cmdArgs) != 1 {
+			return fmt.Errorf("usage: reset <device>")
+		}
+		return printMessage(stdout)(client.ResetDevice(cmdArgs[0]))
+	default:
+		return fmt.Errorf("unknown command %s", command)
+	}
+}
+
+func exportFaultHistory(args []string, stdout io.Writer) error {
+	flagSet := flag.NewFlagSet("history", flag.ContinueOnError)
+	historyFile := flagSet.String("file", common.DefaultFaultHistoryFile, "The file of fault history")
+	phyID := flagSet.Int("phyID", -1, "Only export the fault history of the npu, -1 means all npu")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	records, err := common.ReadFaultHistoryFile(*historyFile)
+	if err != nil {
+		return err
+	}
+	exportRecords := make([]common.FaultHistoryRecord, 0, len(records))
+	for _, record := range records {
+		if *phyID < 0 || record.PhyID == int32(*phyID) {
+			exportRecords = append(exportRecords, record)
+		}
+	}
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(exportRecords)
+}
+
+func printMessage(stdout io.Writer) func(string, error) error {
+	return func(msg string, err error) error {
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, msg)
+		return nil
+	}
+}
+
+func printDevices(stdout io.Writer, devInfos []common.AdminDeviceInfo) {
+	writer := tabwriter.NewWriter(stdout, 0, 0, tabPadding, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tLOGIC ID\tPHY ID\tHEALTH\tNETWORK\tFAULT LEVEL\tSEPARATED")
+	for _, devInfo := range devInfos {
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%s\t%s\t%s\t%t\n", devInfo.DeviceName, devInfo.LogicID, devInfo.PhyID,
+			devInfo.Health, devInfo.NetworkHealth, devInfo.FaultType, devInfo.ManuallySeparated)
+	}
+	writer.Flush()
+}
+
+func printExplanation(stdout io.Writer, devInfo common.AdminDeviceInfo) {
+	fmt.Fprintf(stdout, "device: %s (logic id %d, phy id %d)\n", devInfo.DeviceName, devInfo.LogicID,
+		devInfo.PhyID)
+	fmt.Fprintf(stdout, "health: %s, network health: %s, fault level: %s\n", devInfo.Health,
+		devInfo.NetworkHealth, devInfo.FaultType)
+	if devInfo.FaultCodes != "" {
+		fmt.Fprintf(stdout, "fault codes: %s\n", devInfo.FaultCodes)
+	}
+	for _, reason := range devInfo.UnhealthyReasons {
+		fmt.Fprintf(stdout, "  - %s\n", reason)
+	}
+}
@@ -0,0 +1,115 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package admin implements the client and command line of the admin api served on unix socket by device plugin
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+func startFakeAdminServer(t *testing.T) string {
+	socketPath := filepath.Join(t.TempDir(), "admin.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(common.AdminDevicesPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]common.AdminDeviceInfo{{DeviceName: "Ascend910-0", Health: "Healthy"}})
+	})
+	mux.HandleFunc(common.AdminExplainPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(common.AdminDeviceInfo{DeviceName: r.URL.Query().Get("deviceName"),
+			Health: "Unhealthy", UnhealthyReasons: []string{"manually separated"}})
+	})
+	mux.HandleFunc(common.AdminSeparatePath, func(w http.ResponseWriter, r *http.Request) {
+		var req common.AdminRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(common.AdminResponse{Message: req.DeviceName + " separated: " + req.Reason})
+	})
+	mux.HandleFunc(common.AdminResetPath, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "device Ascend910-0 is in use", http.StatusConflict)
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	t.Cleanup(func() {
+		server.Close()
+	})
+	return socketPath
+}
+
+func runCLI(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := Run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// TestRun for test Run
+func TestRun(t *testing.T) {
+	socketPath := startFakeAdminServer(t)
+	convey.Convey("test Run", t, func() {
+		convey.Convey("list devices", func() {
+			code, stdout, _ := runCLI("-socket", socketPath, "list")
+			convey.So(code, convey.ShouldEqual, 0)
+			convey.So(strings.Contains(stdout, "Ascend910-0"), convey.ShouldBeTrue)
+		})
+		convey.Convey("explain device", func() {
+			code, stdout, _ := runCLI("-socket", socketPath, "explain", "Ascend910-1")
+			convey.So(code, convey.ShouldEqual, 0)
+			convey.So(strings.Contains(stdout, "Ascend910-1"), convey.ShouldBeTrue)
+			convey.So(strings.Contains(stdout, "manually separated"), convey.ShouldBeTrue)
+		})
+		convey.Convey("separate device with reason", func() {
+			code, stdout, _ := runCLI("-socket", socketPath, "separate", "Ascend910-0", "hbm", "error")
+			convey.So(code, convey.ShouldEqual, 0)
+			convey.So(stdout, convey.ShouldEqual, "Ascend910-0 separated: hbm error\n")
+		})
+		convey.Convey("separate device without reason", func() {
+			code, _, stderr := runCLI("-socket", socketPath, "separate", "Ascend910-0")
+			convey.So(code, convey.ShouldEqual, 1)
+			convey.So(strings.Contains(stderr, "usage"), convey.ShouldBeTrue)
+		})
+		convey.Convey("server returns error", func() {
+			code, _, stderr := runCLI("-socket", socketPath, "reset", "Ascend910-0")
+			convey.So(code, convey.ShouldEqual, 1)
+			convey.So(strings.Contains(stderr, "in use"), convey.ShouldBeTrue)
+		})
+		convey.Convey("export fault history", func() {
+			historyFile := filepath.Join(t.TempDir(), "fault_history.json")
+			records := []common.FaultHistoryRecord{{PhyID: 0, FaultCode: "80E01801"}, {PhyID: 1, FaultCode: "80C98009"}}
+			convey.So(os.WriteFile(historyFile, common.MarshalData(records), common.FaultHistoryFileMode),
+				convey.ShouldBeNil)
+			code, stdout, _ := runCLI("history", "-file", historyFile, "-phyID", "1")
+			convey.So(code, convey.ShouldEqual, 0)
+			var exportRecords []common.FaultHistoryRecord
+			convey.So(json.Unmarshal([]byte(stdout), &exportRecords), convey.ShouldBeNil)
+			convey.So(exportRecords, convey.ShouldResemble, records[1:])
+		})
+		convey.Convey("unknown command", func() {
+			code, _, _ := runCLI("-socket", socketPath, "unknown")
+			convey.So(code, convey.ShouldEqual, 1)
+		})
+	})
+}
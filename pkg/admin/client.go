@@ -0,0 +1,121 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package admin implements the client and command line of the admin api served on unix socket by device plugin
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+// baseURL the host of url is ignored as the request is always sent to the unix socket
+const baseURL = "http://unix"
+
+// Client is the client of admin api
+type Client struct {
+	httpClient *http.Client
+}
+
+// NewClient create the client of admin api served on socketPath
+func NewClient(socketPath string) *Client {
+	return &Client{httpClient: &http.Client{
+		Timeout: common.AdminRequestTimeout * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}}
+}
+
+// ListDevices list all physical devices on node
+func (c *Client) ListDevices() ([]common.AdminDeviceInfo, error) {
+	var devInfos []common.AdminDeviceInfo
+	err := c.do(http.MethodGet, common.AdminDevicesPath, nil, &devInfos)
+	return devInfos, err
+}
+
+// ExplainDevice show the device info and why the device is unhealthy
+func (c *Client) ExplainDevice(deviceName string) (common.AdminDeviceInfo, error) {
+	var devInfo common.AdminDeviceInfo
+	path := common.AdminExplainPath + "?" + url.Values{"deviceName": {deviceName}}.Encode()
+	err := c.do(http.MethodGet, path, nil, &devInfo)
+	return devInfo, err
+}
+
+// SeparateDevice manually separate the device with reason
+func (c *Client) SeparateDevice(deviceName, reason string) (string, error) {
+	return c.operate(common.AdminSeparatePath, common.AdminRequest{DeviceName: deviceName, Reason: reason})
+}
+
+// UnseparateDevice remove the manual separation of the device
+func (c *Client) UnseparateDevice(deviceName string) (string, error) {
+	return c.operate(common.AdminUnseparatePath, common.AdminRequest{DeviceName: deviceName})
+}
+
+// ResetDevice hot reset the idle inference device
+func (c *Client) ResetDevice(deviceName string) (string, error) {
+	return c.operate(common.AdminResetPath, common.AdminRequest{DeviceName: deviceName})
+}
+
+func (c *Client) operate(path string, req common.AdminRequest) (string, error) {
+	var resp common.AdminResponse
+	if err := c.do(http.MethodPost, path, req, &resp); err != nil {
+		return "", err
+	}
+	return resp.Message, nil
+}
+
+func (c *Client) do(method, path string, reqBody, respBody interface{}) error {
+	var body io.Reader
+	if reqBody != nil {
+		data, err := json.Marshal(reqBody)
+		if err != nil {
+			return fmt.Errorf("marshal request failed, %v", err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request device plugin failed, %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, common.AdminMaxResponseSize))
+	if err != nil {
+		return fmt.Errorf("read response failed, %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", strings.TrimSpace(string(data)))
+	}
+	if err = json.Unmarshal(data, respBody); err != nil {
+		return fmt.Errorf("unmarshal response failed, %v", err)
+	}
+	return nil
+}
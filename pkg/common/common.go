@@ -58,6 +58,8 @@ func GetPattern() map[string]*regexp.Regexp {
 
 var (
 	allDeviceInfoLock sync.Mutex
+	// deviceUpdateTrigger wake up the loop of listen device, the triggers before the loop handles are merged
+	deviceUpdateTrigger = make(chan string, 1)
 )
 
 // LockAllDeviceInfo lock for device info status
@@ -70,6 +72,19 @@ func UnlockAllDeviceInfo() {
 	allDeviceInfoLock.Unlock()
 }
 
+// TriggerDeviceUpdate wake up the loop of listen device to update device status immediately, it never blocks
+func TriggerDeviceUpdate(reason string) {
+	select {
+	case deviceUpdateTrigger <- reason:
+	default:
+	}
+}
+
+// DeviceUpdateTrigger return the channel which receives the reason of device update trigger
+func DeviceUpdateTrigger() <-chan string {
+	return deviceUpdateTrigger
+}
+
 // SetAscendRuntimeEnv is to set ascend runtime environment
 func SetAscendRuntimeEnv(devices []int, ascendRuntimeOptions string,
 	resp *v1beta1.ContainerAllocateResponse) {
@@ -85,7 +100,7 @@ func SetAscendRuntimeEnv(devices []int, ascendRuntimeOptions string,
 		deviceStr = append(deviceStr, strconv.Itoa(id))
 	}
 	(*resp).Envs[AscendVisibleDevicesEnv] = strings.Join(deviceStr, ",")
-	(*resp).Envs[ascendRuntimeOptionsEnv] = ascendRuntimeOptions
+	(*resp).Envs[AscendRuntimeOptionsEnv] = ascendRuntimeOptions
 	if ParamOption.RealCardType == Ascend310B {
 		(*resp).Envs[ascendAllowLinkEnv] = "True"
 	}
@@ -556,3 +571,65 @@ func GetDeviceRunMode() (string, error) {
 		return "", fmt.Errorf("%v is a unsupported device type", devType)
 	}
 }
+
+var (
+	topologyCache = make(map[int32]*v1beta1.TopologyInfo, MaxDevicesNum)
+	// topologyReadFailed the physical id of chips whose numa node read failed, the failure is only warned once
+	topologyReadFailed = make(map[int32]struct{}, MaxDevicesNum)
+	topologyCacheLock  sync.Mutex
+)
+
+// GetDeviceTopology get numa topology of the chip by physical id from sysfs, nil means no numa affinity. Only the
+// successful read is cached, the chip whose numa node read failed is read again in the next call
+func GetDeviceTopology(phyID int32) *v1beta1.TopologyInfo {
+	topologyCacheLock.Lock()
+	defer topologyCacheLock.Unlock()
+	if topology, exist := topologyCache[phyID]; exist {
+		return topology
+	}
+	numaNode, err := getNumaNodeFromSysfs(phyID)
+	if err != nil {
+		if _, warned := topologyReadFailed[phyID]; !warned {
+			hwlog.RunLog.Warnf("get numa node of device %d failed, topology will not be reported, err: %v",
+				phyID, err)
+			topologyReadFailed[phyID] = struct{}{}
+		}
+		return nil
+	}
+	delete(topologyReadFailed, phyID)
+	var topology *v1beta1.TopologyInfo
+	if numaNode >= 0 {
+		topology = &v1beta1.TopologyInfo{Nodes: []*v1beta1.NUMANode{{ID: numaNode}}}
+	}
+	topologyCache[phyID] = topology
+	return topology
+}
+
+// ResetDeviceTopologyCache clean the cached device topology
+func ResetDeviceTopologyCache() {
+	topologyCacheLock.Lock()
+	topologyCache = make(map[int32]*v1beta1.TopologyInfo, MaxDevicesNum)
+	topologyReadFailed = make(map[int32]struct{}, MaxDevicesNum)
+	topologyCacheLock.Unlock()
+}
+
+func getNumaNodeFromSysfs(phyID int32) (int64, error) {
+	sysfsRoot := ParamOption.SysfsRoot
+	if sysfsRoot == "" {
+		sysfsRoot = DefaultSysfsRoot
+	}
+	numaNodePath := filepath.Join(sysfsRoot, DavinciSysfsClassDir, fmt.Sprintf("%s%d", DavinciDevNamePrefix, phyID),
+		"device", NumaNodeFileName)
+	data, err := os.ReadFile(numaNodePath)
+	if err != nil {
+		return 0, err
+	}
+	numaNode, err := strconv.ParseInt(strings.TrimSpace(string(data)), BaseDec, BitSize)
+	if err != nil {
+		return 0, fmt.Errorf("parse numa node %s failed, %v", numaNodePath, err)
+	}
+	if numaNode >= MaxNumaNodeNum {
+		return 0, fmt.Errorf("numa node %d of %s is invalid", numaNode, numaNodePath)
+	}
+	return numaNode, nil
+}
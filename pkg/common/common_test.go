@@ -552,3 +552,66 @@ func TestCheckDeviceName(t *testing.T) {
 		})
 	})
 }
+
+// TestGetDeviceTopology for test GetDeviceTopology
+func TestGetDeviceTopology(t *testing.T) {
+	sysfsRoot := t.TempDir()
+	ParamOption.SysfsRoot = sysfsRoot
+	writeNumaNode := func(phyID int32, content string) {
+		devDir := filepath.Join(sysfsRoot, DavinciSysfsClassDir, fmt.Sprintf("%s%d", DavinciDevNamePrefix, phyID),
+			"device")
+		convey.So(os.MkdirAll(devDir, os.ModePerm), convey.ShouldBeNil)
+		convey.So(os.WriteFile(filepath.Join(devDir, NumaNodeFileName), []byte(content), SocketChmod),
+			convey.ShouldBeNil)
+	}
+	convey.Convey("test GetDeviceTopology", t, func() {
+		ResetDeviceTopologyCache()
+		convey.Convey("numa node is valid", func() {
+			writeNumaNode(0, "1\n")
+			topology := GetDeviceTopology(0)
+			convey.So(topology, convey.ShouldNotBeNil)
+			convey.So(len(topology.Nodes), convey.ShouldEqual, 1)
+			convey.So(topology.Nodes[0].ID, convey.ShouldEqual, 1)
+		})
+		convey.Convey("no numa affinity", func() {
+			writeNumaNode(1, "-1")
+			convey.So(GetDeviceTopology(1), convey.ShouldBeNil)
+		})
+		convey.Convey("numa node file not exist or invalid", func() {
+			convey.So(GetDeviceTopology(2), convey.ShouldBeNil)
+			writeNumaNode(3, "abc")
+			convey.So(GetDeviceTopology(3), convey.ShouldBeNil)
+		})
+		convey.Convey("failed read is not cached", func() {
+			convey.So(GetDeviceTopology(4), convey.ShouldBeNil)
+			writeNumaNode(4, "0")
+			topology := GetDeviceTopology(4)
+			convey.So(topology, convey.ShouldNotBeNil)
+			convey.So(topology.Nodes[0].ID, convey.ShouldEqual, 0)
+		})
+	})
+	ParamOption.SysfsRoot = ""
+	ResetDeviceTopologyCache()
+}
+
+// TestTriggerDeviceUpdate for test TriggerDeviceUpdate
+func TestTriggerDeviceUpdate(t *testing.T) {
+	convey.Convey("test TriggerDeviceUpdate", t, func() {
+		for len(DeviceUpdateTrigger()) != 0 {
+			<-DeviceUpdateTrigger()
+		}
+		convey.Convey("triggers are merged and never block", func() {
+			TriggerDeviceUpdate("first")
+			TriggerDeviceUpdate("second")
+			convey.So(<-DeviceUpdateTrigger(), convey.ShouldEqual, "first")
+			convey.So(len(DeviceUpdateTrigger()), convey.ShouldEqual, 0)
+		})
+		convey.Convey("device fault event triggers update", func() {
+			const eventID = 0x7fffffff
+			SaveDevFaultInfo(common.DevFaultInfo{EventID: eventID})
+			convey.So(<-DeviceUpdateTrigger(), convey.ShouldEqual, "device fault event")
+			delete(faultSeverityMap, eventID)
+			GetAndCleanFaultInfo()
+		})
+	})
+}
@@ -64,12 +64,14 @@ const (
 	DeviceInfoCMDataKey = "DeviceInfoCfg"
 	// DeviceInfoCMManuallySeparateNPUKey for deviceinfo configmap ManuallySeparateNPU key
 	DeviceInfoCMManuallySeparateNPUKey = "ManuallySeparateNPU"
+	// DeviceInfoCMFaultHistoryKey for deviceinfo configmap fault history summary key
+	DeviceInfoCMFaultHistoryKey = "FaultHistorySummary"
 
 	runtimeEnvNum = 3
 	// AscendVisibleDevicesEnv visible devices env
 	AscendVisibleDevicesEnv = "ASCEND_VISIBLE_DEVICES"
-	// ascendRuntimeOptionsEnv virtual runtime option env
-	ascendRuntimeOptionsEnv = "ASCEND_RUNTIME_OPTIONS"
+	// AscendRuntimeOptionsEnv virtual runtime option env
+	AscendRuntimeOptionsEnv = "ASCEND_RUNTIME_OPTIONS"
 	// ascendAllowLinkEnv a500a2 need mount softlink
 	ascendAllowLinkEnv = "ASCEND_ALLOW_LINK"
 	// PodPredicateTime pod predicate time
@@ -78,6 +80,9 @@ const (
 	Pod2kl = "kltDev"
 	// PodRealAlloc pod annotation key, means pod real mount device
 	PodRealAlloc = "AscendReal"
+	// PodRestartBusinessKey pod annotation key, the restart signal to the workload when the devices used by pod
+	// raised the fault of RestartBusiness
+	PodRestartBusinessKey = "huawei.com/npu-restart-business"
 	// Pod910DeviceKey pod annotation key, for generate 910 hccl rank table
 	Pod910DeviceKey = "ascend.kubectl.kubernetes.io/ascend-910-configuration"
 	// MetaDataAnnotation downward api which map annotation from volcano to container's env
@@ -353,6 +358,8 @@ const (
 	AcceleratorTypeKey = "accelerator-type"
 	// A300IA2Label the value of the A300I A2 node label
 	A300IA2Label = "card-910b-infer"
+	// HCCSRingSizeLabelKey the node label key of the chip number of a fully populated HCCS ring
+	HCCSRingSizeLabelKey = "hccs-ring-size"
 )
 
 const (
@@ -380,6 +387,148 @@ const (
 	HiAi200RCTsAisle = "/dev/ts_aisle"
 )
 
+const (
+	// DefaultCDISpecDir default dir of cdi spec file
+	DefaultCDISpecDir = "/var/run/cdi"
+	// CDISpecFileName name of cdi spec file
+	CDISpecFileName = "huawei.com-npu.json"
+	// CDIVersion version of cdi spec
+	CDIVersion = "0.5.0"
+	// CDIKind kind of npu device in cdi spec
+	CDIKind = "huawei.com/npu"
+	// CDIAnnotationPrefix annotation key prefix of cdi device reference
+	CDIAnnotationPrefix = "cdi.k8s.io/"
+	// CDIAnnotationPluginName plugin name in cdi annotation key
+	CDIAnnotationPluginName = "ascend-device-plugin"
+	// CDISpecFileMode cdi spec file mode
+	CDISpecFileMode = 0644
+	// CDISpecDirMode cdi spec dir mode
+	CDISpecDirMode = 0755
+)
+
+const (
+	// EventComponentName the source component of the events emitted by device plugin
+	EventComponentName = "ascend-device-plugin"
+	// EventBurstSize the max events can be emitted at once for one object
+	EventBurstSize = 10
+	// EventQPS the rate of events refilled for one object, one event per minute
+	EventQPS = 1.0 / 60
+	// EventLRUCacheSize the size of cache used for events aggregation and spam filter
+	EventLRUCacheSize = 1024
+
+	// EventReasonNPUUnhealthy device health changed to unhealthy
+	EventReasonNPUUnhealthy = "NPUUnhealthy"
+	// EventReasonNPURecovered device health changed to healthy
+	EventReasonNPURecovered = "NPURecovered"
+	// EventReasonNPUFaultLevelChanged device fault level changed
+	EventReasonNPUFaultLevelChanged = "NPUFaultLevelChanged"
+	// EventReasonNPUManuallySeparated device is manually separated
+	EventReasonNPUManuallySeparated = "NPUManuallySeparated"
+	// EventReasonNPUIsolated device used by pod is isolated
+	EventReasonNPUIsolated = "NPUIsolated"
+	// EventReasonNPUResetStarted device hot reset is started
+	EventReasonNPUResetStarted = "NPUResetStarted"
+	// EventReasonNPUResetSucceeded device hot reset is succeeded
+	EventReasonNPUResetSucceeded = "NPUResetSucceeded"
+	// EventReasonNPUResetFailed device hot reset is failed
+	EventReasonNPUResetFailed = "NPUResetFailed"
+	// EventReasonNPURestartBusiness device used by pod raised the fault which needs the business to be restarted
+	EventReasonNPURestartBusiness = "NPURestartBusiness"
+)
+
+const (
+	// MetricsPath the http path of prometheus metrics
+	MetricsPath = "/metrics"
+	// HTTPReadHeaderTimeout the timeout(second) of reading http request header
+	HTTPReadHeaderTimeout = 5
+	// HTTPShutdownTimeout the timeout(second) of shutting down http server
+	HTTPShutdownTimeout = 5
+	// HealthzPath the http path of liveness probe
+	HealthzPath = "/healthz"
+	// ReadyzPath the http path of readiness probe
+	ReadyzPath = "/readyz"
+	// DebugStatePath the http path of dumping the internal caches
+	DebugStatePath = "/debug/state"
+	// DebugPprofPath the http path prefix of pprof
+	DebugPprofPath = "/debug/pprof/"
+	// ListenDeviceStuckPeriods ListenDevice is considered stuck when it has no iteration within these periods
+	ListenDeviceStuckPeriods = 3
+)
+
+const (
+	// DefaultFaultHistoryFile the default file of fault history, it is in the log dir which is mounted from host
+	DefaultFaultHistoryFile = "/var/log/mindx-dl/devicePlugin/fault_history.json"
+	// FaultHistoryMaxRecords the max number of records kept in fault history, the oldest one is dropped first
+	FaultHistoryMaxRecords = 2000
+	// FaultHistoryFileMode the mode of fault history file
+	FaultHistoryFileMode = 0640
+	// FaultHistoryDirMode the mode of fault history dir
+	FaultHistoryDirMode = 0750
+	// FaultAssertionOccur the assertion of fault raise event in fault history
+	FaultAssertionOccur = "occur"
+	// FaultAssertionRecover the assertion of fault recover event in fault history
+	FaultAssertionRecover = "recover"
+	// FaultAssertionOnce the assertion of one-time fault event in fault history
+	FaultAssertionOnce = "once"
+	// FaultSourceSubscribe the fault event is got by subscribe interface
+	FaultSourceSubscribe = "subscribe"
+	// FaultSourcePolling the fault event is got by polling all error codes of device
+	FaultSourcePolling = "polling"
+)
+
+const (
+	// DefaultAdminSocketPath the default path of admin unix socket
+	DefaultAdminSocketPath = "/var/run/mindx-dl/devicePlugin/admin.sock"
+	// AdminSocketDirMode the mode of admin unix socket dir, only root can access it
+	AdminSocketDirMode = 0700
+	// AdminSocketMode the mode of admin unix socket, only root can access it
+	AdminSocketMode = 0600
+	// AdminDevicesPath the admin api path of listing devices
+	AdminDevicesPath = "/devices"
+	// AdminExplainPath the admin api path of showing why a device is unhealthy
+	AdminExplainPath = "/explain"
+	// AdminSeparatePath the admin api path of manually separating a device
+	AdminSeparatePath = "/separate"
+	// AdminUnseparatePath the admin api path of removing the manual separation of a device
+	AdminUnseparatePath = "/unseparate"
+	// AdminResetPath the admin api path of hot resetting an idle device
+	AdminResetPath = "/reset"
+	// AdminRequestTimeout the timeout(second) of admin request, hot reset may take up to one minute
+	AdminRequestTimeout = 90
+	// AdminMaxRequestSize the max size of admin request body
+	AdminMaxRequestSize = 4096
+	// AdminMaxResponseSize the max size of admin response body
+	AdminMaxResponseSize = 1024 * 1024
+	// AdminSubCommand the sub command of device plugin binary which runs the admin cli
+	AdminSubCommand = "admin"
+)
+
+const (
+	// NPUHealthyConditionType the type of node condition which summarizes the health of npu on node
+	NPUHealthyConditionType = "AscendNPUHealthy"
+	// NPUHealthyReason all npu on node are healthy
+	NPUHealthyReason = "NPUHealthy"
+	// NPUUnhealthyReason some npu on node are unhealthy
+	NPUUnhealthyReason = "NPUUnhealthy"
+	// NPUNetworkUnhealthyReason the network of some npu on node are unhealthy
+	NPUNetworkUnhealthyReason = "NPUNetworkUnhealthy"
+	// NPUSeparateTaintKey the key of taint added to node when too many npu are separated
+	NPUSeparateTaintKey = "huawei.com/npu-separated"
+)
+
+const (
+	// DefaultSysfsRoot default root path of sysfs
+	DefaultSysfsRoot = "/sys"
+	// DavinciSysfsClassDir sysfs class dir of davinci device, relative to sysfs root
+	DavinciSysfsClassDir = "class/devdrv-class"
+	// DavinciDevNamePrefix davinci device name prefix, like davinci0
+	DavinciDevNamePrefix = "davinci"
+	// NumaNodeFileName the file records numa node of pcie device
+	NumaNodeFileName = "numa_node"
+	// MaxNumaNodeNum max numa node num
+	MaxNumaNodeNum = 64
+)
+
 const (
 	// Atlas200ISoc 200 soc env
 	Atlas200ISoc = "Atlas 200I SoC A1"
@@ -486,6 +635,18 @@ const (
 	WaitRetryTime = 5
 	// ResetRetryTimes for max retry times when reset failed
 	ResetRetryTimes = 3
+	// DefaultResetBudgetTimes the default failed hot reset times of a chip in budget window before it is separated
+	DefaultResetBudgetTimes = 3
+	// MaxResetBudgetTimes the max failed hot reset times of a chip in budget window
+	MaxResetBudgetTimes = 10
+	// DefaultResetBudgetWindow the default seconds of window in which the failed hot reset of a chip is counted
+	DefaultResetBudgetWindow = 3600
+	// MinResetBudgetWindow the min seconds of reset budget window
+	MinResetBudgetWindow = 60
+	// MaxResetBudgetWindow the max seconds of reset budget window
+	MaxResetBudgetWindow = 86400
+	// ResetBackoffBase the seconds to wait after the first failed hot reset, it doubles after each failure
+	ResetBackoffBase = 30
 )
 
 const (
@@ -495,10 +656,16 @@ const (
 	ResetInfoCMDataKey = "reset.json"
 	// ResetInfoCMCheckCodeKey for reset configmap checkcode key
 	ResetInfoCMCheckCodeKey = "checkCode"
+	// ResetProgressCMDataKey for reset configmap data key of the reset progress of each node
+	ResetProgressCMDataKey = "progress.json"
 	// ResetTaskNameKey for obtain the reset task name
 	ResetTaskNameKey = "volcano.sh/job-name"
 	// ResetTaskNameKeyInLabel for obtain the reset task name when using operator
 	ResetTaskNameKeyInLabel = "job-name"
+	// GracePlanCMNamePrefix for grace tolerance plan configmap name prefix, written in dry-run mode
+	GracePlanCMNamePrefix = "mindx-dl-grace-plan-"
+	// GracePlanCMDataKey for grace tolerance plan configmap data key
+	GracePlanCMDataKey = "plan.json"
 )
 
 const (
@@ -550,28 +717,19 @@ const (
 )
 
 const (
-	// LeftRingOf910 means id contains [0, 1, 2, 3]
-	LeftRingOf910 = 0
-	// RightRingOf910 means id contains [4, 5, 6, 7]
-	RightRingOf910 = 1
-	// RingOf910B means id contains [0, 1, 2, 3, 4, 5, 6, 7]
-	RingOf910B = 2
 	// MaxResetWaitRecoverTime max reset wait chip recover time is 150s
 	MaxResetWaitRecoverTime = 150
 )
 
-// LogicID list for reset, get id list of ring
-const (
-	LogicID0 = 0
-	LogicID3 = 3
-	LogicID4 = 4
-	LogicID7 = 7
-)
-
 // Fault customization const
 const (
 	// PollFaultCodeCMInterval is the default interval(second) of polling fault code CM
 	PollFaultCodeCMInterval = 300
+	// DeviceUpdateDebounceTime is the time(millisecond) to wait for more events after the device update
+	// is triggered, the events in this time are handled together
+	DeviceUpdateDebounceTime = 100
+	// DeviceUpdateMinInterval is the min interval(millisecond) between two device updates which are triggered
+	DeviceUpdateMinInterval = 1000
 	// PollFaultCodeCMMaxInterval is the max interval(second) of polling fault code CM
 	PollFaultCodeCMMaxInterval = 3600
 	// PollFaultCodeCMMinInterval is the min interval(second) of polling fault code CM
@@ -28,7 +28,7 @@ import (
 // GetDeviceID get device physical id and virtual by device name
 func GetDeviceID(deviceName string, ascendRuntimeOptions string) (int, int, error) {
 	// share mode of ascend310 ascend310P:davinci-devID-index, like Ascend310P-0-99
-	if ShareDev() {
+	if ShareDev() && strings.HasPrefix(deviceName, Ascend310) {
 		deviceName = deviceName[:strings.LastIndex(deviceName, MiddelLine)]
 	}
 	// hiAIAscend310Prefix: davinci-mini
@@ -84,8 +84,20 @@ func GetDeviceListID(devices []string, ascendRuntimeOptions string) (map[int]int
 
 // ShareDev open the share dev function
 func ShareDev() bool {
-	return ParamOption.ShareCount > 1 &&
-		(ParamOption.RealCardType == Ascend310B || ParamOption.RealCardType == Ascend310P)
+	return ParamOption.ShareCount > 1 && (IsCardTypeOnNode(Ascend310B) || IsCardTypeOnNode(Ascend310P))
+}
+
+// IsCardTypeOnNode whether the card type is the real card type or one of the mixed card types
+func IsCardTypeOnNode(cardType string) bool {
+	if ParamOption.RealCardType == cardType {
+		return true
+	}
+	for _, mixedCardType := range ParamOption.MixedCardTypes {
+		if mixedCardType == cardType {
+			return true
+		}
+	}
+	return false
 }
 
 // IsVirtualDev used to judge whether a physical device or a virtual device
@@ -160,10 +172,15 @@ func GetAICore(templateName string) (int, error) {
 	return strconv.Atoi(aiCoreStr)
 }
 
-// FakeAiCoreDevice fake ai core devices
+// FakeAiCoreDevice fake ai core devices by the ai core count of chip, the ai core count of node is used when the
+// ai core count of chip is unknown
 func FakeAiCoreDevice(dev DavinCiDev, aiCoreDevices *[]*NpuDevice) {
 	aiCoreDevCount := len(*aiCoreDevices)
-	for core := int32(0); core < ParamOption.AiCoreCount; core++ {
+	chipAICore := dev.AICore
+	if chipAICore <= 0 {
+		chipAICore = ParamOption.AiCoreCount
+	}
+	for core := int32(0); core < chipAICore; core++ {
 		*aiCoreDevices = append(*aiCoreDevices, &NpuDevice{
 			DevType:       AiCoreResourceName,
 			DeviceName:    fmt.Sprintf("%s-%d", AiCoreResourceName, aiCoreDevCount),
@@ -171,11 +188,44 @@ func FakeAiCoreDevice(dev DavinCiDev, aiCoreDevices *[]*NpuDevice) {
 			NetworkHealth: v1beta1.Healthy,
 			PhyID:         dev.PhyID,
 			LogicID:       dev.LogicID,
+			AICore:        chipAICore,
+			Topology:      GetDeviceTopology(dev.PhyID),
 		})
 		aiCoreDevCount++
 	}
 }
 
+// GetAICoreRange get the min and max ai core count of chips, the ai core count of node is used when the ai core
+// count of no chip is known
+func GetAICoreRange(devices []NpuDevice) (int32, int32) {
+	var minAICore, maxAICore int32
+	for _, device := range devices {
+		if device.AICore <= 0 {
+			continue
+		}
+		if minAICore == 0 || device.AICore < minAICore {
+			minAICore = device.AICore
+		}
+		if device.AICore > maxAICore {
+			maxAICore = device.AICore
+		}
+	}
+	if maxAICore == 0 {
+		return ParamOption.AiCoreCount, ParamOption.AiCoreCount
+	}
+	return minAICore, maxAICore
+}
+
+// GetServerTypeLabel get the value of server type label, like Ascend910-32. On node with binned chips the max ai
+// core count is appended, like Ascend910-30-32, so that the min ai core count is still the second field
+func GetServerTypeLabel(cardType string) string {
+	label := cardType + MiddelLine + strconv.Itoa(int(ParamOption.AiCoreCount))
+	if ParamOption.MaxAiCoreCount > ParamOption.AiCoreCount {
+		label += MiddelLine + strconv.Itoa(int(ParamOption.MaxAiCoreCount))
+	}
+	return label
+}
+
 // GetTemplateName2DeviceTypeMap get virtual device type by template
 func GetTemplateName2DeviceTypeMap() map[string]string {
 	return map[string]string{
@@ -164,6 +164,39 @@ func TestFakeAiCoreDevice(t *testing.T) {
 			FakeAiCoreDevice(dev, &aiCoreDevices)
 			convey.So(len(aiCoreDevices), convey.ShouldEqual, MinAICoreNum)
 		})
+		convey.Convey("ai core devices are faked by ai core count of chip", func() {
+			binnedDev := DavinCiDev{LogicID: 1, PhyID: 1, AICore: MinAICoreNum - 2}
+			binnedAiCoreDevices := make([]*NpuDevice, 0)
+			FakeAiCoreDevice(binnedDev, &binnedAiCoreDevices)
+			convey.So(len(binnedAiCoreDevices), convey.ShouldEqual, MinAICoreNum-2)
+			convey.So(binnedAiCoreDevices[0].AICore, convey.ShouldEqual, MinAICoreNum-2)
+		})
+	})
+}
+
+// TestGetServerTypeLabel for test GetServerTypeLabel and GetAICoreRange
+func TestGetServerTypeLabel(t *testing.T) {
+	option := ParamOption
+	defer func() {
+		ParamOption = option
+	}()
+	convey.Convey("test GetServerTypeLabel", t, func() {
+		ParamOption.AiCoreCount = MaxAICoreNum
+		convey.Convey("ai core count of node is used when no chip answers", func() {
+			minAICore, maxAICore := GetAICoreRange([]NpuDevice{{PhyID: 0}})
+			convey.So(minAICore, convey.ShouldEqual, MaxAICoreNum)
+			convey.So(maxAICore, convey.ShouldEqual, MaxAICoreNum)
+		})
+		convey.Convey("label of chips with same ai core count", func() {
+			ParamOption.AiCoreCount, ParamOption.MaxAiCoreCount = GetAICoreRange([]NpuDevice{
+				{PhyID: 0, AICore: MaxAICoreNum}, {PhyID: 1, AICore: MaxAICoreNum}})
+			convey.So(GetServerTypeLabel(Ascend910), convey.ShouldEqual, "Ascend910-32")
+		})
+		convey.Convey("label of binned chips", func() {
+			ParamOption.AiCoreCount, ParamOption.MaxAiCoreCount = GetAICoreRange([]NpuDevice{
+				{PhyID: 0, AICore: MaxAICoreNum}, {PhyID: 1, AICore: MaxAICoreNum - 2}, {PhyID: 2}})
+			convey.So(GetServerTypeLabel(Ascend910), convey.ShouldEqual, "Ascend910-30-32")
+		})
 	})
 }
 
@@ -193,3 +226,24 @@ func TestCheckCardUsageMode(t *testing.T) {
 		})
 	})
 }
+
+// TestShareDev for test ShareDev on node with mixed chip families
+func TestShareDev(t *testing.T) {
+	option := ParamOption
+	defer func() {
+		ParamOption = option
+	}()
+	convey.Convey("test ShareDev", t, func() {
+		ParamOption.ShareCount = 2
+		ParamOption.RealCardType, ParamOption.MixedCardTypes = Ascend910B, nil
+		convey.So(ShareDev(), convey.ShouldBeFalse)
+		ParamOption.MixedCardTypes = []string{Ascend310P}
+		convey.So(ShareDev(), convey.ShouldBeTrue)
+		phyID, _, err := GetDeviceID("Ascend910-3", "")
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(phyID, convey.ShouldEqual, 3)
+		phyID, _, err = GetDeviceID("Ascend310P-1-2", "")
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(phyID, convey.ShouldEqual, 1)
+	})
+}
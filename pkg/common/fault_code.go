@@ -114,6 +114,7 @@ type ManuallyFaultInfo struct {
 	LogicID     int32
 	FirstHandle bool
 	RecordTime  int64
+	Reason      string
 }
 
 // FaultTypeCode group code by type
@@ -155,6 +156,11 @@ type FaultCustomization struct {
 type GraceToleranceCustomization struct {
 	WaitFlushingCMTime  int64
 	WaitDeviceResetTime int64
+	// FaultLevelPolicy key: fault level, value: process policy, overrides the default policy of the fault level
+	FaultLevelPolicy map[string]string
+	// PolicyEnable key: process policy, value: whether the policy is enabled, the fault of disabled policy is
+	// escalated to the enabled policy of the next higher level
+	PolicyEnable map[string]bool
 }
 
 // FaultFrequencyCustomization is the customization info of fault frequency
@@ -241,6 +247,7 @@ func LoadFaultCustomization(faultCustomizationStr string) {
 		return
 	}
 	loadGraceToleranceCustomization(faultCustomization.GraceTolerance)
+	loadGracePolicyCustomization(faultCustomization.GraceTolerance)
 	loadFaultFrequencyCustomization(faultCustomization.FaultFrequency)
 	loadFaultDurationCustomization(faultCustomization.FaultDuration)
 }
@@ -251,6 +258,7 @@ func ResetFaultCustomization() {
 	WaitDeviceResetTime = DefaultWaitDeviceResetTime
 	LinkDownTimeoutCustomization = ParamOption.LinkdownTimeout
 	LinkUpTimeoutCustomization = DefaultLinkUpTimeout
+	setGracePolicyTable(newDefaultGracePolicyTable())
 	faultFrequencyMapLock.Lock()
 	faultFrequencyMap = make(map[string]*FaultFrequencyCache, GeneralMapSize)
 	faultFrequencyMapLock.Unlock()
@@ -397,6 +405,26 @@ func validateFaultFrequencyCustomization(customization FaultFrequencyCustomizati
 	return true
 }
 
+// GetFaultTypeCode get the fault code table which is loaded currently
+func GetFaultTypeCode() FaultTypeCode {
+	return faultTypeCode
+}
+
+// GetFaultFrequencyInfo get the copy of fault frequency counters, key is event id
+func GetFaultFrequencyInfo() map[string]FaultFrequencyCache {
+	faultFrequencyMapLock.Lock()
+	defer faultFrequencyMapLock.Unlock()
+	frequencyInfo := make(map[string]FaultFrequencyCache, len(faultFrequencyMap))
+	for eventID, cache := range faultFrequencyMap {
+		frequency := make(map[int32][]int64, len(cache.Frequency))
+		for logicID, occurrenceTime := range cache.Frequency {
+			frequency[logicID] = append([]int64{}, occurrenceTime...)
+		}
+		frequencyInfo[eventID] = FaultFrequencyCache{Frequency: frequency, FaultFrequency: cache.FaultFrequency}
+	}
+	return frequencyInfo
+}
+
 // GetNetworkFaultTypeByCode get network fault type by fault code. if code not record, default PreSeparateNPU
 func GetNetworkFaultTypeByCode(faultCodes []string) string {
 	if len(faultCodes) == 0 {
@@ -431,6 +459,15 @@ func GetFaultType(faultCodes []int64, logicId int32) string {
 	return getMostSeriousFaultType(faultTypes)
 }
 
+// GetCurrentFaultType return the fault type from fault codes and ManuallySeparateNPU cache, unlike GetFaultType,
+// it does not refresh the cache of fault frequency, so it can be used to show the device status
+func GetCurrentFaultType(faultCodes []int64, logicId int32) string {
+	if QueryManuallyFaultInfoByLogicID(logicId) {
+		return ManuallySeparateNPU
+	}
+	return GetFaultTypeByCode(faultCodes)
+}
+
 // GetFaultTypeByCode get fault type by fault code. if code not record, default SeparateNPU0
 func GetFaultTypeByCode(faultCodes []int64) string {
 	if len(faultCodes) == 0 {
@@ -639,6 +676,7 @@ func SaveDevFaultInfo(devFaultInfo common.DevFaultInfo) {
 	devFaultInfoMapLock.Lock()
 	devFaultInfoMap[devFaultInfo.LogicID] = append(devFaultInfoMap[devFaultInfo.LogicID], devFaultInfo)
 	devFaultInfoMapLock.Unlock()
+	TriggerDeviceUpdate("device fault event")
 }
 
 // GetAndCleanFaultInfo get device fault info and clean cache
@@ -655,6 +693,11 @@ func GetAndCleanFaultInfo() map[int32][]common.DevFaultInfo {
 
 // SaveManuallyFaultInfo save manually fault info into manuallySeparateNpuMap
 func SaveManuallyFaultInfo(logicID int32) {
+	SaveManuallyFaultInfoWithReason(logicID, "")
+}
+
+// SaveManuallyFaultInfoWithReason save manually fault info and the reason of separation into manuallySeparateNpuMap
+func SaveManuallyFaultInfoWithReason(logicID int32, reason string) {
 	if logicID < 0 || logicID > 15 {
 		hwlog.RunLog.Warnf("logic id %d is not valid, logic id must be in [0, 15]", logicID)
 		return
@@ -663,6 +706,7 @@ func SaveManuallyFaultInfo(logicID int32) {
 		LogicID:     logicID,
 		FirstHandle: true,
 		RecordTime:  time.Now().UnixMilli(),
+		Reason:      reason,
 	}
 	manuallySeparateNpuMapLock.Lock()
 	defer manuallySeparateNpuMapLock.Unlock()
@@ -723,6 +767,28 @@ func QueryManuallyFaultNPULogicIDsByHandleStatus(handleStatus string) []int32 {
 	return logicIDs
 }
 
+// GetManuallyFaultInfoByLogicID get manually fault info based on logic id from manuallySeparateNpuMap
+func GetManuallyFaultInfoByLogicID(logicID int32) (ManuallyFaultInfo, bool) {
+	manuallySeparateNpuMapLock.Lock()
+	defer manuallySeparateNpuMapLock.Unlock()
+	manuallyFaultInfo, ok := manuallySeparateNpuMap[logicID]
+	return manuallyFaultInfo, ok
+}
+
+// GetManuallyFaultInfo get the copy of manually separate npu info cache
+func GetManuallyFaultInfo() []ManuallyFaultInfo {
+	manuallySeparateNpuMapLock.Lock()
+	defer manuallySeparateNpuMapLock.Unlock()
+	manuallyFaultInfos := make([]ManuallyFaultInfo, 0, len(manuallySeparateNpuMap))
+	for _, manuallyFaultInfo := range manuallySeparateNpuMap {
+		manuallyFaultInfos = append(manuallyFaultInfos, manuallyFaultInfo)
+	}
+	sort.Slice(manuallyFaultInfos, func(i, j int) bool {
+		return manuallyFaultInfos[i].LogicID < manuallyFaultInfos[j].LogicID
+	})
+	return manuallyFaultInfos
+}
+
 // SetManuallyFaultNPUHandled set manually fault NPU handled
 func SetManuallyFaultNPUHandled() {
 	manuallySeparateNpuMapLock.Lock()
@@ -322,7 +322,10 @@ func TestMergeContinuousElementBasedAssertion(t *testing.T) {
 func TestResetFaultCustomization(t *testing.T) {
 	convey.Convey("test ResetFaultCustomization success", t, func() {
 		expectVal := 0
+		loadGracePolicyCustomization(GraceToleranceCustomization{
+			FaultLevelPolicy: map[string]string{RestartBusiness: ResetError}})
 		ResetFaultCustomization()
+		convey.So(GetDevProcessPolicy(RestartBusiness), convey.ShouldEqual, RestartError)
 		convey.So(WaitFlushingCMTime, convey.ShouldEqual, DefaultWaitFlushCMTime)
 		convey.So(WaitDeviceResetTime, convey.ShouldEqual, DefaultWaitDeviceResetTime)
 		convey.So(LinkUpTimeoutCustomization, convey.ShouldEqual, DefaultLinkUpTimeout)
@@ -0,0 +1,189 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package common a series of common function
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	npuCommon "huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+var (
+	// faultHistory the ring buffer of fault events, the oldest event is at the head
+	faultHistory     []FaultHistoryRecord
+	faultHistoryLock sync.Mutex
+)
+
+// IsFaultHistoryEnabled return whether the fault history is recorded
+func IsFaultHistoryEnabled() bool {
+	return ParamOption.FaultHistoryFile != ""
+}
+
+// LoadFaultHistory load the fault history from file, so that the fault history survives restarts
+func LoadFaultHistory() error {
+	if !IsFaultHistoryEnabled() {
+		return nil
+	}
+	records, err := ReadFaultHistoryFile(ParamOption.FaultHistoryFile)
+	if err != nil {
+		return err
+	}
+	faultHistoryLock.Lock()
+	defer faultHistoryLock.Unlock()
+	faultHistory = trimFaultHistory(records)
+	hwlog.RunLog.Infof("load %d fault history records from %s", len(faultHistory), ParamOption.FaultHistoryFile)
+	return nil
+}
+
+// ReadFaultHistoryFile read the fault history records from file, no record is returned if the file does not exist
+func ReadFaultHistoryFile(path string) ([]FaultHistoryRecord, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read fault history file failed, %v", err)
+	}
+	var records []FaultHistoryRecord
+	if err = json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("unmarshal fault history failed, %v", err)
+	}
+	return records, nil
+}
+
+// RecordFaultHistory append the fault events into fault history and persist it into file
+func RecordFaultHistory(records []FaultHistoryRecord) {
+	if !IsFaultHistoryEnabled() || len(records) == 0 {
+		return
+	}
+	faultHistoryLock.Lock()
+	defer faultHistoryLock.Unlock()
+	faultHistory = trimFaultHistory(append(faultHistory, records...))
+	if err := writeFaultHistoryFile(ParamOption.FaultHistoryFile, faultHistory); err != nil {
+		hwlog.RunLog.Errorf("persist fault history failed, %v", err)
+	}
+}
+
+// GetFaultHistory get the copy of fault history
+func GetFaultHistory() []FaultHistoryRecord {
+	faultHistoryLock.Lock()
+	defer faultHistoryLock.Unlock()
+	return append([]FaultHistoryRecord{}, faultHistory...)
+}
+
+// IsFaultActiveInHistory return whether the last event of the fault code of the device in fault history is raised
+func IsFaultActiveInHistory(logicID int32, faultCode int64) bool {
+	code := strings.ToUpper(strconv.FormatInt(faultCode, Hex))
+	faultHistoryLock.Lock()
+	defer faultHistoryLock.Unlock()
+	for i := len(faultHistory) - 1; i >= 0; i-- {
+		if faultHistory[i].LogicID == logicID && faultHistory[i].FaultCode == code {
+			return faultHistory[i].Assertion == FaultAssertionOccur
+		}
+	}
+	return false
+}
+
+// GetFaultHistorySummary get the summary of fault history of each device in json, empty means fault history is
+// disabled
+func GetFaultHistorySummary() string {
+	if !IsFaultHistoryEnabled() {
+		return ""
+	}
+	summaryMap := make(map[int32]*FaultHistorySummary, GeneralMapSize)
+	for _, record := range GetFaultHistory() {
+		summary, ok := summaryMap[record.LogicID]
+		if !ok {
+			summary = &FaultHistorySummary{LogicID: record.LogicID, PhyID: record.PhyID}
+			summaryMap[record.LogicID] = summary
+		}
+		if record.Assertion == FaultAssertionRecover {
+			summary.RecoverCount++
+			continue
+		}
+		summary.RaiseCount++
+		summary.LastFaultCode = record.FaultCode
+		summary.LastFaultLevel = record.FaultLevel
+		summary.LastFaultTime = record.Timestamp
+	}
+	summaries := make([]*FaultHistorySummary, 0, len(summaryMap))
+	for _, summary := range summaryMap {
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].LogicID < summaries[j].LogicID
+	})
+	return string(MarshalData(summaries))
+}
+
+// NewFaultHistoryRecord create a fault history record of the device
+func NewFaultHistoryRecord(device *NpuDevice, faultCode int64, assertion string, timestamp int64,
+	source string) FaultHistoryRecord {
+	return FaultHistoryRecord{
+		LogicID:    device.LogicID,
+		PhyID:      device.PhyID,
+		FaultCode:  strings.ToUpper(strconv.FormatInt(faultCode, Hex)),
+		Assertion:  assertion,
+		FaultLevel: GetFaultTypeByCode([]int64{faultCode}),
+		Timestamp:  timestamp,
+		Source:     source,
+	}
+}
+
+// GetFaultAssertionName get the assertion name of fault event in fault history
+func GetFaultAssertionName(assertion int8) string {
+	switch assertion {
+	case npuCommon.FaultRecover:
+		return FaultAssertionRecover
+	case npuCommon.FaultOnce:
+		return FaultAssertionOnce
+	default:
+		return FaultAssertionOccur
+	}
+}
+
+func trimFaultHistory(records []FaultHistoryRecord) []FaultHistoryRecord {
+	if len(records) <= FaultHistoryMaxRecords {
+		return records
+	}
+	return append([]FaultHistoryRecord{}, records[len(records)-FaultHistoryMaxRecords:]...)
+}
+
+func writeFaultHistoryFile(path string, records []FaultHistoryRecord) error {
+	data := MarshalData(records)
+	if len(data) == 0 {
+		return fmt.Errorf("marshal fault history failed")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), FaultHistoryDirMode); err != nil {
+		return fmt.Errorf("create fault history dir failed, %v", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, FaultHistoryFileMode); err != nil {
+		return fmt.Errorf("write fault history failed, %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("rename fault history failed, %v", err)
+	}
+	return nil
+}
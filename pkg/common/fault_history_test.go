@@ -0,0 +1,71 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package common a series of common function
+package common
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+	"huawei.com/npu-exporter/v5/devmanager/common"
+)
+
+// TestRecordFaultHistory for test RecordFaultHistory
+func TestRecordFaultHistory(t *testing.T) {
+	const faultCode = 0x80E01801
+	ParamOption.FaultHistoryFile = filepath.Join(t.TempDir(), "fault_history.json")
+	defer func() {
+		ParamOption.FaultHistoryFile = ""
+		faultHistory = nil
+	}()
+	device := &NpuDevice{LogicID: 1, PhyID: 2}
+	convey.Convey("test RecordFaultHistory", t, func() {
+		convey.Convey("fault history survives restart", func() {
+			RecordFaultHistory([]FaultHistoryRecord{NewFaultHistoryRecord(device, faultCode,
+				GetFaultAssertionName(common.FaultOccur), 1, FaultSourceSubscribe)})
+			faultHistory = nil
+			convey.So(LoadFaultHistory(), convey.ShouldBeNil)
+			records := GetFaultHistory()
+			convey.So(len(records), convey.ShouldEqual, 1)
+			convey.So(records[0].FaultCode, convey.ShouldEqual, "80E01801")
+			convey.So(records[0].PhyID, convey.ShouldEqual, device.PhyID)
+			convey.So(IsFaultActiveInHistory(device.LogicID, faultCode), convey.ShouldBeTrue)
+		})
+		convey.Convey("summary counts raise and recover events", func() {
+			RecordFaultHistory([]FaultHistoryRecord{NewFaultHistoryRecord(device, faultCode,
+				GetFaultAssertionName(common.FaultRecover), 2, FaultSourcePolling)})
+			convey.So(IsFaultActiveInHistory(device.LogicID, faultCode), convey.ShouldBeFalse)
+			var summaries []FaultHistorySummary
+			convey.So(json.Unmarshal([]byte(GetFaultHistorySummary()), &summaries), convey.ShouldBeNil)
+			convey.So(len(summaries), convey.ShouldEqual, 1)
+			convey.So(summaries[0].RaiseCount, convey.ShouldEqual, 1)
+			convey.So(summaries[0].RecoverCount, convey.ShouldEqual, 1)
+			convey.So(summaries[0].LastFaultTime, convey.ShouldEqual, 1)
+		})
+		convey.Convey("the oldest event is dropped when the buffer is full", func() {
+			records := make([]FaultHistoryRecord, FaultHistoryMaxRecords)
+			for i := range records {
+				records[i] = NewFaultHistoryRecord(device, faultCode, FaultAssertionOnce, int64(i),
+					FaultSourceSubscribe)
+			}
+			RecordFaultHistory(records)
+			history := GetFaultHistory()
+			convey.So(len(history), convey.ShouldEqual, FaultHistoryMaxRecords)
+			convey.So(history[len(history)-1].Timestamp, convey.ShouldEqual, FaultHistoryMaxRecords-1)
+		})
+	})
+}
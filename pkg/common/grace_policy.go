@@ -0,0 +1,159 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package common a series of common function
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+)
+
+// gracePolicyTable the process policy of grace tolerance for each fault level
+type gracePolicyTable struct {
+	// faultLevelPolicy key: fault level, value: process policy
+	faultLevelPolicy map[string]string
+	// disabledPolicy the process policy which is not tolerated by the training framework
+	disabledPolicy map[string]struct{}
+}
+
+var (
+	processPolicyLevel = map[string]int{
+		EmptyError:          EmptyErrorLevel,
+		IgnoreError:         IgnoreErrorLevel,
+		RestartRequestError: RestartRequestErrorLevel,
+		RestartError:        RestartErrorLevel,
+		ResetError:          ResetErrorLevel,
+		IsolateError:        IsolateErrorLevel,
+	}
+	// policyCanNotDisable the process policy which must be enabled, isolation is the last resort of all faults
+	policyCanNotDisable = map[string]struct{}{
+		EmptyError:   {},
+		IgnoreError:  {},
+		IsolateError: {},
+	}
+	gracePolicy     = newDefaultGracePolicyTable()
+	gracePolicyLock sync.RWMutex
+)
+
+func newDefaultGracePolicyTable() *gracePolicyTable {
+	return &gracePolicyTable{
+		faultLevelPolicy: map[string]string{
+			NormalNPU:       EmptyError,
+			NotHandleFault:  EmptyError,
+			RestartRequest:  RestartRequestError,
+			RestartBusiness: RestartError,
+			FreeRestartNPU:  ResetError,
+			RestartNPU:      ResetError,
+		},
+		disabledPolicy: map[string]struct{}{},
+	}
+}
+
+// GetProcessPolicyTable return the level of each process policy
+func GetProcessPolicyTable() map[string]int {
+	table := make(map[string]int, len(processPolicyLevel))
+	for policy, level := range processPolicyLevel {
+		table[policy] = level
+	}
+	return table
+}
+
+// GetDevProcessPolicy return the process policy of the fault level, the policy which is disabled is escalated to
+// the enabled policy of the next higher level. The fault level out of the table is isolated
+func GetDevProcessPolicy(faultType string) string {
+	gracePolicyLock.RLock()
+	defer gracePolicyLock.RUnlock()
+	policy, ok := gracePolicy.faultLevelPolicy[faultType]
+	if !ok {
+		return IsolateError
+	}
+	return gracePolicy.escalate(policy)
+}
+
+func (table *gracePolicyTable) escalate(policy string) string {
+	if _, disabled := table.disabledPolicy[policy]; !disabled {
+		return policy
+	}
+	escalated := IsolateError
+	for candidate, level := range processPolicyLevel {
+		if _, disabled := table.disabledPolicy[candidate]; disabled || level <= processPolicyLevel[policy] {
+			continue
+		}
+		if level < processPolicyLevel[escalated] {
+			escalated = candidate
+		}
+	}
+	return escalated
+}
+
+// String return the effective table, such as "RestartBusiness->restart, RestartNPU->reset(disabled)->isolate"
+func (table *gracePolicyTable) String() string {
+	items := make([]string, 0, len(table.faultLevelPolicy))
+	for faultType, policy := range table.faultLevelPolicy {
+		item := faultType + "->" + policy
+		if effective := table.escalate(policy); effective != policy {
+			item += "(disabled)->" + effective
+		}
+		items = append(items, item)
+	}
+	sort.Strings(items)
+	return strings.Join(items, ", ")
+}
+
+func loadGracePolicyCustomization(customization GraceToleranceCustomization) {
+	table, err := buildGracePolicyTable(customization)
+	if err != nil {
+		hwlog.RunLog.Errorf("grace tolerance policy table is invalid, use default, %v", err)
+		table = newDefaultGracePolicyTable()
+	}
+	setGracePolicyTable(table)
+}
+
+func buildGracePolicyTable(customization GraceToleranceCustomization) (*gracePolicyTable, error) {
+	table := newDefaultGracePolicyTable()
+	for faultType, policy := range customization.FaultLevelPolicy {
+		if _, ok := table.faultLevelPolicy[faultType]; !ok {
+			return nil, fmt.Errorf("fault level %s can not be customized", faultType)
+		}
+		if _, ok := processPolicyLevel[policy]; !ok {
+			return nil, fmt.Errorf("unknown process policy %s of fault level %s", policy, faultType)
+		}
+		table.faultLevelPolicy[faultType] = policy
+	}
+	for policy, enable := range customization.PolicyEnable {
+		if _, ok := processPolicyLevel[policy]; !ok {
+			return nil, fmt.Errorf("unknown process policy %s", policy)
+		}
+		if enable {
+			continue
+		}
+		if _, ok := policyCanNotDisable[policy]; ok {
+			return nil, fmt.Errorf("process policy %s can not be disabled", policy)
+		}
+		table.disabledPolicy[policy] = struct{}{}
+	}
+	return table, nil
+}
+
+func setGracePolicyTable(table *gracePolicyTable) {
+	gracePolicyLock.Lock()
+	defer gracePolicyLock.Unlock()
+	gracePolicy = table
+	hwlog.RunLog.Infof("effective grace tolerance policy table: %s", table)
+}
@@ -0,0 +1,66 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package common a series of common function
+package common
+
+import (
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// TestLoadGracePolicyCustomization for test loadGracePolicyCustomization
+func TestLoadGracePolicyCustomization(t *testing.T) {
+	defer setGracePolicyTable(newDefaultGracePolicyTable())
+	convey.Convey("test loadGracePolicyCustomization", t, func() {
+		setGracePolicyTable(newDefaultGracePolicyTable())
+		convey.Convey("default table is used without customization", func() {
+			loadGracePolicyCustomization(GraceToleranceCustomization{})
+			convey.So(GetDevProcessPolicy(NormalNPU), convey.ShouldEqual, EmptyError)
+			convey.So(GetDevProcessPolicy(RestartRequest), convey.ShouldEqual, RestartRequestError)
+			convey.So(GetDevProcessPolicy(RestartBusiness), convey.ShouldEqual, RestartError)
+			convey.So(GetDevProcessPolicy(RestartNPU), convey.ShouldEqual, ResetError)
+			convey.So(GetDevProcessPolicy(SeparateNPU), convey.ShouldEqual, IsolateError)
+		})
+		convey.Convey("fault level is mapped to customized policy", func() {
+			loadGracePolicyCustomization(GraceToleranceCustomization{
+				FaultLevelPolicy: map[string]string{RestartBusiness: ResetError}})
+			convey.So(GetDevProcessPolicy(RestartBusiness), convey.ShouldEqual, ResetError)
+			convey.So(GetDevProcessPolicy(RestartRequest), convey.ShouldEqual, RestartRequestError)
+		})
+		convey.Convey("disabled policy is escalated to the next enabled policy", func() {
+			loadGracePolicyCustomization(GraceToleranceCustomization{
+				PolicyEnable: map[string]bool{RestartRequestError: false, RestartError: false, ResetError: true}})
+			convey.So(GetDevProcessPolicy(RestartRequest), convey.ShouldEqual, ResetError)
+			convey.So(GetDevProcessPolicy(RestartBusiness), convey.ShouldEqual, ResetError)
+			loadGracePolicyCustomization(GraceToleranceCustomization{PolicyEnable: map[string]bool{ResetError: false}})
+			convey.So(GetDevProcessPolicy(FreeRestartNPU), convey.ShouldEqual, IsolateError)
+		})
+		convey.Convey("invalid customization falls back to default table", func() {
+			invalids := []GraceToleranceCustomization{
+				{FaultLevelPolicy: map[string]string{SeparateNPU: ResetError}},
+				{FaultLevelPolicy: map[string]string{RestartBusiness: "unknown"}},
+				{PolicyEnable: map[string]bool{"unknown": true}},
+				{PolicyEnable: map[string]bool{IsolateError: false}},
+			}
+			for _, invalid := range invalids {
+				loadGracePolicyCustomization(GraceToleranceCustomization{
+					FaultLevelPolicy: map[string]string{RestartBusiness: ResetError}})
+				loadGracePolicyCustomization(invalid)
+				convey.So(GetDevProcessPolicy(RestartBusiness), convey.ShouldEqual, RestartError)
+			}
+		})
+	})
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/fsnotify/fsnotify"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 )
 
 var (
@@ -68,6 +69,8 @@ type NpuDevice struct {
 	LogicID           int32
 	PhyID             int32
 	CardID            int32
+	AICore            int32
+	Topology          *v1beta1.TopologyInfo
 }
 
 // DavinCiDev davinci device
@@ -75,6 +78,7 @@ type DavinCiDev struct {
 	LogicID int32
 	PhyID   int32
 	CardID  int32
+	AICore  int32
 }
 
 // Device id for Instcance
@@ -100,12 +104,23 @@ type Option struct {
 	Use310PMixedInsert bool     // chose 310P mixed insert mode
 	ListAndWatchPeriod int      // set listening device state period
 	HotReset           int      // unhealthy chip hot reset
+	ResetBudgetTimes   int      // failed hot reset times of a chip in budget window before it is separated
+	ResetBudgetWindow  int64    // seconds of window in which the failed hot reset of a chip is counted
+	HotResetDryRun     bool     // plan the grace tolerance of training task without resetting chip
+	WriteGracePlanCM   bool     // write the grace tolerance plan of dry-run into configmap
 	ShareCount         uint     // share device count
-	AiCoreCount        int32    // found by dcmi interface
+	AiCoreCount        int32    // found by dcmi interface, the min ai core count of chips
+	MaxAiCoreCount     int32    // the max ai core count of chips, more than AiCoreCount on node with binned chips
 	BuildScene         string   // build scene judge device-plugin start scene
 	ProductTypes       []string // all product types
 	RealCardType       string   // real card type
+	MixedCardTypes     []string // card types of the other chip families on node with mixed chip families
 	LinkdownTimeout    int64    // linkdown timeout duration
+	SysfsRoot          string   // root path of sysfs, used to find numa node of device
+	UseCDI             bool     // use cdi device reference in allocate response
+	CDISpecDir         string   // dir of cdi spec file
+	SeparateTaintNum   int      // taint node when separated npu num reach it, 0 means never taint
+	FaultHistoryFile   string   // file of fault history ring buffer, empty means fault history is disabled
 }
 
 // GetAllDeviceInfoTypeList Get All Device Info Type List
@@ -161,6 +176,13 @@ type TaskResetInfo struct {
 	UpdateTime int64
 }
 
+// NodeResetProgress is the reset progress of a node in a task, the status is unrecovered while resetting, recovered
+// when reset is done and failed when the fault is escalated to isolation
+type NodeResetProgress struct {
+	Status     string
+	UpdateTime int64
+}
+
 // TaskDevInfo is the device info of a task
 type TaskDevInfo struct {
 	RankId int
@@ -177,6 +199,98 @@ type DevFaultInfo struct {
 	ErrorCodeHex  string
 }
 
+// FaultHistoryRecord is a fault raise or recover event kept in fault history
+type FaultHistoryRecord struct {
+	LogicID    int32  `json:"logicID"`
+	PhyID      int32  `json:"phyID"`
+	FaultCode  string `json:"faultCode"`
+	Assertion  string `json:"assertion"`
+	FaultLevel string `json:"faultLevel"`
+	Timestamp  int64  `json:"timestamp"`
+	Source     string `json:"source"`
+}
+
+// FaultHistorySummary is the summary of fault history of a device, written into device info configmap
+type FaultHistorySummary struct {
+	LogicID        int32  `json:"logicID"`
+	PhyID          int32  `json:"phyID"`
+	RaiseCount     int    `json:"raiseCount"`
+	RecoverCount   int    `json:"recoverCount"`
+	LastFaultCode  string `json:"lastFaultCode"`
+	LastFaultLevel string `json:"lastFaultLevel"`
+	LastFaultTime  int64  `json:"lastFaultTime"`
+}
+
+// AdminRequest is the request of admin api to operate a device
+type AdminRequest struct {
+	DeviceName string `json:"deviceName"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// AdminResponse is the response of admin api to operate a device
+type AdminResponse struct {
+	Message string `json:"message"`
+}
+
+// AdminDeviceInfo is the device info returned by admin api
+type AdminDeviceInfo struct {
+	DeviceName        string   `json:"deviceName"`
+	DevType           string   `json:"devType"`
+	LogicID           int32    `json:"logicID"`
+	PhyID             int32    `json:"phyID"`
+	Health            string   `json:"health"`
+	NetworkHealth     string   `json:"networkHealth"`
+	FaultType         string   `json:"faultType"`
+	FaultCodes        string   `json:"faultCodes,omitempty"`
+	ManuallySeparated bool     `json:"manuallySeparated"`
+	UnhealthyReasons  []string `json:"unhealthyReasons,omitempty"`
+}
+
+// RestartBusinessSignal is the restart signal annotated on the pod whose devices raised the fault of
+// RestartBusiness, the workload restarts the business when the signal changed
+type RestartBusinessSignal struct {
+	Devices    []string `json:"devices"`
+	RaisedTime int64    `json:"raisedTime"`
+}
+
+// HotResetCacheInfo is the snapshot of hot reset caches, used for debugging
+type HotResetCacheInfo struct {
+	RingNum            int
+	TaskDevList        map[string][]int32
+	TaskDevFaultInfo   map[string][]*TaskDevInfo
+	GlobalDevFaultInfo map[int32]*DevFaultInfo
+	ResetTasks         []string
+	ResetDevices       []int32
+	// FaultDev2Pod key is the logic id of fault device, value is namespace/name of the pod using it
+	FaultDev2Pod map[int32]string
+}
+
+// GraceTolerancePlan is what grace tolerance would do in dry-run mode, no chip is reset and no task configmap
+// is written
+type GraceTolerancePlan struct {
+	PlanTime int64           `json:"planTime"`
+	Tasks    []TaskGracePlan `json:"tasks"`
+	// ResetDevices the logic id of chips which would have been reset by the tasks of reset policy
+	ResetDevices []int32 `json:"resetDevices"`
+}
+
+// TaskGracePlan is the grace tolerance plan of a training task
+type TaskGracePlan struct {
+	TaskName    string `json:"taskName"`
+	Namespace   string `json:"namespace"`
+	Policy      string `json:"policy"`
+	PolicyLevel int    `json:"policyLevel"`
+	// SkipReason why the task would not be processed, empty means the task would be processed
+	SkipReason string `json:"skipReason,omitempty"`
+	// ResetDevices the logic id of chips on which SetDeviceReset would be called, each resets the whole ring.
+	// They are reset at once by reset policy, or when restart policy can not heal the fault
+	ResetDevices []int32 `json:"resetDevices"`
+	// IsolateDevices the logic id of chips whose fault can only be handled by isolation
+	IsolateDevices []int32        `json:"isolateDevices"`
+	ResetInfo      *TaskResetInfo `json:"resetInfo,omitempty"`
+	FaultInfo      *TaskFaultInfo `json:"faultInfo,omitempty"`
+}
+
 // TaskFaultInfoCache record task fault rank information cache
 type TaskFaultInfoCache struct {
 	FaultInfo *TaskFaultInfo
@@ -0,0 +1,128 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package common a series of common function
+package common
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+)
+
+// resetBudget the failed hot reset of a chip in budget window
+type resetBudget struct {
+	// failedTimes the unix time of failed hot reset in budget window
+	failedTimes []int64
+	// nextResetTime the unix time before which the chip is not reset again
+	nextResetTime int64
+}
+
+var (
+	resetBudgets = make(map[int32]*resetBudget, GeneralMapSize)
+	// pendingSeparations key: logic id of the escalated chip, value: the reason of separation. It is saved into
+	// manually separate npu cache by updateDevice, see SavePendingResetSeparations
+	pendingSeparations = make(map[int32]string, GeneralMapSize)
+	resetBudgetsLock   sync.Mutex
+	// resetBudgetNow get the current time, replaced in test
+	resetBudgetNow = time.Now
+)
+
+// CheckDeviceResetBudget check whether the chip can be hot reset now, the chip which is separated or waiting for the
+// backoff of last failed hot reset should not be reset
+func CheckDeviceResetBudget(logicID int32) error {
+	if QueryManuallyFaultInfoByLogicID(logicID) {
+		return fmt.Errorf("device %d is %s", logicID, ManuallySeparateNPU)
+	}
+	resetBudgetsLock.Lock()
+	defer resetBudgetsLock.Unlock()
+	if _, pending := pendingSeparations[logicID]; pending {
+		return fmt.Errorf("device %d is escalated to %s", logicID, ManuallySeparateNPU)
+	}
+	budget, exist := resetBudgets[logicID]
+	if !exist || resetBudgetNow().Unix() >= budget.nextResetTime {
+		return nil
+	}
+	return fmt.Errorf("device %d hot reset failed %d times, wait until %s to reset again", logicID,
+		len(budget.failedTimes), time.Unix(budget.nextResetTime, 0).Format(time.RFC3339))
+}
+
+// RecordDeviceResetResult record the result of hot reset of the chip. The budget is cleared when hot reset succeeds,
+// and the chip is escalated to ManuallySeparateNPU when hot reset failed resetBudgetTimes in budget window, otherwise
+// the chip waits for an exponential backoff before the next hot reset. Return true when the chip is escalated.
+// The hot reset may be done without the lock of device info, so the escalation is queued for updateDevice
+func RecordDeviceResetResult(logicID int32, resetErr error) bool {
+	resetBudgetsLock.Lock()
+	defer resetBudgetsLock.Unlock()
+	if resetErr == nil {
+		delete(resetBudgets, logicID)
+		return false
+	}
+	now := resetBudgetNow().Unix()
+	budget, exist := resetBudgets[logicID]
+	if !exist {
+		budget = &resetBudget{}
+		resetBudgets[logicID] = budget
+	}
+	index := 0
+	for index < len(budget.failedTimes) && budget.failedTimes[index] <= now-getResetBudgetWindow() {
+		index++
+	}
+	budget.failedTimes = append(budget.failedTimes[index:], now)
+	failedTimes := len(budget.failedTimes)
+	if failedTimes >= getResetBudgetTimes() {
+		delete(resetBudgets, logicID)
+		reason := fmt.Sprintf("hot reset failed %d times in %d seconds, last error: %v", failedTimes,
+			getResetBudgetWindow(), resetErr)
+		hwlog.RunLog.Errorf("device %d is escalated to %s, %s", logicID, ManuallySeparateNPU, reason)
+		pendingSeparations[logicID] = reason
+		TriggerDeviceUpdate("hot reset escalation")
+		return true
+	}
+	backoff := int64(ResetBackoffBase) << (failedTimes - 1)
+	if backoff > getResetBudgetWindow() {
+		backoff = getResetBudgetWindow()
+	}
+	budget.nextResetTime = now + backoff
+	hwlog.RunLog.Warnf("device %d hot reset failed %d times in budget window, reset again after %d seconds",
+		logicID, failedTimes, backoff)
+	return false
+}
+
+// SavePendingResetSeparations save the chips escalated by RecordDeviceResetResult into manually separate npu cache,
+// the caller should hold the lock of device info
+func SavePendingResetSeparations() {
+	resetBudgetsLock.Lock()
+	defer resetBudgetsLock.Unlock()
+	for logicID, reason := range pendingSeparations {
+		SaveManuallyFaultInfoWithReason(logicID, reason)
+		delete(pendingSeparations, logicID)
+	}
+}
+
+func getResetBudgetTimes() int {
+	if ParamOption.ResetBudgetTimes <= 0 {
+		return DefaultResetBudgetTimes
+	}
+	return ParamOption.ResetBudgetTimes
+}
+
+func getResetBudgetWindow() int64 {
+	if ParamOption.ResetBudgetWindow <= 0 {
+		return DefaultResetBudgetWindow
+	}
+	return ParamOption.ResetBudgetWindow
+}
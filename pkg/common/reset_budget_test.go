@@ -0,0 +1,74 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package common a series of common function
+package common
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// TestRecordDeviceResetResult for test RecordDeviceResetResult and CheckDeviceResetBudget
+func TestRecordDeviceResetResult(t *testing.T) {
+	const logicID = 3
+	option := ParamOption
+	ParamOption.ResetBudgetTimes, ParamOption.ResetBudgetWindow = 0, 0
+	now := time.Now()
+	resetBudgetNow = func() time.Time { return now }
+	defer func() {
+		ParamOption = option
+		resetBudgetNow = time.Now
+		DeleteManuallyFaultInfo(logicID)
+	}()
+	resetErr := fmt.Errorf("boot timeout")
+	convey.Convey("test RecordDeviceResetResult", t, func() {
+		convey.Convey("hot reset backs off exponentially and budget is cleared by success", func() {
+			convey.So(RecordDeviceResetResult(logicID, resetErr), convey.ShouldBeFalse)
+			convey.So(CheckDeviceResetBudget(logicID), convey.ShouldNotBeNil)
+			now = now.Add(ResetBackoffBase * time.Second)
+			convey.So(CheckDeviceResetBudget(logicID), convey.ShouldBeNil)
+			convey.So(RecordDeviceResetResult(logicID, resetErr), convey.ShouldBeFalse)
+			now = now.Add(ResetBackoffBase * time.Second)
+			convey.So(CheckDeviceResetBudget(logicID), convey.ShouldNotBeNil)
+			now = now.Add(ResetBackoffBase * time.Second)
+			convey.So(CheckDeviceResetBudget(logicID), convey.ShouldBeNil)
+			convey.So(RecordDeviceResetResult(logicID, nil), convey.ShouldBeFalse)
+			convey.So(CheckDeviceResetBudget(logicID), convey.ShouldBeNil)
+		})
+		convey.Convey("failures out of window are not counted", func() {
+			convey.So(RecordDeviceResetResult(logicID, resetErr), convey.ShouldBeFalse)
+			now = now.Add(DefaultResetBudgetWindow * time.Second)
+			convey.So(RecordDeviceResetResult(logicID, resetErr), convey.ShouldBeFalse)
+			convey.So(RecordDeviceResetResult(logicID, nil), convey.ShouldBeFalse)
+		})
+		convey.Convey("chip is escalated when budget is exhausted", func() {
+			for i := 1; i < DefaultResetBudgetTimes; i++ {
+				convey.So(RecordDeviceResetResult(logicID, resetErr), convey.ShouldBeFalse)
+			}
+			convey.So(RecordDeviceResetResult(logicID, resetErr), convey.ShouldBeTrue)
+			_, ok := GetManuallyFaultInfoByLogicID(logicID)
+			convey.So(ok, convey.ShouldBeFalse)
+			convey.So(CheckDeviceResetBudget(logicID), convey.ShouldNotBeNil)
+			SavePendingResetSeparations()
+			info, ok := GetManuallyFaultInfoByLogicID(logicID)
+			convey.So(ok, convey.ShouldBeTrue)
+			convey.So(info.Reason, convey.ShouldContainSubstring, "boot timeout")
+			convey.So(CheckDeviceResetBudget(logicID), convey.ShouldNotBeNil)
+		})
+	})
+}
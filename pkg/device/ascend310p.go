@@ -69,6 +69,7 @@ func (hnm *HwAscend310PManager) GetNPUs() (common.NpuAllInfo, error) {
 		if vDevInfos.TotalResource.VDevNum > common.MaxVirtualDeviceNum {
 			return common.NpuAllInfo{}, fmt.Errorf("invalid virtual device count")
 		}
+		davinCiDev.AICore = hnm.getChipAICore(vDevInfos)
 		if vDevInfos.TotalResource.VDevNum > 0 && common.ShareDev() {
 			return common.NpuAllInfo{}, fmt.Errorf("virtual device is exist, shareDevCount should be 1")
 		}
@@ -29,6 +29,7 @@ import (
 	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	"Ascend-device-plugin/pkg/common"
+	"Ascend-device-plugin/pkg/metrics"
 )
 
 const (
@@ -46,6 +47,13 @@ var (
 type HwAscend910Manager struct {
 	AscendTools
 	hotResetManager HotResetManager
+	// gracePlan the grace tolerance plan of dry-run, gracePlanData is the tasks of plan which is logged last time
+	gracePlan     *common.GraceTolerancePlan
+	gracePlanData string
+	gracePlanLock sync.RWMutex
+	// topology the HCCS ring topology of chips, built when the usage of device is set
+	topology     *ChipTopology
+	topologyLock sync.RWMutex
 }
 
 // NewHwAscend910Manager is used to create ascend 910 manager
@@ -88,6 +96,7 @@ func (hnm *HwAscend910Manager) GetNPUs() (common.NpuAllInfo, error) {
 		if vDevInfos.TotalResource.VDevNum > common.MaxVirtualDeviceNum {
 			return common.NpuAllInfo{}, fmt.Errorf("invalid virtual device count")
 		}
+		davinCiDev.AICore = hnm.getChipAICore(vDevInfos)
 		if !common.ParamOption.PresetVDevice {
 			common.FakeAiCoreDevice(davinCiDev, &aiCoreDevices)
 		}
@@ -101,6 +110,39 @@ func (hnm *HwAscend910Manager) GetNPUs() (common.NpuAllInfo, error) {
 	return common.NpuAllInfo{AllDevs: allDevices, AICoreDevs: aiCoreDevices, AllDevTypes: allDeviceTypes}, nil
 }
 
+// SetDeviceUsage set usage of device and build the ring topology of chips, the ring size depends on the usage
+func (hnm *HwAscend910Manager) SetDeviceUsage(devLogicID int32) error {
+	if err := hnm.AscendTools.SetDeviceUsage(devLogicID); err != nil {
+		return err
+	}
+	topology := BuildTopology(hnm.GetDmgr(), GetRingNumByUsage(hnm.GetDeviceUsage()))
+	hnm.topologyLock.Lock()
+	defer hnm.topologyLock.Unlock()
+	if hnm.topology.String() != topology.String() {
+		hwlog.RunLog.Infof("chip topology changed, %s", topology)
+	}
+	hnm.topology = topology
+	return nil
+}
+
+// GetChipTopology get the HCCS ring topology of chips, the constant ring size of usage is used before built
+func (hnm *HwAscend910Manager) GetChipTopology() *ChipTopology {
+	hnm.topologyLock.RLock()
+	defer hnm.topologyLock.RUnlock()
+	if hnm.topology == nil {
+		return newDefaultTopology(GetRingNumByUsage(hnm.GetDeviceUsage()))
+	}
+	return hnm.topology
+}
+
+// GetHotResetCacheInfo get the snapshot of hot reset caches, nil means hot reset manager is not initialized
+func (hnm *HwAscend910Manager) GetHotResetCacheInfo() *common.HotResetCacheInfo {
+	if hnm.hotResetManager == nil {
+		return nil
+	}
+	return hnm.hotResetManager.GetCacheInfo()
+}
+
 // GraceTolerance process training task with device fault gracefully
 func (hnm *HwAscend910Manager) GraceTolerance(classifyDevs map[string][]*common.NpuDevice) {
 	hotResetManagerInitOnce.Do(func() {
@@ -110,11 +152,17 @@ func (hnm *HwAscend910Manager) GraceTolerance(classifyDevs map[string][]*common.
 		hwlog.RunLog.Debugf("hot reset manager is nil, devType: %s", common.ParamOption.RealCardType)
 		return
 	}
+	hnm.hotResetManager.SetTopology(hnm.GetChipTopology())
 	// 1. obtain the current device status and update the cache of hot reset manager
 	if err := hnm.updateHotResetCache(classifyDevs); err != nil {
 		hwlog.RunLog.Errorf("failed to update hot reset cache, err: %#v", err)
 		return
 	}
+	// in dry-run mode, only plan the grace tolerance without resetting device or writing task configmap
+	if common.ParamOption.HotResetDryRun {
+		hnm.planAllTask()
+		return
+	}
 	// 2. performs graceful fault tolerance for tasks to be processed based on the device information in the cache
 	if err := hnm.processAllTask(); err != nil {
 		hwlog.RunLog.Errorf("failed to process task, err: %#v", err)
@@ -400,14 +448,7 @@ func (hnm *HwAscend910Manager) convertLogicIdToPhysicId(logicIds []int32) (physi
 }
 
 func (hnm *HwAscend910Manager) isReSchedulingScene(npuCount int) bool {
-	if common.ParamOption.RealCardType == common.Ascend910 && npuCount < common.Ascend910RingsNum {
-		return true
-	}
-	if common.ParamOption.RealCardType == common.Ascend910B && hnm.GetDeviceUsage() == common.Train &&
-		npuCount < common.Ascend910BRingsNumTrain {
-		return true
-	}
-	return false
+	return npuCount < hnm.hotResetManager.GetTopology().RingSize()
 }
 
 func (hnm *HwAscend910Manager) isTaskInReset(taskName string) (bool, error) {
@@ -449,6 +490,7 @@ func (hnm *HwAscend910Manager) filterDevStatus(classifyDevs map[string][]*common
 		return fmt.Errorf("no ascend 910 device needed filter")
 	}
 	devInReset := hnm.hotResetManager.GetDevListInReset()
+	topology := hnm.hotResetManager.GetTopology()
 	filteredRingIndex := -1
 	for _, devStatus := range devStatusList {
 		if _, ok := devInReset[devStatus.LogicID]; !ok || devStatus.Health == v1beta1.Healthy ||
@@ -457,27 +499,23 @@ func (hnm *HwAscend910Manager) filterDevStatus(classifyDevs map[string][]*common
 		}
 
 		devStatus.Health = v1beta1.Healthy
-		ringNum := hnm.hotResetManager.GetRingNum()
-		ringIndex := int(devStatus.LogicID) / ringNum
-		if ringIndex != filteredRingIndex {
-			startDevIndex := ringIndex * ringNum
-			endDevIndex := startDevIndex + ringNum
-			for devIndex := startDevIndex; devIndex < endDevIndex; devIndex++ {
-				devStatusList[devIndex].NetworkHealth = v1beta1.Healthy
+		ringIndex := topology.GetRingIndex(devStatus.LogicID)
+		if ringIndex == filteredRingIndex {
+			continue
+		}
+		for _, ringDevStatus := range devStatusList {
+			if topology.GetRingIndex(ringDevStatus.LogicID) == ringIndex {
+				ringDevStatus.NetworkHealth = v1beta1.Healthy
 			}
-			filteredRingIndex = ringIndex
 		}
+		filteredRingIndex = ringIndex
 	}
 	return nil
 }
 
 // refreshNormalPodAnnotation do not add new annotation to pod, actually.
-// It just refreshes annotation to trigger pod syncing
+// It just refreshes annotation to trigger pod syncing, it is called when the task is in reset
 func (hnm *HwAscend910Manager) refreshNormalPodAnnotation(taskName string) {
-	if resetFlag, _ := hnm.isTaskInReset(taskName); !resetFlag {
-		return
-	}
-
 	pod, err := hnm.hotResetManager.GetTaskPod(taskName)
 	if err != nil {
 		hwlog.RunLog.Errorf("failed to get task pod, err: %#v", err)
@@ -511,13 +549,16 @@ func (hnm *HwAscend910Manager) processAllTask() error {
 		case common.RestartErrorLevel, common.ResetErrorLevel, common.RestartRequestErrorLevel:
 			hwlog.RunLog.Debugf("start handle fault: %s - %d, task name: %s", policy, policyLevel, taskName)
 		default:
-			hnm.refreshNormalPodAnnotation(taskName)
+			// the reset info cm of task is read only once in a cycle, the progress is reported only in reset
+			if resetFlag, _ := hnm.isTaskInReset(taskName); resetFlag {
+				hnm.refreshNormalPodAnnotation(taskName)
+				hnm.reportIdleResetProgress(taskName, common.RecoveredStatus)
+			}
 			continue
 		}
 		if resetFlag, err := hnm.isTaskInReset(taskName); err != nil || resetFlag {
-			if resetFlag && !hnm.hotResetManager.IsCurNodeTaskInReset(taskName) &&
-				hnm.hotResetManager.IsExistFaultyDevInTask(taskName) {
-				go hnm.tryWriteIsolationInfo(taskName)
+			if resetFlag && !hnm.hotResetManager.IsCurNodeTaskInReset(taskName) {
+				hnm.processTaskResetByOthers(taskName)
 			}
 			continue
 		}
@@ -532,6 +573,16 @@ func (hnm *HwAscend910Manager) processAllTask() error {
 	return nil
 }
 
+// processTaskResetByOthers the faulty device of current node is isolated when the task is reset by other nodes
+func (hnm *HwAscend910Manager) processTaskResetByOthers(taskName string) {
+	if !hnm.hotResetManager.IsExistFaultyDevInTask(taskName) {
+		hnm.reportIdleResetProgress(taskName, common.RecoveredStatus)
+		return
+	}
+	hnm.reportIdleResetProgress(taskName, common.RecoverFailedStatus)
+	go hnm.tryWriteIsolationInfo(taskName)
+}
+
 func (hnm *HwAscend910Manager) runProcessTask(taskName string, policyLevel int, resetInfo *common.TaskResetInfo) error {
 	switch policyLevel {
 	case common.RestartRequestErrorLevel:
@@ -738,15 +789,32 @@ func (hnm *HwAscend910Manager) resetProcess(taskName string, resetInfo *common.T
 		return
 	}
 	hwlog.RunLog.Infof("start handle L5 fault, task name: %s", taskName)
+	hnm.recordTaskEvent(taskName, v1.EventTypeNormal, common.EventReasonNPUResetStarted,
+		"start hot reset of devices %v used by task %s", getLogicIDs(devFaultInfoList), taskName)
 	common.RecordFaultInfoList(devFaultInfoList)
 	devFaultInfoListInReset := hnm.hotResetManager.DeepCopyDevFaultInfoList(devFaultInfoList)
+	hnm.reportResetProgress(taskName, common.UnrecoveredStatus)
 	time.Sleep(common.WaitFlushingCMTime * time.Second)
 	if err := hnm.resetDeviceOnce(devFaultInfoList); err != nil {
 		hwlog.RunLog.Errorf("failed to reset device, err: %v", err)
+		hnm.reportResetProgress(taskName, common.RecoverFailedStatus)
+		hnm.recordTaskEvent(taskName, v1.EventTypeWarning, common.EventReasonNPUResetFailed,
+			"hot reset of devices %v used by task %s failed, %v", getLogicIDs(devFaultInfoList), taskName, err)
 		return
 	}
 	if err := hnm.upgradeResetProcess(taskName, devFaultInfoList); err != nil {
 		hwlog.RunLog.Errorf("failed to exec upgrade reset process, err :%v", err)
+		hnm.reportResetProgress(taskName, common.RecoverFailedStatus)
+		hnm.recordTaskEvent(taskName, v1.EventTypeWarning, common.EventReasonNPUResetFailed,
+			"hot reset of devices %v used by task %s failed and is escalated to isolation, %v",
+			getLogicIDs(devFaultInfoList), taskName, err)
+		return
+	}
+	hnm.reportResetProgress(taskName, common.RecoveredStatus)
+	// the task is recovered only when the reset on all nodes is done, otherwise it is escalated to isolation
+	if err := hnm.waitResetBarrier(taskName, resetInfo.UpdateTime); err != nil {
+		hwlog.RunLog.Errorf("reset barrier of task %s is not reached, err: %v", taskName, err)
+		hnm.escalateResetTask(taskName, devFaultInfoListInReset, err)
 		return
 	}
 	if err := hnm.updateResetCMStatus(taskName, common.ResetError, common.ResetError, common.RecoveredStatus,
@@ -758,9 +826,42 @@ func (hnm *HwAscend910Manager) resetProcess(taskName string, resetInfo *common.T
 		hwlog.RunLog.Errorf("failed to unset task in reset, err: %v", err)
 		return
 	}
+	hnm.recordTaskEvent(taskName, v1.EventTypeNormal, common.EventReasonNPUResetSucceeded,
+		"hot reset of devices %v used by task %s succeeded", getLogicIDs(devFaultInfoListInReset), taskName)
 	return
 }
 
+// escalateResetTask upgrade the task whose reset is not finished on all nodes to isolation
+func (hnm *HwAscend910Manager) escalateResetTask(taskName string, devFaultInfoList []*common.TaskDevInfo,
+	reason error) {
+	hnm.reportResetProgress(taskName, common.RecoverFailedStatus)
+	if err := hnm.updateResetCMStatus(taskName, common.IsolateError, common.ResetError, common.RecoverFailedStatus,
+		devFaultInfoList); err != nil {
+		hwlog.RunLog.Errorf("failed to update reset cm to recover failed status, err: %v", err)
+	}
+	hnm.recordTaskEvent(taskName, v1.EventTypeWarning, common.EventReasonNPUResetFailed,
+		"hot reset of devices %v used by task %s is escalated to isolation, %v", getLogicIDs(devFaultInfoList),
+		taskName, reason)
+}
+
+// recordTaskEvent record event on the pod of task
+func (hnm *HwAscend910Manager) recordTaskEvent(taskName, eventType, reason, messageFmt string, args ...interface{}) {
+	pod, err := hnm.hotResetManager.GetTaskPod(taskName)
+	if err != nil {
+		hwlog.RunLog.Warnf("failed to record event of task %s, err: %v", taskName, err)
+		return
+	}
+	hnm.client.RecordPodEvent(&pod, eventType, reason, messageFmt, args...)
+}
+
+func getLogicIDs(devFaultInfoList []*common.TaskDevInfo) []int32 {
+	logicIDs := make([]int32, 0, len(devFaultInfoList))
+	for _, devFaultInfo := range devFaultInfoList {
+		logicIDs = append(logicIDs, devFaultInfo.LogicId)
+	}
+	return logicIDs
+}
+
 // upgradeResetProcess upgrade the device reset processing to the device isolation processing
 func (hnm *HwAscend910Manager) upgradeResetProcess(taskName string, devFaultInfoList []*common.TaskDevInfo) error {
 	resultFaultInfoList, err := hnm.hotResetManager.GetNeedResetDevList(devFaultInfoList)
@@ -796,6 +897,8 @@ func (hnm *HwAscend910Manager) preProcess(taskName, policy string) (*common.Task
 		hwlog.RunLog.Errorf("failed to get task reset info list, err: %v", err)
 		return nil, err
 	}
+	// the reset progress reported before the reset starts is outdated
+	resetInfo.UpdateTime = time.Now().Unix()
 	if _, err := hnm.client.WriteResetInfoDataIntoCM(taskName, pod.Namespace, resetInfo); err != nil {
 		hwlog.RunLog.Errorf("failed to write reset info to cm, err: %v", err)
 		return nil, err
@@ -880,15 +983,28 @@ func (hnm *HwAscend910Manager) execResetDevice(devList map[int32]struct{}) error
 			hwlog.RunLog.Errorf("failed to get reset device card id and device id, err %v", err)
 			return err
 		}
+		if err := common.CheckDeviceResetBudget(devLogicId); err != nil {
+			hwlog.RunLog.Warnf("skip hot reset, %v", err)
+			errList = append(errList, err)
+			continue
+		}
+		metrics.IncHotResetAttempt()
+		startTime := time.Now()
 		if err := hnm.tryResetDevice(cardId, deviceId); err != nil {
+			metrics.ObserveHotReset(startTime, err)
+			hnm.recordResetFailure(devLogicId, err)
 			errList = append(errList, err)
 			continue
 		}
 		// wait for the device to reset completely
 		if err := hnm.isRingResetComplete(devLogicId); err != nil {
+			metrics.ObserveHotReset(startTime, err)
+			hnm.recordResetFailure(devLogicId, err)
 			errList = append(errList, err)
 			continue
 		}
+		metrics.ObserveHotReset(startTime, nil)
+		common.RecordDeviceResetResult(devLogicId, nil)
 		hwlog.RunLog.Infof("hot reset complete, cardId: %d, logicId: %d", cardId, devLogicId)
 	}
 	if len(errList) == 0 {
@@ -897,6 +1013,15 @@ func (hnm *HwAscend910Manager) execResetDevice(devList map[int32]struct{}) error
 	return errList[0]
 }
 
+// recordResetFailure record the failed hot reset, and emit event when the chip is escalated to ManuallySeparateNPU
+func (hnm *HwAscend910Manager) recordResetFailure(logicID int32, err error) {
+	if !common.RecordDeviceResetResult(logicID, err) || hnm.GetKubeClient() == nil {
+		return
+	}
+	hnm.GetKubeClient().RecordNodeEvent(v1.EventTypeWarning, common.EventReasonNPUManuallySeparated,
+		"device of logic id %d is separated as hot reset keeps failing", logicID)
+}
+
 func (hnm *HwAscend910Manager) waitDeviceResetComplete(logicId int32, totalTime *int) error {
 	if err := wait.PollImmediate(time.Second, common.WaitDeviceResetTime*time.Second, func() (bool, error) {
 		*totalTime += 1
@@ -920,25 +1045,11 @@ func (hnm *HwAscend910Manager) waitDeviceResetComplete(logicId int32, totalTime
 	return nil
 }
 
+// isRingResetComplete wait all chips on the ring of the reset chip to boot up
 func (hnm *HwAscend910Manager) isRingResetComplete(oriLogicID int32) error {
-	var ringType = -1
-	switch common.ParamOption.RealCardType {
-	case common.Ascend910:
-		ringType = common.LeftRingOf910
-		if oriLogicID >= common.Ascend910RingsNum {
-			ringType = common.RightRingOf910
-		}
-	case common.Ascend910B:
-		ringType = common.RingOf910B
-	}
-	if ringType == -1 {
-		hwlog.RunLog.Error("device type cannot be identified")
-		return fmt.Errorf("device type cannot be identified")
-	}
 	// totalTime, statistic chip reset recover time
 	var totalTime int
-	leftRingID, rightRingID := hnm.getLogicIDOnRing(ringType)
-	for ringLogicID := leftRingID; ringLogicID <= rightRingID; ringLogicID++ {
+	for _, ringLogicID := range hnm.hotResetManager.GetTopology().GetRing(oriLogicID) {
 		if err := hnm.waitDeviceResetComplete(ringLogicID, &totalTime); err != nil {
 			return err
 		}
@@ -946,17 +1057,6 @@ func (hnm *HwAscend910Manager) isRingResetComplete(oriLogicID int32) error {
 	return nil
 }
 
-func (hnm *HwAscend910Manager) getLogicIDOnRing(ringType int) (int32, int32) {
-	switch ringType {
-	case common.LeftRingOf910:
-		return common.LogicID0, common.LogicID3
-	case common.RightRingOf910:
-		return common.LogicID4, common.LogicID7
-	default:
-		return common.LogicID0, common.LogicID7
-	}
-}
-
 func (hnm *HwAscend910Manager) tryResetDevice(cardId, deviceId int32) error {
 	var realError error
 	for i := 0; i < common.ResetRetryTimes; i++ {
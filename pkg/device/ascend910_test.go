@@ -180,6 +180,36 @@ func TestProcessAllTask(t *testing.T) {
 	})
 }
 
+// TestProcessAllTaskNotInReset for test the task without fault which is not in reset reads reset info cm once
+func TestProcessAllTaskNotInReset(t *testing.T) {
+	manager := createFake910Manager()
+	convey.Convey("test processAllTask of task not in reset", t, func() {
+		getCMTimes, writeProgressTimes := 0, 0
+		patches := gomonkey.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "GetConfigMap",
+			func(_ *kubeclient.ClientK8s, _ string, _ string) (*v1.ConfigMap, error) {
+				getCMTimes++
+				resetInfo := common.TaskResetInfo{UpdateTime: 11111111}
+				return &v1.ConfigMap{Data: map[string]string{
+					common.ResetInfoCMDataKey:      string(common.MarshalData(resetInfo)),
+					common.ResetInfoCMCheckCodeKey: common.MakeDataHash(resetInfo)}}, nil
+			}).ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "WriteResetProgressIntoCM",
+			func(_ *kubeclient.ClientK8s, _, _, _ string) (map[string]*common.NodeResetProgress, error) {
+				writeProgressTimes++
+				return nil, nil
+			})
+		defer patches.Reset()
+		manager.hotResetManager = &HotResetTools{
+			allTaskDevFaultInfo: map[string][]*common.TaskDevInfo{"task1": {
+				{DevFaultInfo: common.DevFaultInfo{LogicId: chipPhyID0, Policy: common.EmptyError}}}},
+			taskPod:            map[string]v1.Pod{"task1": getSinglePod("pod1", map[string]string{})},
+			processPolicyTable: map[string]int{common.EmptyError: common.EmptyErrorLevel},
+		}
+		convey.So(manager.processAllTask(), convey.ShouldBeNil)
+		convey.So(getCMTimes, convey.ShouldEqual, 1)
+		convey.So(writeProgressTimes, convey.ShouldEqual, 0)
+	})
+}
+
 // TestFilterDevStatus a ut for function filterDevStatus
 func TestFilterDevStatus(t *testing.T) {
 	manager := createFake910Manager()
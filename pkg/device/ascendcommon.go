@@ -34,6 +34,7 @@ import (
 
 	"Ascend-device-plugin/pkg/common"
 	"Ascend-device-plugin/pkg/kubeclient"
+	"Ascend-device-plugin/pkg/metrics"
 )
 
 // isFirstFlushFault for device fault init
@@ -43,9 +44,9 @@ var subscribeToPollingTime = 5 * time.Minute.Milliseconds()
 
 var faultMode = make(map[int32]string, common.GeneralMapSize)
 
-const subscribe = "subscribe"
+const subscribe = common.FaultSourceSubscribe
 
-const polling = "polling"
+const polling = common.FaultSourcePolling
 
 var lastCheckNodeLabel int64
 
@@ -89,6 +90,9 @@ type DevManager interface {
 	GetChipAiCoreCount() (int32, error)
 	SetDeviceUsage(int32) error
 	GetDeviceUsage() string
+	GetHotResetCacheInfo() *common.HotResetCacheInfo
+	GetGraceTolerancePlan() *common.GraceTolerancePlan
+	GetChipTopology() *ChipTopology
 }
 
 // SetDmgr set devmanager
@@ -111,6 +115,21 @@ func (tool *AscendTools) GetKubeClient() *kubeclient.ClientK8s {
 	return tool.client
 }
 
+// GetHotResetCacheInfo get the snapshot of hot reset caches, nil means hot reset is not supported
+func (tool *AscendTools) GetHotResetCacheInfo() *common.HotResetCacheInfo {
+	return nil
+}
+
+// GetGraceTolerancePlan get the grace tolerance plan of dry-run, nil means grace tolerance is not supported
+func (tool *AscendTools) GetGraceTolerancePlan() *common.GraceTolerancePlan {
+	return nil
+}
+
+// GetChipTopology get the HCCS ring topology of chips, nil means the chips are not interconnected by ring
+func (tool *AscendTools) GetChipTopology() *ChipTopology {
+	return nil
+}
+
 // GetChipAICore get ai core
 func (tool *AscendTools) GetChipAICore() int32 {
 	return common.ParamOption.AiCoreCount
@@ -247,6 +266,8 @@ func (tool *AscendTools) assembleNpuDeviceStruct(deviType, deviceName string,
 		LogicID:       davinCiDev.LogicID,
 		PhyID:         davinCiDev.PhyID,
 		CardID:        davinCiDev.CardID,
+		AICore:        davinCiDev.AICore,
+		Topology:      common.GetDeviceTopology(davinCiDev.PhyID),
 	}
 }
 
@@ -744,6 +765,10 @@ func (tool *AscendTools) CheckDeviceTypeLabel() error {
 	if aiCore != int(common.ParamOption.AiCoreCount) {
 		return fmt.Errorf("label ai core %d not equal real chip ai core %d", aiCore, common.ParamOption.AiCoreCount)
 	}
+	if deviceType != common.GetServerTypeLabel(tool.name) {
+		return fmt.Errorf("label %s not equal real chip ai core range %s", deviceType,
+			common.GetServerTypeLabel(tool.name))
+	}
 	return nil
 }
 
@@ -759,6 +784,7 @@ func (tool *AscendTools) CreateVirtualDevice(phyID int32, templateName string) (
 		return "", err
 	}
 	createOut, err := tool.dmgr.CreateVirtualDevice(logicID, createInfo)
+	metrics.ObserveVNPUOperation(metrics.OperationCreate, err)
 	if err != nil {
 		hwlog.RunLog.Error(err)
 		return "", fmt.Errorf(common.NPUSegmentFailed)
@@ -788,10 +814,11 @@ func (tool *AscendTools) DestroyVirtualDevice(deviceName string) error {
 		}
 		time.Sleep(time.Second)
 	}
+	metrics.ObserveVNPUOperation(metrics.OperationDestroy, err)
 	return err
 }
 
-// GetChipAiCoreCount get chip aicore count
+// GetChipAiCoreCount get the min aicore count of chips, binned chips on node may have less aicore
 func (tool *AscendTools) GetChipAiCoreCount() (int32, error) {
 	_, logicIDs, err := tool.dmgr.GetDeviceList()
 	if err != nil {
@@ -800,6 +827,7 @@ func (tool *AscendTools) GetChipAiCoreCount() (int32, error) {
 	if len(logicIDs) < 1 {
 		return 0, fmt.Errorf("not found logicIDs")
 	}
+	var minAiCoreCount int32
 	for _, logicID := range logicIDs {
 		cgoVDevInfo, err := tool.dmgr.GetVirtualDeviceInfo(logicID)
 		if err != nil && strings.Contains(err.Error(), strconv.Itoa(common.DeviceNotSupport)) {
@@ -810,9 +838,25 @@ func (tool *AscendTools) GetChipAiCoreCount() (int32, error) {
 			hwlog.RunLog.Infof("not found aicore number by dcmi: %v", err)
 			return common.DefaultAiCoreNum, nil
 		}
-		return tool.getAiCoreCount(cgoVDevInfo)
+		aiCoreCount, err := tool.getAiCoreCount(cgoVDevInfo)
+		if err != nil {
+			return 0, err
+		}
+		if minAiCoreCount == 0 || aiCoreCount < minAiCoreCount {
+			minAiCoreCount = aiCoreCount
+		}
 	}
-	return 0, fmt.Errorf("not get aicore count")
+	return minAiCoreCount, nil
+}
+
+// getChipAICore get the aicore count of chip by its virtual device info, the aicore count of node is used when the
+// chip does not answer
+func (tool *AscendTools) getChipAICore(cgoVDevInfo npuCommon.VirtualDevInfo) int32 {
+	aiCoreCount, err := tool.getAiCoreCount(cgoVDevInfo)
+	if err != nil {
+		return common.ParamOption.AiCoreCount
+	}
+	return aiCoreCount
 }
 
 func (tool *AscendTools) getAiCoreCount(cgoVDevInfo npuCommon.VirtualDevInfo) (int32, error) {
@@ -827,9 +871,11 @@ func (tool *AscendTools) getAiCoreCount(cgoVDevInfo npuCommon.VirtualDevInfo) (i
 func (tool *AscendTools) writeNewFaultCode(deviceMap map[string][]*common.NpuDevice, runMode string) {
 	initLogicIDs := common.GetAndCleanLogicID()
 	devFaultInfoMap := common.GetAndCleanFaultInfo()
+	var historyRecords []common.FaultHistoryRecord
 	for _, devices := range deviceMap {
 		for _, device := range devices {
-			tool.flushFaultCodesWithInit(device, initLogicIDs, devFaultInfoMap)
+			historyRecords = append(historyRecords,
+				tool.flushFaultCodesWithInit(device, initLogicIDs, devFaultInfoMap)...)
 			device.Health = tool.isHealthy(device)
 			if runMode == common.Ascend910 && tool.deviceUsage == common.Train {
 				tool.handleDeviceNetworkFault(device, devFaultInfoMap)
@@ -837,23 +883,64 @@ func (tool *AscendTools) writeNewFaultCode(deviceMap map[string][]*common.NpuDev
 		}
 	}
 	isFirstFlushFault = false
+	common.RecordFaultHistory(historyRecords)
 }
 
+// flushFaultCodesWithInit flush the fault codes of device, return the fault events for fault history
 func (tool *AscendTools) flushFaultCodesWithInit(device *common.NpuDevice, initLogicIDs []int32,
-	devFaultInfoMap map[int32][]npuCommon.DevFaultInfo) {
+	devFaultInfoMap map[int32][]npuCommon.DevFaultInfo) []common.FaultHistoryRecord {
 	if isFirstFlushFault || (common.Int32Tool.Contains(initLogicIDs, device.LogicID)) || common.SubscribeFailed ||
 		(device.Health == v1beta1.Unhealthy && moreThanFiveMin(device)) {
 		_, errCodes, err := tool.dmgr.GetDeviceAllErrorCode(device.LogicID)
 		if err != nil {
 			hwlog.RunLog.Errorf("get device fault failed logic: %d, err: %v", device.LogicID, err)
-			return
+			return nil
 		}
+		oldFaultCodes := device.FaultCodes
 		common.SetFaultCodes(device, errCodes)
 		logFaultModeChange(device, initLogicIDs, polling)
-		return
+		return getPollingFaultHistory(device, oldFaultCodes)
 	}
 	common.SetNewFaultAndCacheOnceRecoverFault(device.LogicID, devFaultInfoMap[device.LogicID], device)
 	logFaultModeChange(device, initLogicIDs, subscribe)
+	return getSubscribeFaultHistory(device, devFaultInfoMap[device.LogicID])
+}
+
+// getPollingFaultHistory get the fault events by comparing the fault codes before and after polling
+func getPollingFaultHistory(device *common.NpuDevice, oldFaultCodes []int64) []common.FaultHistoryRecord {
+	var records []common.FaultHistoryRecord
+	now := time.Now().UnixMilli()
+	for _, faultCode := range device.FaultCodes {
+		// the fault raised before restart is still active, it should not be recorded again
+		if isFirstFlushFault && common.IsFaultActiveInHistory(device.LogicID, faultCode) {
+			continue
+		}
+		if common.Int64Tool.Index(oldFaultCodes, faultCode) == -1 {
+			records = append(records, common.NewFaultHistoryRecord(device, faultCode, common.FaultAssertionOccur,
+				now, common.FaultSourcePolling))
+		}
+	}
+	for _, faultCode := range oldFaultCodes {
+		if common.Int64Tool.Index(device.FaultCodes, faultCode) == -1 {
+			records = append(records, common.NewFaultHistoryRecord(device, faultCode, common.FaultAssertionRecover,
+				now, common.FaultSourcePolling))
+		}
+	}
+	return records
+}
+
+func getSubscribeFaultHistory(device *common.NpuDevice,
+	faultInfos []npuCommon.DevFaultInfo) []common.FaultHistoryRecord {
+	records := make([]common.FaultHistoryRecord, 0, len(faultInfos))
+	for _, faultInfo := range faultInfos {
+		timestamp := faultInfo.AlarmRaisedTime
+		if timestamp == 0 {
+			timestamp = time.Now().UnixMilli()
+		}
+		records = append(records, common.NewFaultHistoryRecord(device, faultInfo.EventID,
+			common.GetFaultAssertionName(faultInfo.Assertion), timestamp, common.FaultSourceSubscribe))
+	}
+	return records
 }
 
 func moreThanFiveMin(device *common.NpuDevice) bool {
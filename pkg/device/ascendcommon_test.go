@@ -350,6 +350,22 @@ func TestCheckDeviceTypeLabel(t *testing.T) {
 			err = tool.CheckDeviceTypeLabel()
 			convey.So(err, convey.ShouldBeNil)
 		})
+		convey.Convey("CheckDeviceTypeLabel of binned chips", func() {
+			mockNode := gomonkey.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "GetNode",
+				func(_ *kubeclient.ClientK8s) (*v1.Node, error) {
+					return node, nil
+				})
+			defer mockNode.Reset()
+			maxAiCoreCount := common.ParamOption.MaxAiCoreCount
+			defer func() {
+				common.ParamOption.MaxAiCoreCount = maxAiCoreCount
+			}()
+			common.ParamOption.AiCoreCount, common.ParamOption.MaxAiCoreCount = aiCoreCount, aiCoreCount+1
+			node.Labels[common.ServerTypeLabelKey] = "Ascend310P-8"
+			convey.So(tool.CheckDeviceTypeLabel(), convey.ShouldNotBeNil)
+			node.Labels[common.ServerTypeLabelKey] = "Ascend310P-8-9"
+			convey.So(tool.CheckDeviceTypeLabel(), convey.ShouldBeNil)
+		})
 	})
 }
 
@@ -0,0 +1,165 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package device a series of device function
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+// GetGraceTolerancePlan get the grace tolerance plan of dry-run, nil means dry-run is not enabled
+func (hnm *HwAscend910Manager) GetGraceTolerancePlan() *common.GraceTolerancePlan {
+	hnm.gracePlanLock.RLock()
+	defer hnm.gracePlanLock.RUnlock()
+	return hnm.gracePlan
+}
+
+// planAllTask compute what processAllTask would do, it never resets chip or writes the configmap of task
+func (hnm *HwAscend910Manager) planAllTask() {
+	taskDevFaultInfoList := hnm.hotResetManager.GetAllTaskDevFaultInfoList()
+	taskNames := make([]string, 0, len(taskDevFaultInfoList))
+	for taskName := range taskDevFaultInfoList {
+		taskNames = append(taskNames, taskName)
+	}
+	sort.Strings(taskNames)
+	plan := &common.GraceTolerancePlan{
+		PlanTime:     time.Now().Unix(),
+		Tasks:        make([]common.TaskGracePlan, 0, len(taskNames)),
+		ResetDevices: make([]int32, 0),
+	}
+	for _, taskName := range taskNames {
+		taskPlan, err := hnm.planTask(taskName)
+		if err != nil {
+			hwlog.RunLog.Errorf("failed to plan task %s, err: %v", taskName, err)
+			continue
+		}
+		if taskPlan == nil {
+			continue
+		}
+		plan.Tasks = append(plan.Tasks, *taskPlan)
+		if taskPlan.SkipReason == "" && taskPlan.PolicyLevel == common.ResetErrorLevel {
+			plan.ResetDevices = append(plan.ResetDevices, taskPlan.ResetDevices...)
+		}
+	}
+	hnm.setGraceTolerancePlan(plan)
+}
+
+// planTask compute the grace tolerance plan of task, nil means the task has no fault to be handled
+func (hnm *HwAscend910Manager) planTask(taskName string) (*common.TaskGracePlan, error) {
+	devFaultInfoList, err := hnm.hotResetManager.GetTaskDevFaultInfoList(taskName)
+	if err != nil {
+		return nil, err
+	}
+	policy, policyLevel, err := hnm.hotResetManager.GetTaskProcessPolicy(taskName)
+	if err != nil {
+		return nil, err
+	}
+	if policyLevel < common.RestartRequestErrorLevel {
+		return nil, nil
+	}
+	pod, err := hnm.hotResetManager.GetTaskPod(taskName)
+	if err != nil {
+		return nil, err
+	}
+	isolateDevs, err := hnm.hotResetManager.GetDevListByPolicyLevel(devFaultInfoList, common.IsolateErrorLevel)
+	if err != nil {
+		return nil, err
+	}
+	taskPlan := &common.TaskGracePlan{
+		TaskName:       taskName,
+		Namespace:      pod.Namespace,
+		Policy:         policy,
+		PolicyLevel:    policyLevel,
+		ResetDevices:   make([]int32, 0),
+		IsolateDevices: sortLogicIDs(isolateDevs),
+	}
+	if policyLevel == common.IsolateErrorLevel {
+		taskPlan.SkipReason = "task should be rescheduled as its devices are isolated"
+		return taskPlan, nil
+	}
+	if resetFlag, err := hnm.isTaskInReset(taskName); err != nil || resetFlag {
+		taskPlan.SkipReason = getTaskSkipReason(hnm.hotResetManager.IsExistFaultyDevInTask(taskName), err)
+		return taskPlan, nil
+	}
+	if taskPlan.ResetInfo, err = hnm.hotResetManager.GetTaskResetInfo(devFaultInfoList, policy, policy,
+		common.UnrecoveredStatus); err != nil {
+		return nil, err
+	}
+	if taskPlan.FaultInfo, err = hnm.hotResetManager.GetTaskFaultRankInfo(devFaultInfoList); err != nil {
+		return nil, err
+	}
+	resetDevs, err := hnm.hotResetManager.GetNeedResetDevList(devFaultInfoList)
+	if err != nil {
+		return nil, err
+	}
+	taskPlan.ResetDevices = sortLogicIDs(resetDevs)
+	return taskPlan, nil
+}
+
+func getTaskSkipReason(existFaultyDev bool, err error) string {
+	if err != nil {
+		return fmt.Sprintf("failed to get reset info cm of task, %v", err)
+	}
+	if existFaultyDev {
+		return "task is in reset by other node, isolation info would be written into reset info cm"
+	}
+	return "task is in reset"
+}
+
+// setGraceTolerancePlan save the plan, and log and write it into configmap when it changed
+func (hnm *HwAscend910Manager) setGraceTolerancePlan(plan *common.GraceTolerancePlan) {
+	hnm.gracePlanLock.Lock()
+	defer hnm.gracePlanLock.Unlock()
+	hnm.gracePlan = plan
+	data, err := json.Marshal(plan.Tasks)
+	if err != nil {
+		hwlog.RunLog.Errorf("failed to marshal grace tolerance plan, err: %v", err)
+		return
+	}
+	if string(data) == hnm.gracePlanData {
+		return
+	}
+	hnm.gracePlanData = string(data)
+	hwlog.RunLog.Infof("[dry-run] grace tolerance plan changed: %s", string(data))
+	if len(plan.ResetDevices) != 0 {
+		hwlog.RunLog.Warnf("[dry-run] hot reset of devices %v is skipped", plan.ResetDevices)
+	}
+	if !common.ParamOption.WriteGracePlanCM || hnm.GetKubeClient() == nil {
+		return
+	}
+	if err = hnm.GetKubeClient().WriteGracePlanIntoCM(plan); err != nil {
+		hwlog.RunLog.Errorf("failed to write grace tolerance plan into cm, err: %v", err)
+		// write again in next plan
+		hnm.gracePlanData = ""
+	}
+}
+
+func sortLogicIDs(devList map[int32]struct{}) []int32 {
+	logicIDs := make([]int32, 0, len(devList))
+	for logicID := range devList {
+		logicIDs = append(logicIDs, logicID)
+	}
+	sort.Slice(logicIDs, func(i, j int) bool {
+		return logicIDs[i] < logicIDs[j]
+	})
+	return logicIDs
+}
@@ -0,0 +1,94 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package device a series of device function
+package device
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+	"k8s.io/api/core/v1"
+
+	"Ascend-device-plugin/pkg/common"
+	"Ascend-device-plugin/pkg/kubeclient"
+)
+
+func newDryRunTaskDevInfo(logicID int32, policy string) *common.TaskDevInfo {
+	return &common.TaskDevInfo{DevFaultInfo: common.DevFaultInfo{LogicId: logicID, Policy: policy}}
+}
+
+// TestPlanAllTask for test planAllTask
+func TestPlanAllTask(t *testing.T) {
+	option := common.ParamOption
+	defer func() {
+		common.ParamOption = option
+	}()
+	var updateCMTimes, writePlanTimes int
+	patches := mockGetCM()
+	defer patches.Reset()
+	patches.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "UpdateConfigMap",
+		func(_ *kubeclient.ClientK8s, _ *v1.ConfigMap) (*v1.ConfigMap, error) {
+			updateCMTimes++
+			return &v1.ConfigMap{}, nil
+		})
+	patches.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "WriteGracePlanIntoCM",
+		func(_ *kubeclient.ClientK8s, _ *common.GraceTolerancePlan) error {
+			writePlanTimes++
+			return nil
+		})
+	convey.Convey("test planAllTask", t, func() {
+		common.ParamOption.HotResetDryRun, common.ParamOption.WriteGracePlanCM = true, true
+		manager := createFake910Manager()
+		manager.SetKubeClient(&kubeclient.ClientK8s{})
+		manager.hotResetManager = &HotResetTools{
+			ringNum: common.Ascend910RingsNum,
+			allTaskDevFaultInfo: map[string][]*common.TaskDevInfo{
+				"reset-task": {newDryRunTaskDevInfo(chipPhyID1, common.ResetError),
+					newDryRunTaskDevInfo(chipPhyID2, common.EmptyError)},
+				"restart-task":  {newDryRunTaskDevInfo(chipPhyID5, common.RestartError)},
+				"isolate-task":  {newDryRunTaskDevInfo(chipPhyID6, common.IsolateError)},
+				"no-fault-task": {newDryRunTaskDevInfo(chipPhyID7, common.EmptyError)},
+			},
+			taskPod: map[string]v1.Pod{
+				"reset-task":    getSinglePod("pod1", nil),
+				"restart-task":  getSinglePod("pod2", nil),
+				"isolate-task":  getSinglePod("pod3", nil),
+				"no-fault-task": getSinglePod("pod4", nil),
+			},
+			resetTask:          map[string]struct{}{},
+			resetDev:           map[int32]struct{}{},
+			processPolicyTable: common.GetProcessPolicyTable(),
+		}
+		updateCMTimes, writePlanTimes = 0, 0
+		manager.planAllTask()
+		manager.planAllTask()
+		plan := manager.GetGraceTolerancePlan()
+		convey.So(plan, convey.ShouldNotBeNil)
+		convey.So(len(plan.Tasks), convey.ShouldEqual, 3)
+		convey.So(plan.Tasks[0].TaskName, convey.ShouldEqual, "isolate-task")
+		convey.So(plan.Tasks[0].SkipReason, convey.ShouldNotBeEmpty)
+		convey.So(plan.Tasks[0].IsolateDevices, convey.ShouldResemble, []int32{chipPhyID6})
+		convey.So(plan.Tasks[1].TaskName, convey.ShouldEqual, "reset-task")
+		convey.So(plan.Tasks[1].ResetDevices, convey.ShouldResemble, []int32{chipPhyID0})
+		convey.So(len(plan.Tasks[1].ResetInfo.RankList), convey.ShouldEqual, len(
+			manager.hotResetManager.GetAllTaskDevFaultInfoList()["reset-task"]))
+		convey.So(plan.Tasks[2].ResetDevices, convey.ShouldResemble, []int32{chipPhyID4})
+		convey.So(plan.ResetDevices, convey.ShouldResemble, []int32{chipPhyID0})
+		convey.So(manager.hotResetManager.GetDevListInReset(), convey.ShouldBeEmpty)
+		convey.So(updateCMTimes, convey.ShouldEqual, 0)
+		convey.So(writePlanTimes, convey.ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,148 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package device a series of device function
+package device
+
+import (
+	"fmt"
+	"time"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"Ascend-device-plugin/pkg/common"
+	"Ascend-device-plugin/pkg/kubeclient"
+)
+
+// reportResetProgress write the reset progress of current node into reset info cm. The failure is only logged,
+// because the barrier of other nodes is bounded by time
+func (hnm *HwAscend910Manager) reportResetProgress(taskName, status string) {
+	pod, err := hnm.hotResetManager.GetTaskPod(taskName)
+	if err != nil {
+		hwlog.RunLog.Warnf("failed to get task pod, err: %v", err)
+		return
+	}
+	if _, err = hnm.client.WriteResetProgressIntoCM(taskName, pod.Namespace, status); err != nil {
+		hwlog.RunLog.Warnf("failed to write reset progress %s of task %s, err: %v", status, taskName, err)
+	}
+}
+
+// reportIdleResetProgress report the progress of current node when the task is reset by other nodes only, so that
+// the barrier of the resetting nodes does not wait for current node. It is reported again when other node starts
+// a reset after the last report
+func (hnm *HwAscend910Manager) reportIdleResetProgress(taskName, status string) {
+	if hnm.hotResetManager.IsCurNodeTaskInReset(taskName) {
+		return
+	}
+	pod, err := hnm.hotResetManager.GetTaskPod(taskName)
+	if err != nil {
+		hwlog.RunLog.Warnf("failed to get task pod, err: %v", err)
+		return
+	}
+	resetCM, err := hnm.client.GetConfigMap(common.ResetInfoCMNamePrefix+taskName, pod.Namespace)
+	if err != nil {
+		return
+	}
+	if rankList, err := getResetInfoData(resetCM); err != nil || len(rankList) == 0 {
+		return
+	}
+	progress, err := kubeclient.GetResetProgress(resetCM)
+	if err != nil {
+		hwlog.RunLog.Warnf("failed to get reset progress of task %s, err: %v", taskName, err)
+		return
+	}
+	if !isResetProgressOutdated(progress, hnm.client.NodeName, status) {
+		return
+	}
+	hwlog.RunLog.Infof("task %s is reset by other nodes, report %s progress of current node", taskName, status)
+	hnm.reportResetProgress(taskName, status)
+}
+
+func isResetProgressOutdated(progress map[string]*common.NodeResetProgress, nodeName, status string) bool {
+	nodeProgress, ok := progress[nodeName]
+	if !ok || nodeProgress == nil || nodeProgress.Status != status {
+		return true
+	}
+	for otherNodeName, otherProgress := range progress {
+		if otherNodeName != nodeName && otherProgress != nil && otherProgress.Status == common.UnrecoveredStatus &&
+			otherProgress.UpdateTime > nodeProgress.UpdateTime {
+			return true
+		}
+	}
+	return false
+}
+
+// waitResetBarrier wait all nodes of the task to report reset done or escalation to isolation since the reset
+// started, the node which has not reported is unfinished. The wait is bounded by WaitFlushingCMTime. When the nodes
+// of task can not be listed, the barrier is on the nodes which have reported progress, so the failure of listing is
+// not taken as the timeout of the barrier
+func (hnm *HwAscend910Manager) waitResetBarrier(taskName string, resetStartTime int64) error {
+	pod, err := hnm.hotResetManager.GetTaskPod(taskName)
+	if err != nil {
+		return err
+	}
+	var taskNodes sets.String
+	var unfinishedNodes []string
+	if err = wait.PollImmediate(time.Second, common.WaitFlushingCMTime*time.Second, func() (bool, error) {
+		progress, err := hnm.client.GetResetProgressFromCM(taskName, pod.Namespace)
+		if err != nil {
+			hwlog.RunLog.Warnf("failed to get reset progress of task %s, err: %v", taskName, err)
+			return false, nil
+		}
+		if taskNodes == nil {
+			if nodeNames, err := hnm.client.GetTaskNodeNames(taskName, pod.Namespace); err == nil {
+				taskNodes = nodeNames.Insert(hnm.client.NodeName)
+			} else {
+				hwlog.RunLog.Warnf("failed to get nodes of task %s, wait for the reported nodes, err: %v",
+					taskName, err)
+			}
+		}
+		barrierNodes := taskNodes
+		if barrierNodes == nil {
+			barrierNodes = getReportedResetNodes(progress, hnm.client.NodeName)
+		}
+		unfinishedNodes = getUnfinishedResetNodes(barrierNodes, progress, resetStartTime)
+		return len(unfinishedNodes) == 0, nil
+	}); err != nil {
+		return fmt.Errorf("reset on nodes %v is not finished in %d seconds", unfinishedNodes,
+			common.WaitFlushingCMTime)
+	}
+	hwlog.RunLog.Infof("reset on all nodes of task %s is finished", taskName)
+	return nil
+}
+
+// getReportedResetNodes get the nodes which have reported reset progress and the current node
+func getReportedResetNodes(progress map[string]*common.NodeResetProgress, nodeName string) sets.String {
+	nodeNames := sets.NewString(nodeName)
+	for reportedNode := range progress {
+		nodeNames.Insert(reportedNode)
+	}
+	return nodeNames
+}
+
+// getUnfinishedResetNodes get the task nodes which has not reported done or failed since the reset started
+func getUnfinishedResetNodes(taskNodes sets.String, progress map[string]*common.NodeResetProgress,
+	resetStartTime int64) []string {
+	unfinishedNodes := make([]string, 0, taskNodes.Len())
+	for _, nodeName := range taskNodes.List() {
+		nodeProgress, ok := progress[nodeName]
+		if !ok || nodeProgress == nil || nodeProgress.UpdateTime < resetStartTime ||
+			(nodeProgress.Status != common.RecoveredStatus && nodeProgress.Status != common.RecoverFailedStatus) {
+			unfinishedNodes = append(unfinishedNodes, nodeName)
+		}
+	}
+	return unfinishedNodes
+}
@@ -0,0 +1,119 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package device a series of device function
+package device
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/smartystreets/goconvey/convey"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"Ascend-device-plugin/pkg/common"
+	"Ascend-device-plugin/pkg/kubeclient"
+)
+
+// TestWaitResetBarrier for test waitResetBarrier
+func TestWaitResetBarrier(t *testing.T) {
+	waitTime := common.WaitFlushingCMTime
+	defer func() {
+		common.WaitFlushingCMTime = waitTime
+	}()
+	var progressList []map[string]*common.NodeResetProgress
+	var listNodeErr error
+	patches := gomonkey.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "GetResetProgressFromCM",
+		func(_ *kubeclient.ClientK8s, _, _ string) (map[string]*common.NodeResetProgress, error) {
+			progress := progressList[0]
+			if len(progressList) > 1 {
+				progressList = progressList[1:]
+			}
+			return progress, nil
+		}).ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "GetTaskNodeNames",
+		func(_ *kubeclient.ClientK8s, _, _ string) (sets.String, error) {
+			return sets.NewString("node1", "node2"), listNodeErr
+		})
+	defer patches.Reset()
+	convey.Convey("test waitResetBarrier", t, func() {
+		common.WaitFlushingCMTime = 1
+		manager := createFake910Manager()
+		manager.SetKubeClient(&kubeclient.ClientK8s{NodeName: "node1"})
+		manager.hotResetManager = &HotResetTools{taskPod: map[string]v1.Pod{"task": getSinglePod("pod", nil)}}
+		resetting := map[string]*common.NodeResetProgress{"node1": {Status: common.RecoveredStatus},
+			"node2": {Status: common.UnrecoveredStatus}}
+		convey.Convey("barrier is reached when all nodes are done or isolated", func() {
+			progressList = []map[string]*common.NodeResetProgress{resetting, {
+				"node1": {Status: common.RecoveredStatus}, "node2": {Status: common.RecoverFailedStatus}}}
+			convey.So(manager.waitResetBarrier("task", 0), convey.ShouldBeNil)
+		})
+		convey.Convey("wait is bounded when node is still resetting", func() {
+			progressList = []map[string]*common.NodeResetProgress{resetting}
+			convey.So(manager.waitResetBarrier("task", 0), convey.ShouldNotBeNil)
+		})
+		convey.Convey("node of task which has not reported is unfinished", func() {
+			progressList = []map[string]*common.NodeResetProgress{{"node1": {Status: common.RecoveredStatus}}}
+			convey.So(manager.waitResetBarrier("task", 0), convey.ShouldNotBeNil)
+		})
+		convey.Convey("barrier is on the reported nodes when nodes of task can not be listed", func() {
+			listNodeErr = errors.New("list pods failed")
+			defer func() {
+				listNodeErr = nil
+			}()
+			progressList = []map[string]*common.NodeResetProgress{{"node1": {Status: common.RecoveredStatus}}}
+			convey.So(manager.waitResetBarrier("task", 0), convey.ShouldBeNil)
+			progressList = []map[string]*common.NodeResetProgress{resetting}
+			convey.So(manager.waitResetBarrier("task", 0), convey.ShouldNotBeNil)
+		})
+		convey.Convey("task without pod is failed", func() {
+			convey.So(manager.waitResetBarrier("no-task", 0), convey.ShouldNotBeNil)
+		})
+	})
+}
+
+// TestGetUnfinishedResetNodes for test getUnfinishedResetNodes
+func TestGetUnfinishedResetNodes(t *testing.T) {
+	const resetStartTime = 100
+	convey.Convey("test getUnfinishedResetNodes", t, func() {
+		convey.So(getUnfinishedResetNodes(sets.NewString(), nil, resetStartTime), convey.ShouldBeEmpty)
+		convey.So(getUnfinishedResetNodes(sets.NewString("node0", "node1", "node2", "node3", "node4", "node5"),
+			map[string]*common.NodeResetProgress{
+				"node3": {Status: common.UnrecoveredStatus, UpdateTime: resetStartTime},
+				"node2": {Status: common.RecoverFailedStatus, UpdateTime: resetStartTime},
+				"node1": nil,
+				"node0": {Status: common.RecoveredStatus, UpdateTime: resetStartTime + 1},
+				"node4": {Status: common.RecoveredStatus, UpdateTime: resetStartTime - 1},
+				"other": {Status: common.UnrecoveredStatus, UpdateTime: resetStartTime},
+			}, resetStartTime), convey.ShouldResemble, []string{"node1", "node3", "node4", "node5"})
+	})
+}
+
+// TestIsResetProgressOutdated for test isResetProgressOutdated
+func TestIsResetProgressOutdated(t *testing.T) {
+	const updateTime = 100
+	convey.Convey("test isResetProgressOutdated", t, func() {
+		progress := map[string]*common.NodeResetProgress{
+			"node0": {Status: common.RecoveredStatus, UpdateTime: updateTime},
+			"node1": {Status: common.UnrecoveredStatus, UpdateTime: updateTime},
+		}
+		convey.So(isResetProgressOutdated(progress, "node0", common.RecoveredStatus), convey.ShouldBeFalse)
+		convey.So(isResetProgressOutdated(progress, "node0", common.RecoverFailedStatus), convey.ShouldBeTrue)
+		convey.So(isResetProgressOutdated(progress, "node2", common.RecoveredStatus), convey.ShouldBeTrue)
+		progress["node1"].UpdateTime = updateTime + 1
+		convey.So(isResetProgressOutdated(progress, "node0", common.RecoveredStatus), convey.ShouldBeTrue)
+	})
+}
@@ -30,6 +30,8 @@ import (
 // HotResetManager hot reset manager
 type HotResetManager interface {
 	GetRingNum() int
+	GetTopology() *ChipTopology
+	SetTopology(*ChipTopology)
 	GetDevIdList(string) []int32
 	GetTaskDevFaultInfoList(string) ([]*common.TaskDevInfo, error)
 	GetTaskPod(string) (v1.Pod, error)
@@ -59,6 +61,7 @@ type HotResetManager interface {
 	IsExistFaultyDevInTask(string) bool
 	DeepCopyDevInfo(*common.TaskDevInfo) *common.TaskDevInfo
 	DeepCopyDevFaultInfoList([]*common.TaskDevInfo) []*common.TaskDevInfo
+	GetCacheInfo() *common.HotResetCacheInfo
 }
 
 // HotResetTools hot reset tool
@@ -72,39 +75,22 @@ type HotResetTools struct {
 	resetTask           map[string]struct{}
 	resetDev            map[int32]struct{}
 	processPolicyTable  map[string]int
+	// topology the ring topology of chips, the ring is derived from ringNum when it is nil
+	topology *ChipTopology
 }
 
 // NewHotResetManager create HotResetManager and init data
 func NewHotResetManager(devUsage string) HotResetManager {
-	var ringNumber int
-	switch common.ParamOption.RealCardType {
-	case common.Ascend910:
-		ringNumber = common.Ascend910RingsNum
-	case common.Ascend910B:
-		switch devUsage {
-		case common.Infer:
-			ringNumber = common.Ascend910BRingsNumInfer
-		case common.Train:
-			ringNumber = common.Ascend910BRingsNumTrain
-		default:
-			return nil
-		}
-	default:
+	ringNumber := GetRingNumByUsage(devUsage)
+	if ringNumber == 0 {
 		return nil
 	}
 	return &HotResetTools{
-		ringNum:         ringNumber,
-		resetTask:       map[string]struct{}{},
-		resetDev:        map[int32]struct{}{},
-		faultDev2PodMap: map[int32]v1.Pod{},
-		processPolicyTable: map[string]int{
-			common.EmptyError:          common.EmptyErrorLevel,
-			common.IgnoreError:         common.IgnoreErrorLevel,
-			common.RestartRequestError: common.RestartRequestErrorLevel,
-			common.RestartError:        common.RestartErrorLevel,
-			common.ResetError:          common.ResetErrorLevel,
-			common.IsolateError:        common.IsolateErrorLevel,
-		},
+		ringNum:            ringNumber,
+		resetTask:          map[string]struct{}{},
+		resetDev:           map[int32]struct{}{},
+		faultDev2PodMap:    map[int32]v1.Pod{},
+		processPolicyTable: common.GetProcessPolicyTable(),
 	}
 }
 
@@ -116,14 +102,49 @@ func getChipCountOnRing() int {
 	return ring[common.ParamOption.RealCardType]
 }
 
+// GetRingNumByUsage get device num in a ring by real card type and device usage, 0 means not support ring
+func GetRingNumByUsage(devUsage string) int {
+	switch common.ParamOption.RealCardType {
+	case common.Ascend910:
+		return common.Ascend910RingsNum
+	case common.Ascend910B:
+		switch devUsage {
+		case common.Infer:
+			return common.Ascend910BRingsNumInfer
+		case common.Train:
+			return common.Ascend910BRingsNumTrain
+		default:
+			return 0
+		}
+	default:
+		return 0
+	}
+}
+
 // GetRingNum get device num in a ring
 func (hrt *HotResetTools) GetRingNum() int {
+	if hrt.topology != nil {
+		return hrt.topology.RingSize()
+	}
 	if hrt.ringNum == 0 {
 		return getChipCountOnRing()
 	}
 	return hrt.ringNum
 }
 
+// GetTopology return the ring topology of chips
+func (hrt *HotResetTools) GetTopology() *ChipTopology {
+	if hrt.topology != nil {
+		return hrt.topology
+	}
+	return newDefaultTopology(hrt.GetRingNum())
+}
+
+// SetTopology set the ring topology of chips built from the present chips
+func (hrt *HotResetTools) SetTopology(topology *ChipTopology) {
+	hrt.topology = topology
+}
+
 // GetTaskDevFaultInfoList return task device fault info list
 func (hrt *HotResetTools) GetTaskDevFaultInfoList(taskName string) ([]*common.TaskDevInfo, error) {
 	taskDevFaultInfoList, ok := hrt.allTaskDevFaultInfo[taskName]
@@ -152,20 +173,9 @@ func (hrt *HotResetTools) GetDevListInReset() map[int32]struct{} {
 	return hrt.resetDev
 }
 
-// GetDevProcessPolicy return the policy of device with fault
+// GetDevProcessPolicy return the policy of device with fault, which is customized in fault code configmap
 func (hrt *HotResetTools) GetDevProcessPolicy(faultType string) string {
-	switch faultType {
-	case common.NormalNPU, common.NotHandleFault:
-		return common.EmptyError
-	case common.RestartRequest:
-		return common.RestartRequestError
-	case common.RestartBusiness:
-		return common.RestartError
-	case common.FreeRestartNPU, common.RestartNPU:
-		return common.ResetError
-	default:
-		return common.IsolateError
-	}
+	return common.GetDevProcessPolicy(faultType)
 }
 
 // GetTaskProcessPolicy return a task process policy
@@ -226,6 +236,7 @@ func (hrt *HotResetTools) GetDevListByPolicyLevel(devFaultInfoList []*common.Tas
 // GetNeedResetDevList return device logic id list to be reset
 func (hrt *HotResetTools) GetNeedResetDevList(devFaultInfoList []*common.TaskDevInfo) (map[int32]struct{}, error) {
 	needResetDevList := make(map[int32]struct{})
+	topology := hrt.GetTopology()
 	for _, devFaultInfo := range devFaultInfoList {
 		policyType, ok := hrt.processPolicyTable[devFaultInfo.Policy]
 		if !ok {
@@ -236,10 +247,7 @@ func (hrt *HotResetTools) GetNeedResetDevList(devFaultInfoList []*common.TaskDev
 		}
 		if policyType == common.RestartErrorLevel || policyType == common.ResetErrorLevel ||
 			policyType == common.RestartRequestErrorLevel {
-			resetIndex := devFaultInfo.LogicId / int32(hrt.GetRingNum())
-			if _, ok := needResetDevList[devFaultInfo.LogicId]; !ok {
-				needResetDevList[resetIndex*int32(hrt.GetRingNum())] = struct{}{}
-			}
+			needResetDevList[topology.GetRingLeader(devFaultInfo.LogicId)] = struct{}{}
 		}
 	}
 	return needResetDevList, nil
@@ -249,6 +257,7 @@ func (hrt *HotResetTools) GetNeedResetDevList(devFaultInfoList []*common.TaskDev
 func (hrt *HotResetTools) GetTaskResetInfo(devFaultInfoList []*common.TaskDevInfo, policy, initPolicy,
 	status string) (*common.TaskResetInfo, error) {
 	faultRing := make(map[int]struct{}, common.RingSum)
+	topology := hrt.GetTopology()
 	var rankList []*common.TaskDevInfo
 	for _, devFaultInfo := range devFaultInfoList {
 		policyType, ok := hrt.processPolicyTable[devFaultInfo.Policy]
@@ -262,11 +271,11 @@ func (hrt *HotResetTools) GetTaskResetInfo(devFaultInfoList []*common.TaskDevInf
 			policyType != common.RestartRequestErrorLevel {
 			continue
 		}
-		ringStartIndex := int(devFaultInfo.LogicId) / hrt.GetRingNum()
+		ringStartIndex := topology.GetRingIndex(devFaultInfo.LogicId)
 		faultRing[ringStartIndex] = struct{}{}
 	}
 	for _, devInfo := range devFaultInfoList {
-		ringIndex := int(devInfo.LogicId) / hrt.GetRingNum()
+		ringIndex := topology.GetRingIndex(devInfo.LogicId)
 		if _, ok := faultRing[ringIndex]; !ok {
 			continue
 		}
@@ -287,17 +296,18 @@ func (hrt *HotResetTools) GetTaskFaultRankInfo(devFaultInfoList []*common.TaskDe
 		FaultRank: make([]int, 0),
 	}
 	faultRing := make(map[int]struct{}, common.RingSum)
+	topology := hrt.GetTopology()
 	for _, devFaultInfo := range devFaultInfoList {
 		policy := hrt.processPolicyTable[devFaultInfo.Policy]
 		if policy != common.RestartErrorLevel && policy != common.ResetErrorLevel &&
 			policy != common.RestartRequestErrorLevel {
 			continue
 		}
-		ringStartIndex := int(devFaultInfo.LogicId) / hrt.GetRingNum()
+		ringStartIndex := topology.GetRingIndex(devFaultInfo.LogicId)
 		faultRing[ringStartIndex] = struct{}{}
 	}
 	for _, devInfo := range devFaultInfoList {
-		ringIndex := int(devInfo.LogicId) / hrt.GetRingNum()
+		ringIndex := topology.GetRingIndex(devInfo.LogicId)
 		if _, ok := faultRing[ringIndex]; !ok {
 			continue
 		}
@@ -539,3 +549,40 @@ func (hrt *HotResetTools) DeepCopyDevFaultInfoList(devFaultInfoList []*common.Ta
 	}
 	return newDevFaultInfoList
 }
+
+// GetCacheInfo return the snapshot of hot reset caches
+func (hrt *HotResetTools) GetCacheInfo() *common.HotResetCacheInfo {
+	info := &common.HotResetCacheInfo{
+		RingNum:            hrt.GetRingNum(),
+		TaskDevList:        make(map[string][]int32, len(hrt.allTaskDevList)),
+		TaskDevFaultInfo:   make(map[string][]*common.TaskDevInfo, len(hrt.allTaskDevFaultInfo)),
+		GlobalDevFaultInfo: make(map[int32]*common.DevFaultInfo, len(hrt.globalDevFaultInfo)),
+		ResetTasks:         make([]string, 0, len(hrt.resetTask)),
+		ResetDevices:       make([]int32, 0, len(hrt.resetDev)),
+		FaultDev2Pod:       make(map[int32]string, len(hrt.faultDev2PodMap)),
+	}
+	for taskName, devList := range hrt.allTaskDevList {
+		info.TaskDevList[taskName] = append([]int32{}, devList...)
+	}
+	for taskName, devFaultInfoList := range hrt.allTaskDevFaultInfo {
+		info.TaskDevFaultInfo[taskName] = hrt.DeepCopyDevFaultInfoList(devFaultInfoList)
+	}
+	for logicID, devFaultInfo := range hrt.globalDevFaultInfo {
+		devFaultInfoCopy := *devFaultInfo
+		info.GlobalDevFaultInfo[logicID] = &devFaultInfoCopy
+	}
+	for taskName := range hrt.resetTask {
+		info.ResetTasks = append(info.ResetTasks, taskName)
+	}
+	sort.Strings(info.ResetTasks)
+	for logicID := range hrt.resetDev {
+		info.ResetDevices = append(info.ResetDevices, logicID)
+	}
+	sort.Slice(info.ResetDevices, func(i, j int) bool {
+		return info.ResetDevices[i] < info.ResetDevices[j]
+	})
+	for logicID, pod := range hrt.faultDev2PodMap {
+		info.FaultDev2Pod[logicID] = pod.Namespace + "/" + pod.Name
+	}
+	return info
+}
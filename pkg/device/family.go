@@ -0,0 +1,109 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package device a series of device function
+package device
+
+import (
+	"regexp"
+	"sort"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/devmanager"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+// chipNamePatterns the patterns of chip name of each device type, the earlier pattern is matched first
+var chipNamePatterns = []struct {
+	devType string
+	pattern *regexp.Regexp
+}{
+	{devType: common.Ascend910B, pattern: regexp.MustCompile(`^910B\d`)},
+	{devType: common.Ascend910, pattern: regexp.MustCompile(`^910`)},
+	{devType: common.Ascend310P, pattern: regexp.MustCompile(`^310P`)},
+	{devType: common.Ascend310B, pattern: regexp.MustCompile(`^310B`)},
+	{devType: common.Ascend310, pattern: regexp.MustCompile(`^310`)},
+}
+
+// familyDmgr is the view of devmanager which only contains the chips of one chip family, the other methods are
+// served by the devmanager of node, such as reset and fault subscription which identify the chip by logic id
+type familyDmgr struct {
+	devmanager.DeviceInterface
+	devType  string
+	logicIDs []int32
+}
+
+// GetDevType get the device type of the chip family
+func (f *familyDmgr) GetDevType() string {
+	return f.devType
+}
+
+// GetDeviceList get the chips of the chip family
+func (f *familyDmgr) GetDeviceList() (int32, []int32, error) {
+	return int32(len(f.logicIDs)), append([]int32{}, f.logicIDs...), nil
+}
+
+// GetDevTypeByChipName get device type by the chip name, like 910B3 or 310P3, empty means unknown chip
+func GetDevTypeByChipName(chipName string) string {
+	for _, chipNamePattern := range chipNamePatterns {
+		if chipNamePattern.pattern.MatchString(chipName) {
+			return chipNamePattern.devType
+		}
+	}
+	return ""
+}
+
+// SplitChipFamilies split the chips on node by the device type of chip name, a devmanager is returned for each chip
+// family and the training chip family is the first. The devmanager of node is returned when all chips are the same
+// device type, or the chips can not be listed
+func SplitChipFamilies(dmgr devmanager.DeviceInterface) []devmanager.DeviceInterface {
+	_, logicIDs, err := dmgr.GetDeviceList()
+	if err != nil {
+		hwlog.RunLog.Warnf("get device list failed, chips are regarded as %s, %v", dmgr.GetDevType(), err)
+		return []devmanager.DeviceInterface{dmgr}
+	}
+	familyLogicIDs := make(map[string][]int32, len(chipNamePatterns))
+	for _, logicID := range logicIDs {
+		devType := dmgr.GetDevType()
+		if chip, err := dmgr.GetChipInfo(logicID); err == nil && GetDevTypeByChipName(chip.Name) != "" {
+			devType = GetDevTypeByChipName(chip.Name)
+		}
+		familyLogicIDs[devType] = append(familyLogicIDs[devType], logicID)
+	}
+	if len(familyLogicIDs) <= 1 {
+		return []devmanager.DeviceInterface{dmgr}
+	}
+	devTypes := make([]string, 0, len(familyLogicIDs))
+	for devType := range familyLogicIDs {
+		devTypes = append(devTypes, devType)
+	}
+	sort.Slice(devTypes, func(i, j int) bool {
+		if isTrainDevType(devTypes[i]) != isTrainDevType(devTypes[j]) {
+			return isTrainDevType(devTypes[i])
+		}
+		return devTypes[i] < devTypes[j]
+	})
+	familyDmgrs := make([]devmanager.DeviceInterface, 0, len(devTypes))
+	for _, devType := range devTypes {
+		hwlog.RunLog.Infof("found chip family %s, logic ids: %v", devType, familyLogicIDs[devType])
+		familyDmgrs = append(familyDmgrs, &familyDmgr{DeviceInterface: dmgr, devType: devType,
+			logicIDs: familyLogicIDs[devType]})
+	}
+	return familyDmgrs
+}
+
+func isTrainDevType(devType string) bool {
+	return devType == common.Ascend910 || devType == common.Ascend910B
+}
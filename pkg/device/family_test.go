@@ -0,0 +1,75 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package device a series of device function
+package device
+
+import (
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+	"huawei.com/npu-exporter/v5/devmanager"
+
+	"Ascend-device-plugin/pkg/common"
+	"Ascend-device-plugin/pkg/simulator"
+)
+
+const mixedScenario = `
+devType: Ascend310P
+chips:
+- {logicId: 0, phyId: 0, cardId: 0}
+- {logicId: 1, phyId: 1, cardId: 1, chipName: 910B3}
+- {logicId: 2, phyId: 2, cardId: 2}
+`
+
+// TestGetDevTypeByChipName for test GetDevTypeByChipName
+func TestGetDevTypeByChipName(t *testing.T) {
+	convey.Convey("test GetDevTypeByChipName", t, func() {
+		convey.So(GetDevTypeByChipName("910B3"), convey.ShouldEqual, common.Ascend910B)
+		convey.So(GetDevTypeByChipName("910ProB"), convey.ShouldEqual, common.Ascend910)
+		convey.So(GetDevTypeByChipName("310P3"), convey.ShouldEqual, common.Ascend310P)
+		convey.So(GetDevTypeByChipName("310B1"), convey.ShouldEqual, common.Ascend310B)
+		convey.So(GetDevTypeByChipName("310"), convey.ShouldEqual, common.Ascend310)
+		convey.So(GetDevTypeByChipName("unknown"), convey.ShouldEqual, "")
+	})
+}
+
+// TestSplitChipFamilies for test SplitChipFamilies
+func TestSplitChipFamilies(t *testing.T) {
+	convey.Convey("test SplitChipFamilies", t, func() {
+		convey.Convey("chips of the same device type are not split", func() {
+			dmgr := &devmanager.DeviceManagerMock{}
+			familyDmgrs := SplitChipFamilies(dmgr)
+			convey.So(len(familyDmgrs), convey.ShouldEqual, 1)
+			convey.So(familyDmgrs[0], convey.ShouldEqual, dmgr)
+		})
+		convey.Convey("chips of mixed chip families are split and training family is the first", func() {
+			scenario, err := simulator.ParseScenario([]byte(mixedScenario))
+			convey.So(err, convey.ShouldBeNil)
+			sim, err := simulator.NewSimulator(scenario)
+			convey.So(err, convey.ShouldBeNil)
+			familyDmgrs := SplitChipFamilies(sim)
+			convey.So(len(familyDmgrs), convey.ShouldEqual, 2)
+			convey.So(familyDmgrs[0].GetDevType(), convey.ShouldEqual, common.Ascend910B)
+			convey.So(familyDmgrs[1].GetDevType(), convey.ShouldEqual, common.Ascend310P)
+			num, logicIDs, err := familyDmgrs[1].GetDeviceList()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(num, convey.ShouldEqual, 2)
+			convey.So(logicIDs, convey.ShouldResemble, []int32{0, 2})
+			phyID, err := familyDmgrs[0].GetPhysicIDFromLogicID(1)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(phyID, convey.ShouldEqual, 1)
+		})
+	})
+}
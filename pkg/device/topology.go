@@ -0,0 +1,193 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package device a series of device function
+package device
+
+import (
+	"fmt"
+	"sort"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	npuCommon "huawei.com/npu-exporter/v5/devmanager/common"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+// TopologySource the source of chip and board info to build the HCCS topology of chips, it is implemented by
+// devmanager.DeviceInterface and can be replaced in test
+type TopologySource interface {
+	GetDeviceList() (int32, []int32, error)
+	GetPhysicIDFromLogicID(int32) (int32, error)
+	GetBoardInfo(int32) (npuCommon.BoardInfo, error)
+}
+
+// ChipTopology the HCCS ring topology of chips on node. The chips on a ring are interconnected by HCCS, they are
+// hot reset together and preferred to be allocated together
+type ChipTopology struct {
+	// ringSize the chip number of a fully populated ring
+	ringSize int
+	// rings key: ring index, value: the sorted logic id of present chips on the ring
+	rings map[int][]int32
+	// ringIndex key: logic id, value: ring index
+	ringIndex map[int32]int
+	// byPhysicID whether the chips are grouped by physical id, otherwise by logic id
+	byPhysicID bool
+}
+
+// boardRingSizes key: board id, value: the chip number of a fully populated ring on the board, 1 means the chips
+// of board are not interconnected. The board not in it uses the ring size of chip family and usage
+var boardRingSizes = map[uint32]int{
+	common.A300IA2BoardId: 1,
+}
+
+// BuildTopology build the ring topology of chips. The HCCS links are not probed, because devmanager has no query of
+// the interconnect topology. The ring size is looked up by the board id in boardRingSizes, defaultRingSize, which is
+// got from the chip family and usage, is used for the board not in it, and 0 means the chips are not interconnected
+// by ring. Chips of consecutive physical id are on a ring, the ring of partially populated board only contains the
+// present chips, the absent chips never change the ring size. The chips are grouped by logic id when the physical id
+// can not be got
+func BuildTopology(source TopologySource, defaultRingSize int) *ChipTopology {
+	if defaultRingSize <= 0 {
+		return nil
+	}
+	_, logicIDs, err := source.GetDeviceList()
+	if err != nil || len(logicIDs) == 0 {
+		hwlog.RunLog.Warnf("get device list failed, use default topology, err: %v", err)
+		return newDefaultTopology(defaultRingSize)
+	}
+	phyIDs := make(map[int32]int32, len(logicIDs))
+	for _, logicID := range logicIDs {
+		phyID, err := source.GetPhysicIDFromLogicID(logicID)
+		if err != nil {
+			hwlog.RunLog.Warnf("get physic id of logic id %d failed, use default topology, err: %v", logicID, err)
+			return newChipTopology(defaultRingSize, logicIDs, false)
+		}
+		phyIDs[logicID] = phyID
+	}
+	ringSize := getBoardRingSize(source, logicIDs[0], defaultRingSize)
+	topology := newChipTopology(ringSize, nil, true)
+	for _, logicID := range logicIDs {
+		topology.addChip(logicID, int(phyIDs[logicID])/ringSize)
+	}
+	topology.sortRings()
+	return topology
+}
+
+// getBoardRingSize get the ring size of board by board info
+func getBoardRingSize(source TopologySource, logicID int32, defaultRingSize int) int {
+	boardInfo, err := source.GetBoardInfo(logicID)
+	if err != nil {
+		hwlog.RunLog.Warnf("get board info of logic id %d failed, use ring size %d, err: %v", logicID,
+			defaultRingSize, err)
+		return defaultRingSize
+	}
+	if ringSize, exist := boardRingSizes[boardInfo.BoardId]; exist {
+		return ringSize
+	}
+	hwlog.RunLog.Debugf("ring size of board %#x is unknown, use ring size %d", boardInfo.BoardId, defaultRingSize)
+	return defaultRingSize
+}
+
+// newDefaultTopology the topology which groups chips by logic id, nil means the chips are not interconnected by ring
+func newDefaultTopology(ringSize int) *ChipTopology {
+	if ringSize <= 0 {
+		return nil
+	}
+	return newChipTopology(ringSize, nil, false)
+}
+
+func newChipTopology(ringSize int, logicIDs []int32, byPhysicID bool) *ChipTopology {
+	topology := &ChipTopology{
+		ringSize:   ringSize,
+		rings:      make(map[int][]int32, common.MaxDevicesNum),
+		ringIndex:  make(map[int32]int, common.MaxDevicesNum),
+		byPhysicID: byPhysicID,
+	}
+	for _, logicID := range logicIDs {
+		topology.addChip(logicID, int(logicID)/ringSize)
+	}
+	topology.sortRings()
+	return topology
+}
+
+func (t *ChipTopology) addChip(logicID int32, ringIndex int) {
+	t.ringIndex[logicID] = ringIndex
+	t.rings[ringIndex] = append(t.rings[ringIndex], logicID)
+}
+
+func (t *ChipTopology) sortRings() {
+	for _, ring := range t.rings {
+		sort.Slice(ring, func(i, j int) bool {
+			return ring[i] < ring[j]
+		})
+	}
+}
+
+// RingSize get the chip number of a fully populated ring, 0 means the chips are not interconnected by ring
+func (t *ChipTopology) RingSize() int {
+	if t == nil {
+		return 0
+	}
+	return t.ringSize
+}
+
+// GetRingIndex get the index of ring which the chip is on
+func (t *ChipTopology) GetRingIndex(logicID int32) int {
+	if t == nil || t.ringSize <= 0 {
+		return 0
+	}
+	if ringIndex, ok := t.ringIndex[logicID]; ok {
+		return ringIndex
+	}
+	return int(logicID) / t.ringSize
+}
+
+// GetRing get the sorted logic id of chips on the ring which the chip is on
+func (t *ChipTopology) GetRing(logicID int32) []int32 {
+	if t == nil || t.ringSize <= 0 {
+		return []int32{logicID}
+	}
+	if ringIndex, ok := t.ringIndex[logicID]; ok {
+		return append([]int32{}, t.rings[ringIndex]...)
+	}
+	ring := make([]int32, 0, t.ringSize)
+	startID := logicID / int32(t.ringSize) * int32(t.ringSize)
+	for id := startID; id < startID+int32(t.ringSize); id++ {
+		ring = append(ring, id)
+	}
+	return ring
+}
+
+// GetRingLeader get the first chip on the ring which the chip is on, hot reset of the ring is executed on it
+func (t *ChipTopology) GetRingLeader(logicID int32) int32 {
+	return t.GetRing(logicID)[0]
+}
+
+// String return the rings of topology, such as "ringSize: 4, rings: [[0 1 2 3] [4 5]], byPhysicID: true"
+func (t *ChipTopology) String() string {
+	if t == nil {
+		return "no ring"
+	}
+	ringIndexes := make([]int, 0, len(t.rings))
+	for ringIndex := range t.rings {
+		ringIndexes = append(ringIndexes, ringIndex)
+	}
+	sort.Ints(ringIndexes)
+	rings := make([][]int32, 0, len(ringIndexes))
+	for _, ringIndex := range ringIndexes {
+		rings = append(rings, t.rings[ringIndex])
+	}
+	return fmt.Sprintf("ringSize: %d, rings: %v, byPhysicID: %v", t.ringSize, rings, t.byPhysicID)
+}
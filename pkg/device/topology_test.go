@@ -0,0 +1,123 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package device a series of device function
+package device
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+	npuCommon "huawei.com/npu-exporter/v5/devmanager/common"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+// fakeTopologySource key of phyIDs: logic id, value: physical id
+type fakeTopologySource struct {
+	phyIDs   map[int32]int32
+	boardID  uint32
+	listErr  error
+	phyIDErr error
+}
+
+func (s *fakeTopologySource) GetDeviceList() (int32, []int32, error) {
+	logicIDs := make([]int32, 0, len(s.phyIDs))
+	for logicID := int32(0); logicID < common.MaxDevicesNum; logicID++ {
+		if _, ok := s.phyIDs[logicID]; ok {
+			logicIDs = append(logicIDs, logicID)
+		}
+	}
+	return int32(len(logicIDs)), logicIDs, s.listErr
+}
+
+func (s *fakeTopologySource) GetPhysicIDFromLogicID(logicID int32) (int32, error) {
+	return s.phyIDs[logicID], s.phyIDErr
+}
+
+func (s *fakeTopologySource) GetBoardInfo(int32) (npuCommon.BoardInfo, error) {
+	return npuCommon.BoardInfo{BoardId: s.boardID}, nil
+}
+
+func newFakeTopologySource(phyIDs ...int32) *fakeTopologySource {
+	source := &fakeTopologySource{phyIDs: make(map[int32]int32, len(phyIDs))}
+	for logicID, phyID := range phyIDs {
+		source.phyIDs[int32(logicID)] = phyID
+	}
+	return source
+}
+
+// TestBuildTopology for test BuildTopology
+func TestBuildTopology(t *testing.T) {
+	convey.Convey("test BuildTopology", t, func() {
+		convey.Convey("chips are not interconnected by ring", func() {
+			topology := BuildTopology(newFakeTopologySource(0, 1), 0)
+			convey.So(topology, convey.ShouldBeNil)
+			convey.So(topology.RingSize(), convey.ShouldEqual, 0)
+			convey.So(topology.GetRing(chipPhyID1), convey.ShouldResemble, []int32{chipPhyID1})
+		})
+		convey.Convey("fully populated 910 server has two rings of 4 chips", func() {
+			topology := BuildTopology(newFakeTopologySource(0, 1, 2, 3, 4, 5, 6, 7), common.Ascend910RingsNum)
+			convey.So(topology.RingSize(), convey.ShouldEqual, common.Ascend910RingsNum)
+			convey.So(topology.GetRingIndex(chipPhyID5), convey.ShouldEqual, 1)
+			convey.So(topology.GetRing(chipPhyID5), convey.ShouldResemble,
+				[]int32{chipPhyID4, chipPhyID5, chipPhyID6, chipPhyID7})
+			convey.So(topology.GetRingLeader(chipPhyID6), convey.ShouldEqual, chipPhyID4)
+		})
+		convey.Convey("ring of partially populated server only contains the present chips", func() {
+			topology := BuildTopology(newFakeTopologySource(0, 1, 4, 5, 6), common.Ascend910RingsNum)
+			convey.So(topology.GetRing(chipPhyID1), convey.ShouldResemble, []int32{chipPhyID0, chipPhyID1})
+			convey.So(topology.GetRingIndex(chipPhyID2), convey.ShouldEqual, 1)
+			convey.So(topology.GetRing(chipPhyID2), convey.ShouldResemble, []int32{chipPhyID2, chipPhyID3,
+				chipPhyID4})
+			convey.So(topology.GetRingLeader(chipPhyID4), convey.ShouldEqual, chipPhyID2)
+		})
+		convey.Convey("partially populated board is not split into smaller rings", func() {
+			topology := BuildTopology(newFakeTopologySource(0, 1, 2, 3), common.Ascend910BRingsNumTrain)
+			convey.So(topology.RingSize(), convey.ShouldEqual, common.Ascend910BRingsNumTrain)
+			convey.So(topology.GetRing(chipPhyID3), convey.ShouldResemble,
+				[]int32{chipPhyID0, chipPhyID1, chipPhyID2, chipPhyID3})
+			topology = BuildTopology(newFakeTopologySource(0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14,
+				15), common.Ascend910BRingsNumTrain)
+			convey.So(topology.RingSize(), convey.ShouldEqual, common.Ascend910BRingsNumTrain)
+			convey.So(topology.GetRingIndex(chipPhyID7), convey.ShouldEqual, 0)
+			convey.So(topology.GetRingIndex(int32(common.Ascend910BRingsNumTrain)), convey.ShouldEqual, 1)
+		})
+		convey.Convey("chips of standalone card are not interconnected", func() {
+			source := newFakeTopologySource(0, 1, 2, 3)
+			source.boardID = common.A300IA2BoardId
+			topology := BuildTopology(source, common.Ascend910RingsNum)
+			convey.So(topology.RingSize(), convey.ShouldEqual, 1)
+			convey.So(topology.GetRing(chipPhyID2), convey.ShouldResemble, []int32{chipPhyID2})
+		})
+		convey.Convey("board of unknown board id uses the ring size of chip family", func() {
+			source := newFakeTopologySource(0, 1, 2, 3)
+			source.boardID = common.A300IA2BoardId + 1
+			topology := BuildTopology(source, common.Ascend910RingsNum)
+			convey.So(topology.RingSize(), convey.ShouldEqual, common.Ascend910RingsNum)
+		})
+		convey.Convey("chips are grouped by logic id when hardware info can not be got", func() {
+			source := newFakeTopologySource(4, 5, 6, 7)
+			source.phyIDErr = errors.New("get physic id failed")
+			topology := BuildTopology(source, common.Ascend910RingsNum)
+			convey.So(topology.GetRing(chipPhyID3), convey.ShouldResemble,
+				[]int32{chipPhyID0, chipPhyID1, chipPhyID2, chipPhyID3})
+			source.listErr = errors.New("get device list failed")
+			topology = BuildTopology(source, common.Ascend910RingsNum)
+			convey.So(topology.GetRing(chipPhyID5), convey.ShouldResemble,
+				[]int32{chipPhyID4, chipPhyID5, chipPhyID6, chipPhyID7})
+		})
+	})
+}
@@ -0,0 +1,378 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package fakekubelet implements a fake kubelet for the end-to-end test of device plugin, it serves the
+// registration and pod resources api on unix sockets and keeps the pods in a fake api server
+package fakekubelet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	podResourcesV1 "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+const (
+	// NodeName the name of node which the fake kubelet runs on
+	NodeName = "fake-node"
+
+	pluginDirName         = "device-plugins"
+	podResourceDirName    = "pod-resources"
+	podResourceSocketName = "kubelet.sock"
+	callTimeout           = 5 * time.Second
+	waitInterval          = 100 * time.Millisecond
+)
+
+// Kubelet is a fake kubelet, the device plugin registers to it and it allocates devices as kubelet does
+type Kubelet struct {
+	// PluginDir the dir of device plugin sockets, like /var/lib/kubelet/device-plugins/
+	PluginDir string
+	// KubeletSocket the registration socket in PluginDir
+	KubeletSocket string
+	// PodResourceSocket the socket of pod resources api
+	PodResourceSocket string
+	// Clientset the fake api server which holds the pods and config maps
+	Clientset *fake.Clientset
+
+	registrationServer *grpc.Server
+	podResourceServer  *grpc.Server
+	lock               sync.Mutex
+	plugins            map[string]*Plugin
+	podResources       map[string]*podResourcesV1.PodResources
+}
+
+// Plugin is a device plugin registered to the fake kubelet
+type Plugin struct {
+	ResourceName string
+	Endpoint     string
+	Options      *v1beta1.DevicePluginOptions
+
+	conn    *grpc.ClientConn
+	client  v1beta1.DevicePluginClient
+	cancel  context.CancelFunc
+	lock    sync.Mutex
+	devices []*v1beta1.Device
+	updates int
+}
+
+// NewKubelet start a fake kubelet whose sockets are in rootDir, rootDir is usually the temp dir of test
+func NewKubelet(rootDir string) (*Kubelet, error) {
+	k := &Kubelet{
+		PluginDir:         filepath.Join(rootDir, pluginDirName) + string(filepath.Separator),
+		PodResourceSocket: filepath.Join(rootDir, podResourceDirName, podResourceSocketName),
+		Clientset:         fake.NewSimpleClientset(),
+		plugins:           make(map[string]*Plugin),
+		podResources:      make(map[string]*podResourcesV1.PodResources),
+	}
+	k.KubeletSocket = filepath.Join(k.PluginDir, filepath.Base(v1beta1.KubeletSocket))
+	if _, err := k.Clientset.CoreV1().Nodes().Create(context.Background(),
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: NodeName}}, metav1.CreateOptions{}); err != nil {
+		return nil, fmt.Errorf("create node failed, %v", err)
+	}
+	var err error
+	if k.registrationServer, err = serveUnix(k.KubeletSocket, func(server *grpc.Server) {
+		v1beta1.RegisterRegistrationServer(server, k)
+	}); err != nil {
+		return nil, err
+	}
+	if k.podResourceServer, err = serveUnix(k.PodResourceSocket, func(server *grpc.Server) {
+		podResourcesV1.RegisterPodResourcesListerServer(server, &podResourceServer{kubelet: k})
+	}); err != nil {
+		k.registrationServer.Stop()
+		return nil, err
+	}
+	return k, nil
+}
+
+func serveUnix(socketPath string, register func(*grpc.Server)) (*grpc.Server, error) {
+	if err := os.MkdirAll(filepath.Dir(socketPath), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("create socket dir failed, %v", err)
+	}
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("listen %s failed, %v", socketPath, err)
+	}
+	server := grpc.NewServer()
+	register(server)
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			hwlog.RunLog.Warnf("fake kubelet server on %s stopped, %v", socketPath, err)
+		}
+	}()
+	return server, nil
+}
+
+// Stop stop the servers of fake kubelet and disconnect all plugins
+func (k *Kubelet) Stop() {
+	k.registrationServer.Stop()
+	k.podResourceServer.Stop()
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	for _, plugin := range k.plugins {
+		plugin.close()
+	}
+	k.plugins = make(map[string]*Plugin)
+}
+
+// Register implements the registration api, the fake kubelet connects to the plugin and starts ListAndWatch
+func (k *Kubelet) Register(ctx context.Context, req *v1beta1.RegisterRequest) (*v1beta1.Empty, error) {
+	if req.Version != v1beta1.Version {
+		return nil, fmt.Errorf("unsupported version %s", req.Version)
+	}
+	conn, err := grpc.Dial(filepath.Join(k.PluginDir, req.Endpoint),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", addr)
+		}))
+	if err != nil {
+		return nil, fmt.Errorf("connect plugin %s failed, %v", req.Endpoint, err)
+	}
+	plugin := &Plugin{
+		ResourceName: req.ResourceName,
+		Endpoint:     req.Endpoint,
+		Options:      req.Options,
+		conn:         conn,
+		client:       v1beta1.NewDevicePluginClient(conn),
+	}
+	if plugin.Options == nil {
+		// the plugin does not carry options in register request, ask the plugin as old kubelet does
+		if plugin.Options, err = plugin.client.GetDevicePluginOptions(ctx, &v1beta1.Empty{}); err != nil {
+			plugin.close()
+			return nil, fmt.Errorf("get options of plugin %s failed, %v", req.Endpoint, err)
+		}
+	}
+	streamCtx, cancel := context.WithCancel(context.Background())
+	plugin.cancel = cancel
+	stream, err := plugin.client.ListAndWatch(streamCtx, &v1beta1.Empty{})
+	if err != nil {
+		plugin.close()
+		return nil, fmt.Errorf("list and watch plugin %s failed, %v", req.Endpoint, err)
+	}
+	go plugin.watch(stream)
+	k.lock.Lock()
+	if oldPlugin, ok := k.plugins[req.ResourceName]; ok {
+		oldPlugin.close()
+	}
+	k.plugins[req.ResourceName] = plugin
+	k.lock.Unlock()
+	return &v1beta1.Empty{}, nil
+}
+
+// WaitForPlugin wait until the plugin of resource registers
+func (k *Kubelet) WaitForPlugin(resourceName string, timeout time.Duration) (*Plugin, error) {
+	var plugin *Plugin
+	err := waitFor(timeout, func() bool {
+		k.lock.Lock()
+		defer k.lock.Unlock()
+		plugin = k.plugins[resourceName]
+		return plugin != nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("plugin of %s is not registered, %v", resourceName, err)
+	}
+	return plugin, nil
+}
+
+// CreatePod create the pod on the node in the fake api server
+func (k *Kubelet) CreatePod(pod *v1.Pod) (*v1.Pod, error) {
+	pod.Spec.NodeName = NodeName
+	if pod.Status.Phase == "" {
+		pod.Status.Phase = v1.PodPending
+	}
+	return k.Clientset.CoreV1().Pods(pod.Namespace).Create(context.Background(), pod, metav1.CreateOptions{})
+}
+
+// GetPod get the pod from the fake api server, so that the annotations written by plugin can be checked
+func (k *Kubelet) GetPod(namespace, name string) (*v1.Pod, error) {
+	return k.Clientset.CoreV1().Pods(namespace).Get(context.Background(), name, metav1.GetOptions{})
+}
+
+// DeletePod delete the pod from the fake api server and release its devices
+func (k *Kubelet) DeletePod(namespace, name string) error {
+	k.lock.Lock()
+	delete(k.podResources, podKey(namespace, name))
+	k.lock.Unlock()
+	return k.Clientset.CoreV1().Pods(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+}
+
+// Allocate allocate the devices of resource to the container of pod as kubelet does, the allocation is
+// recorded into pod resources when the plugin accepts it
+func (k *Kubelet) Allocate(pod *v1.Pod, containerName, resourceName string,
+	deviceIDs []string) (*v1beta1.ContainerAllocateResponse, error) {
+	k.lock.Lock()
+	plugin, ok := k.plugins[resourceName]
+	k.lock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("plugin of %s is not registered", resourceName)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	resp, err := plugin.client.Allocate(ctx, &v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{{DevicesIDs: deviceIDs}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.ContainerResponses) != 1 {
+		return nil, fmt.Errorf("plugin returns %d container responses", len(resp.ContainerResponses))
+	}
+	k.recordAllocation(pod, containerName, resourceName, deviceIDs)
+	return resp.ContainerResponses[0], nil
+}
+
+func (k *Kubelet) recordAllocation(pod *v1.Pod, containerName, resourceName string, deviceIDs []string) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	key := podKey(pod.Namespace, pod.Name)
+	podResource, ok := k.podResources[key]
+	if !ok {
+		podResource = &podResourcesV1.PodResources{Name: pod.Name, Namespace: pod.Namespace}
+		k.podResources[key] = podResource
+	}
+	var container *podResourcesV1.ContainerResources
+	for _, existContainer := range podResource.Containers {
+		if existContainer.Name == containerName {
+			container = existContainer
+		}
+	}
+	if container == nil {
+		container = &podResourcesV1.ContainerResources{Name: containerName}
+		podResource.Containers = append(podResource.Containers, container)
+	}
+	container.Devices = append(container.Devices, &podResourcesV1.ContainerDevices{
+		ResourceName: resourceName,
+		DeviceIds:    append([]string{}, deviceIDs...),
+	})
+}
+
+func podKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// GetPreferredAllocation call GetPreferredAllocation of plugin as kubelet does before Allocate
+func (p *Plugin) GetPreferredAllocation(available, mustInclude []string, size int32) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	resp, err := p.client.GetPreferredAllocation(ctx, &v1beta1.PreferredAllocationRequest{
+		ContainerRequests: []*v1beta1.ContainerPreferredAllocationRequest{{AvailableDeviceIDs: available,
+			MustIncludeDeviceIDs: mustInclude, AllocationSize: size}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.ContainerResponses) != 1 {
+		return nil, fmt.Errorf("plugin returns %d container responses", len(resp.ContainerResponses))
+	}
+	return resp.ContainerResponses[0].DeviceIDs, nil
+}
+
+func (p *Plugin) watch(stream v1beta1.DevicePlugin_ListAndWatchClient) {
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return
+		}
+		p.lock.Lock()
+		p.devices = resp.Devices
+		p.updates++
+		p.lock.Unlock()
+	}
+}
+
+// Devices get the devices advertised by the plugin in the latest ListAndWatch response
+func (p *Plugin) Devices() []*v1beta1.Device {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]*v1beta1.Device{}, p.devices...)
+}
+
+// WaitForDevices wait until the devices advertised by the plugin satisfy the condition
+func (p *Plugin) WaitForDevices(timeout time.Duration, condition func([]*v1beta1.Device) bool) (
+	[]*v1beta1.Device, error) {
+	var devices []*v1beta1.Device
+	err := waitFor(timeout, func() bool {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		devices = append([]*v1beta1.Device{}, p.devices...)
+		return p.updates > 0 && condition(devices)
+	})
+	return devices, err
+}
+
+func (p *Plugin) close() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	if err := p.conn.Close(); err != nil {
+		hwlog.RunLog.Warnf("close connection of plugin %s failed, %v", p.Endpoint, err)
+	}
+}
+
+func waitFor(timeout time.Duration, condition func() bool) error {
+	deadline := time.Now().Add(timeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout after %v", timeout)
+		}
+		time.Sleep(waitInterval)
+	}
+	return nil
+}
+
+// podResourceServer implements the v1 pod resources api with the allocations of fake kubelet
+type podResourceServer struct {
+	kubelet *Kubelet
+}
+
+// List return the devices allocated to the pods
+func (s *podResourceServer) List(ctx context.Context, req *podResourcesV1.ListPodResourcesRequest) (
+	*podResourcesV1.ListPodResourcesResponse, error) {
+	s.kubelet.lock.Lock()
+	defer s.kubelet.lock.Unlock()
+	resp := &podResourcesV1.ListPodResourcesResponse{}
+	for _, podResource := range s.kubelet.podResources {
+		resp.PodResources = append(resp.PodResources, podResource)
+	}
+	return resp, nil
+}
+
+// GetAllocatableResources return the devices advertised by all plugins
+func (s *podResourceServer) GetAllocatableResources(ctx context.Context,
+	req *podResourcesV1.AllocatableResourcesRequest) (*podResourcesV1.AllocatableResourcesResponse, error) {
+	s.kubelet.lock.Lock()
+	defer s.kubelet.lock.Unlock()
+	resp := &podResourcesV1.AllocatableResourcesResponse{}
+	for resourceName, plugin := range s.kubelet.plugins {
+		var deviceIDs []string
+		for _, dev := range plugin.Devices() {
+			deviceIDs = append(deviceIDs, dev.ID)
+		}
+		resp.Devices = append(resp.Devices, &podResourcesV1.ContainerDevices{ResourceName: resourceName,
+			DeviceIds: deviceIDs})
+	}
+	return resp, nil
+}
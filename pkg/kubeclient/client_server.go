@@ -16,6 +16,8 @@
 package kubeclient
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"strconv"
@@ -26,9 +28,13 @@ import (
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/retry"
 
 	"Ascend-device-plugin/pkg/common"
+	"Ascend-device-plugin/pkg/metrics"
 )
 
 var tryUpdatePodWaitTime = 200 * time.Millisecond
@@ -180,9 +186,13 @@ func (ki *ClientK8s) WriteDeviceInfoDataIntoCM(deviceInfo map[string]string,
 		Data: map[string]string{common.DeviceInfoCMDataKey: string(data),
 			common.DeviceInfoCMManuallySeparateNPUKey: manuallySeparateNPU},
 	}
+	if faultHistorySummary := common.GetFaultHistorySummary(); faultHistorySummary != "" {
+		deviceInfoCM.Data[common.DeviceInfoCMFaultHistoryKey] = faultHistorySummary
+	}
 
 	hwlog.RunLog.Debugf("write device info cache into cm: %s/%s.", deviceInfoCM.Namespace, deviceInfoCM.Name)
 	if err := ki.createOrUpdateDeviceCM(deviceInfoCM); err != nil {
+		metrics.IncConfigMapWriteFailure(metrics.DeviceInfoConfigMap)
 		return nil, err
 	}
 	return &nodeDeviceData, nil
@@ -191,38 +201,154 @@ func (ki *ClientK8s) WriteDeviceInfoDataIntoCM(deviceInfo map[string]string,
 // WriteResetInfoDataIntoCM write reset info into config map
 func (ki *ClientK8s) WriteResetInfoDataIntoCM(taskName string, namespace string,
 	taskInfo *common.TaskResetInfo) (*v1.ConfigMap, error) {
-	oldCM, err := ki.GetConfigMap(common.ResetInfoCMNamePrefix+taskName, namespace)
+	newTaskInfo := setNewTaskInfoWithHexString(taskInfo)
+	newTaskInfo.UpdateTime = time.Now().Unix()
+	checkCode := common.MakeDataHash(newTaskInfo)
+	var data []byte
+	if data = common.MarshalData(newTaskInfo); len(data) == 0 {
+		return nil, fmt.Errorf("marshal task reset data failed")
+	}
+	return ki.updateConfigMapOnConflict(common.ResetInfoCMNamePrefix+taskName, namespace,
+		metrics.ResetInfoConfigMap, func(resetInfoCM *v1.ConfigMap) error {
+			oldResetInfoData, ok := resetInfoCM.Data[common.ResetInfoCMDataKey]
+			if !ok {
+				return fmt.Errorf("invalid reset info data")
+			}
+			if strings.Contains(oldResetInfoData, common.IsolateError) && len(taskInfo.RankList) != 0 {
+				return fmt.Errorf("task should be rescheduled")
+			}
+			newData := map[string]string{
+				common.ResetInfoCMDataKey:      string(data),
+				common.ResetInfoCMCheckCodeKey: checkCode,
+			}
+			// the reset progress is written by each node, keep it
+			if progress, ok := resetInfoCM.Data[common.ResetProgressCMDataKey]; ok {
+				newData[common.ResetProgressCMDataKey] = progress
+			}
+			resetInfoCM.Data = newData
+			hwlog.RunLog.Debugf("write reset info cache into cm: %s/%s.", resetInfoCM.Namespace, resetInfoCM.Name)
+			return nil
+		})
+}
+
+// WriteResetProgressIntoCM write the reset progress of current node into reset config map, and return the reset
+// progress of all nodes
+func (ki *ClientK8s) WriteResetProgressIntoCM(taskName, namespace,
+	status string) (map[string]*common.NodeResetProgress, error) {
+	var progress map[string]*common.NodeResetProgress
+	_, err := ki.updateConfigMapOnConflict(common.ResetInfoCMNamePrefix+taskName, namespace,
+		metrics.ResetInfoConfigMap, func(resetInfoCM *v1.ConfigMap) error {
+			var err error
+			if progress, err = GetResetProgress(resetInfoCM); err != nil {
+				return err
+			}
+			progress[ki.NodeName] = &common.NodeResetProgress{Status: status, UpdateTime: time.Now().Unix()}
+			var data []byte
+			if data = common.MarshalData(progress); len(data) == 0 {
+				return fmt.Errorf("marshal reset progress failed")
+			}
+			if resetInfoCM.Data == nil {
+				resetInfoCM.Data = make(map[string]string, 1)
+			}
+			resetInfoCM.Data[common.ResetProgressCMDataKey] = string(data)
+			return nil
+		})
 	if err != nil {
-		hwlog.RunLog.Errorf("failed to get reset cm of task %s, err: %v", taskName, err)
 		return nil, err
 	}
+	return progress, nil
+}
 
-	oldResetInfoData, ok := oldCM.Data[common.ResetInfoCMDataKey]
-	if !ok {
-		return nil, fmt.Errorf("invalid reset info data")
+// GetResetProgressFromCM get the latest reset progress of all nodes from reset config map
+func (ki *ClientK8s) GetResetProgressFromCM(taskName, namespace string) (map[string]*common.NodeResetProgress,
+	error) {
+	resetInfoCM, err := ki.GetLatestConfigMap(common.ResetInfoCMNamePrefix+taskName, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return GetResetProgress(resetInfoCM)
+}
+
+// GetResetProgress get the reset progress of all nodes from reset config map
+func GetResetProgress(resetInfoCM *v1.ConfigMap) (map[string]*common.NodeResetProgress, error) {
+	progress := make(map[string]*common.NodeResetProgress)
+	data, ok := resetInfoCM.Data[common.ResetProgressCMDataKey]
+	if !ok || data == "" {
+		return progress, nil
 	}
-	if strings.Contains(oldResetInfoData, common.IsolateError) && len(taskInfo.RankList) != 0 {
-		return nil, fmt.Errorf("task should be rescheduled")
+	if len(data) > common.CMDataMaxLength {
+		return nil, fmt.Errorf("reset progress data size is out of memory")
 	}
+	if err := json.Unmarshal([]byte(data), &progress); err != nil {
+		return nil, fmt.Errorf("unmarshal reset progress failed, err: %v", err)
+	}
+	return progress, nil
+}
 
-	newTaskInfo := setNewTaskInfoWithHexString(taskInfo)
-	newTaskInfo.UpdateTime = time.Now().Unix()
-	checkCode := common.MakeDataHash(newTaskInfo)
-	var data []byte
-	if data = common.MarshalData(newTaskInfo); len(data) == 0 {
-		return nil, fmt.Errorf("marshal task reset data failed")
+// GetTaskNodeNames get the name of nodes running the active pods of task. The pods are selected by the label of task
+// name, which is set on the pods of volcano job and other job, so only the pods of task are listed
+func (ki *ClientK8s) GetTaskNodeNames(taskName, namespace string) (sets.String, error) {
+	fieldSelector, err := fields.ParseSelector("status.phase!=" + string(v1.PodSucceeded) + ",status.phase!=" +
+		string(v1.PodFailed))
+	if err != nil {
+		return nil, err
 	}
-	resetInfoCM := &v1.ConfigMap{
-		TypeMeta:   oldCM.TypeMeta,
-		ObjectMeta: oldCM.ObjectMeta,
-		Data: map[string]string{
-			common.ResetInfoCMDataKey:      string(data),
-			common.ResetInfoCMCheckCodeKey: checkCode,
-		},
+	nodeNames := sets.NewString()
+	for _, labelKey := range []string{common.ResetTaskNameKey, common.ResetTaskNameKeyInLabel} {
+		podList, err := ki.Clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{
+			LabelSelector:   labels.Set{labelKey: taskName}.String(),
+			FieldSelector:   fieldSelector.String(),
+			ResourceVersion: "0",
+		})
+		if err != nil {
+			if strings.Contains(err.Error(), common.ApiServerPort) {
+				ki.IsApiErr = true
+			}
+			return nil, err
+		}
+		if len(podList.Items) >= common.MaxPodLimit {
+			return nil, fmt.Errorf("the number of pods of task %s exceeds the upper limit", taskName)
+		}
+		for _, pod := range podList.Items {
+			if pod.Spec.NodeName != "" {
+				nodeNames.Insert(pod.Spec.NodeName)
+			}
+		}
 	}
+	return nodeNames, nil
+}
 
-	hwlog.RunLog.Debugf("write reset info cache into cm: %s/%s.", resetInfoCM.Namespace, resetInfoCM.Name)
-	return ki.UpdateConfigMap(resetInfoCM)
+// updateConfigMapOnConflict update config map with the resourceVersion of the read one, so the concurrent update by
+// other node is not overwritten. When conflict, the latest config map is read and mutated again
+func (ki *ClientK8s) updateConfigMapOnConflict(cmName, namespace, cmLabel string,
+	mutate func(*v1.ConfigMap) error) (*v1.ConfigMap, error) {
+	var newCM *v1.ConfigMap
+	getConfigMap := ki.GetConfigMap
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		oldCM, err := getConfigMap(cmName, namespace)
+		// the config map in the cache of api server may be stale, read the latest one when retry
+		getConfigMap = ki.GetLatestConfigMap
+		if err != nil {
+			hwlog.RunLog.Errorf("failed to get cm %s/%s, err: %v", namespace, cmName, err)
+			return err
+		}
+		cm := oldCM.DeepCopy()
+		if err = mutate(cm); err != nil {
+			return err
+		}
+		if newCM, err = ki.UpdateConfigMap(cm); errors.IsConflict(err) {
+			hwlog.RunLog.Warnf("cm %s/%s is updated by others, retry", namespace, cmName)
+			return err
+		}
+		if err != nil {
+			metrics.IncConfigMapWriteFailure(cmLabel)
+		}
+		return err
+	})
+	if errors.IsConflict(err) {
+		metrics.IncConfigMapWriteFailure(cmLabel)
+	}
+	return newCM, err
 }
 
 func setNewTaskInfoWithHexString(taskInfo *common.TaskResetInfo) *common.TaskResetInfo {
@@ -270,6 +396,23 @@ func (ki *ClientK8s) WriteFaultInfoDataIntoCM(taskName string, namespace string,
 	return ki.UpdateConfigMap(faultInfoCM)
 }
 
+// WriteGracePlanIntoCM write the grace tolerance plan of dry-run into the configmap of node
+func (ki *ClientK8s) WriteGracePlanIntoCM(plan *common.GraceTolerancePlan) error {
+	var data []byte
+	if data = common.MarshalData(plan); len(data) == 0 {
+		return fmt.Errorf("marshal grace tolerance plan failed")
+	}
+	planCM := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      common.GracePlanCMNamePrefix + ki.NodeName,
+			Namespace: common.DeviceInfoCMNameSpace,
+		},
+		Data: map[string]string{common.GracePlanCMDataKey: string(data)},
+	}
+	hwlog.RunLog.Debugf("write grace tolerance plan into cm: %s/%s.", planCM.Namespace, planCM.Name)
+	return ki.createOrUpdateDeviceCM(planCM)
+}
+
 // AnnotationReset reset annotation and device info
 func (ki *ClientK8s) AnnotationReset() error {
 	curNode, err := ki.GetNode()
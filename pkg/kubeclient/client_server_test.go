@@ -28,9 +28,12 @@ import (
 	"github.com/smartystreets/goconvey/convey"
 	"huawei.com/npu-exporter/v5/common-utils/hwlog"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes/fake"
 
 	"Ascend-device-plugin/pkg/common"
 )
@@ -197,6 +200,89 @@ func TestTryUpdatePodAnnotation(t *testing.T) {
 	})
 }
 
+// mockResetCMStore mock the reset info cm in api server, the cm read from cache is stale and the update with stale
+// resourceVersion is conflict
+func mockResetCMStore(latestCM **v1.ConfigMap, staleCM *v1.ConfigMap) *gomonkey.Patches {
+	return gomonkey.ApplyMethod(reflect.TypeOf(new(ClientK8s)), "GetConfigMap",
+		func(_ *ClientK8s, _ string, _ string) (*v1.ConfigMap, error) {
+			return staleCM.DeepCopy(), nil
+		}).ApplyMethod(reflect.TypeOf(new(ClientK8s)), "GetLatestConfigMap",
+		func(_ *ClientK8s, _ string, _ string) (*v1.ConfigMap, error) {
+			return (*latestCM).DeepCopy(), nil
+		}).ApplyMethod(reflect.TypeOf(new(ClientK8s)), "UpdateConfigMap",
+		func(_ *ClientK8s, cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+			if cm.ResourceVersion != (*latestCM).ResourceVersion {
+				return nil, errors.NewConflict(schema.GroupResource{Resource: "configmaps"}, cm.Name,
+					fmt.Errorf("the object has been modified"))
+			}
+			version, err := strconv.Atoi(cm.ResourceVersion)
+			if err != nil {
+				return nil, err
+			}
+			*latestCM = cm.DeepCopy()
+			(*latestCM).ResourceVersion = strconv.Itoa(version + 1)
+			return (*latestCM).DeepCopy(), nil
+		})
+}
+
+// TestWriteResetProgressIntoCM for test WriteResetProgressIntoCM
+func TestWriteResetProgressIntoCM(t *testing.T) {
+	utKubeClient := &ClientK8s{NodeName: nodeNameValue}
+	otherProgress := map[string]*common.NodeResetProgress{"worker": {Status: common.UnrecoveredStatus}}
+	staleCM := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "1"}, Data: map[string]string{
+		common.ResetInfoCMDataKey: string(common.MarshalData(common.TaskResetInfo{}))}}
+	latestCM := staleCM.DeepCopy()
+	latestCM.ResourceVersion = "2"
+	latestCM.Data[common.ResetProgressCMDataKey] = string(common.MarshalData(otherProgress))
+	patches := mockResetCMStore(&latestCM, staleCM)
+	defer patches.Reset()
+	convey.Convey("test WriteResetProgressIntoCM", t, func() {
+		convey.Convey("progress of other node is kept when conflict", func() {
+			progress, err := utKubeClient.WriteResetProgressIntoCM("task", "default", common.RecoveredStatus)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(len(progress), convey.ShouldEqual, len(otherProgress)+1)
+			convey.So(progress[nodeNameValue].Status, convey.ShouldEqual, common.RecoveredStatus)
+			convey.So(latestCM.ResourceVersion, convey.ShouldEqual, "3")
+			progress, err = utKubeClient.GetResetProgressFromCM("task", "default")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(progress["worker"].Status, convey.ShouldEqual, common.UnrecoveredStatus)
+		})
+		convey.Convey("progress is kept when reset info is written", func() {
+			_, err := utKubeClient.WriteResetInfoDataIntoCM("task", "default", &common.TaskResetInfo{})
+			convey.So(err, convey.ShouldBeNil)
+			progress, err := GetResetProgress(latestCM)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(progress[nodeNameValue].Status, convey.ShouldEqual, common.RecoveredStatus)
+		})
+		convey.Convey("progress of current node is overwritten by the next reset", func() {
+			progress, err := utKubeClient.WriteResetProgressIntoCM("task", "default", common.UnrecoveredStatus)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(progress[nodeNameValue].Status, convey.ShouldEqual, common.UnrecoveredStatus)
+			convey.So(progress, convey.ShouldContainKey, "worker")
+		})
+	})
+}
+
+// TestGetTaskNodeNames for test GetTaskNodeNames
+func TestGetTaskNodeNames(t *testing.T) {
+	newTaskPod := func(name, nodeName string, annotations, labels map[string]string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: annotations,
+			Labels: labels}, Spec: v1.PodSpec{NodeName: nodeName}}
+	}
+	utKubeClient := &ClientK8s{NodeName: nodeNameValue, Clientset: fake.NewSimpleClientset(
+		newTaskPod("pod0", "node0", nil, map[string]string{common.ResetTaskNameKey: "task"}),
+		newTaskPod("pod1", "node1", nil, map[string]string{common.ResetTaskNameKeyInLabel: "task"}),
+		newTaskPod("pod2", "node1", nil, map[string]string{common.ResetTaskNameKey: "task"}),
+		newTaskPod("pod3", "node2", nil, map[string]string{common.ResetTaskNameKey: "other"}),
+		newTaskPod("pod4", "", nil, map[string]string{common.ResetTaskNameKey: "task"}),
+		newTaskPod("pod5", "node3", map[string]string{common.ResetTaskNameKey: "other"}, nil))}
+	convey.Convey("test GetTaskNodeNames", t, func() {
+		nodeNames, err := utKubeClient.GetTaskNodeNames("task", "default")
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(nodeNames.List(), convey.ShouldResemble, []string{"node0", "node1"})
+	})
+}
+
 func getMockCreateCM(ascendType, ascendValue string) *v1.ConfigMap {
 	return &v1.ConfigMap{
 		Data: map[string]string{
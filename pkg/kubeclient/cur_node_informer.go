@@ -21,6 +21,8 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/tools/cache"
+
+	"Ascend-device-plugin/pkg/common"
 )
 
 // InitPodInformer init pod informer
@@ -40,6 +42,8 @@ func (ki *ClientK8s) InitPodInformer() {
 		},
 		DeleteFunc: func(obj interface{}) {
 			UpdatePodList(nil, obj, podDeleteOperator)
+			// the devices used by deleted pod may be waiting for hot reset or becoming free
+			common.TriggerDeviceUpdate("pod deleted")
 		},
 	})
 	factory.Start(make(chan struct{}))
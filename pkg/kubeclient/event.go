@@ -0,0 +1,61 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package kubeclient a series of k8s function
+package kubeclient
+
+import (
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+// newEventRecorder create an event recorder, the events of the same object are aggregated and rate limited
+// to prevent a flapping device from flooding the api server
+func newEventRecorder(client kubernetes.Interface, nodeName string) record.EventRecorder {
+	broadcaster := record.NewBroadcasterWithCorrelatorOptions(record.CorrelatorOptions{
+		LRUCacheSize: common.EventLRUCacheSize,
+		BurstSize:    common.EventBurstSize,
+		QPS:          common.EventQPS,
+	})
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: common.EventComponentName,
+		Host: nodeName})
+}
+
+// RecordNodeEvent record an event on current node
+func (ki *ClientK8s) RecordNodeEvent(eventType, reason, messageFmt string, args ...interface{}) {
+	if ki == nil || ki.recorder == nil {
+		return
+	}
+	// the uid of node reference is node name, which is the same as kubelet
+	nodeRef := &v1.ObjectReference{Kind: "Node", Name: ki.NodeName, UID: types.UID(ki.NodeName)}
+	ki.recorder.Eventf(nodeRef, eventType, reason, messageFmt, args...)
+	hwlog.RunLog.Debugf("record node event, reason: %s", reason)
+}
+
+// RecordPodEvent record an event on pod
+func (ki *ClientK8s) RecordPodEvent(pod *v1.Pod, eventType, reason, messageFmt string, args ...interface{}) {
+	if ki == nil || ki.recorder == nil || pod == nil {
+		return
+	}
+	ki.recorder.Eventf(pod, eventType, reason, messageFmt, args...)
+	hwlog.RunLog.Debugf("record event on pod %s/%s, reason: %s", pod.Namespace, pod.Name, reason)
+}
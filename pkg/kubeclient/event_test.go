@@ -0,0 +1,53 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package kubeclient a series of k8s function
+package kubeclient
+
+import (
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+const eventBufferSize = 10
+
+// TestRecordEvent for test RecordNodeEvent and RecordPodEvent
+func TestRecordEvent(t *testing.T) {
+	convey.Convey("test record event", t, func() {
+		convey.Convey("recorder is nil, event is dropped", func() {
+			utKubeClient := &ClientK8s{NodeName: "node"}
+			convey.So(func() {
+				utKubeClient.RecordNodeEvent(v1.EventTypeNormal, common.EventReasonNPURecovered, "msg")
+			}, convey.ShouldNotPanic)
+		})
+		convey.Convey("record node and pod event", func() {
+			recorder := record.NewFakeRecorder(eventBufferSize)
+			utKubeClient := &ClientK8s{NodeName: "node", recorder: recorder}
+			utKubeClient.RecordNodeEvent(v1.EventTypeWarning, common.EventReasonNPUUnhealthy,
+				"device %s is unhealthy", "Ascend910-0")
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+			utKubeClient.RecordPodEvent(pod, v1.EventTypeNormal, common.EventReasonNPUResetSucceeded, "reset succeeded")
+			utKubeClient.RecordPodEvent(nil, v1.EventTypeNormal, common.EventReasonNPUResetSucceeded, "reset succeeded")
+			convey.So(len(recorder.Events), convey.ShouldEqual, 2)
+			convey.So(<-recorder.Events, convey.ShouldEqual, "Warning NPUUnhealthy device Ascend910-0 is unhealthy")
+			convey.So(<-recorder.Events, convey.ShouldEqual, "Normal NPUResetSucceeded reset succeeded")
+		})
+	})
+}
@@ -17,6 +17,7 @@ package kubeclient
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -26,8 +27,10 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/component-helpers/node/util"
 
 	"Ascend-device-plugin/pkg/common"
@@ -39,6 +42,7 @@ type ClientK8s struct {
 	NodeName       string
 	DeviceInfoName string
 	IsApiErr       bool
+	recorder       record.EventRecorder
 }
 
 // NewClientK8s create k8s client
@@ -64,6 +68,7 @@ func NewClientK8s() (*ClientK8s, error) {
 		NodeName:       nodeName,
 		DeviceInfoName: common.DeviceInfoCMNamePrefix + nodeName,
 		IsApiErr:       false,
+		recorder:       newEventRecorder(client, nodeName),
 	}, nil
 }
 
@@ -84,6 +89,28 @@ func (ki *ClientK8s) PatchNodeState(curNode, newNode *v1.Node) (*v1.Node, []byte
 	return node, patchBytes, err
 }
 
+// PatchNode patch node spec and metadata, PatchNodeState only patch the status of node
+func (ki *ClientK8s) PatchNode(curNode, newNode *v1.Node) (*v1.Node, error) {
+	oldData, err := json.Marshal(curNode)
+	if err != nil {
+		return nil, fmt.Errorf("marshal current node failed, %v", err)
+	}
+	newData, err := json.Marshal(newNode)
+	if err != nil {
+		return nil, fmt.Errorf("marshal new node failed, %v", err)
+	}
+	patchBytes, err := strategicpatch.CreateTwoWayMergePatch(oldData, newData, v1.Node{})
+	if err != nil {
+		return nil, fmt.Errorf("create node patch failed, %v", err)
+	}
+	node, err := ki.Clientset.CoreV1().Nodes().Patch(context.Background(), ki.NodeName,
+		types.StrategicMergePatchType, patchBytes, metav1.PatchOptions{})
+	if err != nil && strings.Contains(err.Error(), common.ApiServerPort) {
+		ki.IsApiErr = true
+	}
+	return node, err
+}
+
 // GetPod get pod by namespace and name
 func (ki *ClientK8s) GetPod(pod *v1.Pod) (*v1.Pod, error) {
 	if pod == nil {
@@ -201,6 +228,16 @@ func (ki *ClientK8s) GetConfigMap(cmName, cmNameSpace string) (*v1.ConfigMap, er
 	return newCM, err
 }
 
+// GetLatestConfigMap get the latest config map by name and namespace, which is not read from the cache of api server
+func (ki *ClientK8s) GetLatestConfigMap(cmName, cmNameSpace string) (*v1.ConfigMap, error) {
+	newCM, err := ki.Clientset.CoreV1().ConfigMaps(cmNameSpace).Get(context.TODO(), cmName, metav1.GetOptions{})
+	if err != nil && strings.Contains(err.Error(), common.ApiServerPort) {
+		ki.IsApiErr = true
+	}
+
+	return newCM, err
+}
+
 // UpdateConfigMap update device info, which is cm
 func (ki *ClientK8s) UpdateConfigMap(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
 	if cm == nil {
@@ -0,0 +1,168 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package metrics holds the prometheus metrics of device plugin internals
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+const (
+	namespace = "ascend_device_plugin"
+
+	// ResultSuccess the result label value of succeeded operation
+	ResultSuccess = "success"
+	// ResultFailure the result label value of failed operation
+	ResultFailure = "failure"
+	// OperationCreate the operation label value of creating vnpu
+	OperationCreate = "create"
+	// OperationDestroy the operation label value of destroying vnpu
+	OperationDestroy = "destroy"
+	// DeviceInfoConfigMap the configmap label value of device info configmap
+	DeviceInfoConfigMap = "device-info"
+	// ResetInfoConfigMap the configmap label value of reset info configmap
+	ResetInfoConfigMap = "reset-info"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	deviceCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "devices",
+		Help:      "Number of devices of each resource by health and network health.",
+	}, []string{"resource", "health", "network_health"})
+	chipFaultLevel = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "chip_fault_level",
+		Help:      "Current fault level of each chip, the value of the current level is 1.",
+	}, []string{"device", "fault_level"})
+	allocateTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "allocate_total",
+		Help:      "Number of allocate requests from kubelet.",
+	}, []string{"device_type"})
+	allocateErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "allocate_errors_total",
+		Help:      "Number of failed allocate requests from kubelet.",
+	}, []string{"device_type"})
+	allocateDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "allocate_duration_seconds",
+		Help:      "Latency of allocate requests from kubelet.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"device_type"})
+	listAndWatchSendFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "list_and_watch_send_failures_total",
+		Help:      "Number of device lists failed to send to kubelet after retries.",
+	}, []string{"device_type"})
+	hotResetAttempts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "hot_reset_attempts_total",
+		Help:      "Number of chip hot reset attempts.",
+	})
+	hotResetDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "hot_reset_duration_seconds",
+		Help:      "Duration of chip hot reset by result.",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300},
+	}, []string{"result"})
+	vnpuOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "vnpu_operations_total",
+		Help:      "Number of vnpu create and destroy operations by result.",
+	}, []string{"operation", "result"})
+	configMapWriteFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "configmap_write_failures_total",
+		Help:      "Number of failed writes of configmaps to api server.",
+	}, []string{"configmap"})
+)
+
+func init() {
+	registry.MustRegister(deviceCount, chipFaultLevel, allocateTotal, allocateErrors, allocateDuration,
+		listAndWatchSendFailures, hotResetAttempts, hotResetDuration, vnpuOperations, configMapWriteFailures)
+}
+
+// Handler return the http handler of metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// SetDeviceStatus refresh the device count and chip fault level gauges by the devices on node
+func SetDeviceStatus(groupDevice map[string][]*common.NpuDevice) {
+	deviceCount.Reset()
+	chipFaultLevel.Reset()
+	for devType, devices := range groupDevice {
+		for _, dev := range devices {
+			deviceCount.WithLabelValues(devType, dev.Health, dev.NetworkHealth).Inc()
+			if common.IsVirtualDev(dev.DeviceName) {
+				continue
+			}
+			// GetFaultType refreshes the fault frequency cache, so the level without fault frequency is shown
+			faultLevel := common.GetCurrentFaultType(dev.FaultCodes, dev.LogicID)
+			chipFaultLevel.WithLabelValues(dev.DeviceName, faultLevel).Set(1)
+		}
+	}
+}
+
+// ObserveAllocate record the result and latency of an allocate request
+func ObserveAllocate(deviceType string, startTime time.Time, err error) {
+	allocateTotal.WithLabelValues(deviceType).Inc()
+	allocateDuration.WithLabelValues(deviceType).Observe(time.Since(startTime).Seconds())
+	if err != nil {
+		allocateErrors.WithLabelValues(deviceType).Inc()
+	}
+}
+
+// IncListAndWatchSendFailure record a device list failed to send to kubelet
+func IncListAndWatchSendFailure(deviceType string) {
+	listAndWatchSendFailures.WithLabelValues(deviceType).Inc()
+}
+
+// IncHotResetAttempt record a chip hot reset attempt
+func IncHotResetAttempt() {
+	hotResetAttempts.Inc()
+}
+
+// ObserveHotReset record the result and duration of a chip hot reset
+func ObserveHotReset(startTime time.Time, err error) {
+	hotResetDuration.WithLabelValues(getResult(err)).Observe(time.Since(startTime).Seconds())
+}
+
+// ObserveVNPUOperation record the result of a vnpu create or destroy operation
+func ObserveVNPUOperation(operation string, err error) {
+	vnpuOperations.WithLabelValues(operation, getResult(err)).Inc()
+}
+
+// IncConfigMapWriteFailure record a failed write of configmap
+func IncConfigMapWriteFailure(configMap string) {
+	configMapWriteFailures.WithLabelValues(configMap).Inc()
+}
+
+func getResult(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
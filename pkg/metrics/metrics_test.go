@@ -0,0 +1,56 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package metrics holds the prometheus metrics of device plugin internals
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/smartystreets/goconvey/convey"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+// TestSetDeviceStatus for test SetDeviceStatus
+func TestSetDeviceStatus(t *testing.T) {
+	convey.Convey("test SetDeviceStatus", t, func() {
+		groupDevice := map[string][]*common.NpuDevice{common.Ascend910: {
+			{DeviceName: "Ascend910-0", Health: v1beta1.Healthy, NetworkHealth: v1beta1.Healthy},
+			{DeviceName: "Ascend910-1", Health: v1beta1.Healthy, NetworkHealth: v1beta1.Healthy},
+			{DeviceName: "Ascend910-2", Health: v1beta1.Unhealthy, NetworkHealth: v1beta1.Healthy}}}
+		SetDeviceStatus(groupDevice)
+		convey.So(testutil.ToFloat64(deviceCount.WithLabelValues(common.Ascend910, v1beta1.Healthy,
+			v1beta1.Healthy)), convey.ShouldEqual, len([]string{"Ascend910-0", "Ascend910-1"}))
+		convey.So(testutil.ToFloat64(deviceCount.WithLabelValues(common.Ascend910, v1beta1.Unhealthy,
+			v1beta1.Healthy)), convey.ShouldEqual, 1)
+		convey.So(testutil.ToFloat64(chipFaultLevel.WithLabelValues("Ascend910-2", common.NormalNPU)),
+			convey.ShouldEqual, 1)
+	})
+}
+
+// TestObserveAllocate for test ObserveAllocate
+func TestObserveAllocate(t *testing.T) {
+	convey.Convey("test ObserveAllocate", t, func() {
+		ObserveAllocate(common.Ascend310P, time.Now(), nil)
+		ObserveAllocate(common.Ascend310P, time.Now(), errors.New("allocate failed"))
+		convey.So(testutil.ToFloat64(allocateTotal.WithLabelValues(common.Ascend310P)), convey.ShouldEqual,
+			len([]string{"success", "failure"}))
+		convey.So(testutil.ToFloat64(allocateErrors.WithLabelValues(common.Ascend310P)), convey.ShouldEqual, 1)
+	})
+}
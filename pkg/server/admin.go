@@ -0,0 +1,300 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+// adminError is the error of admin api with the http status code
+type adminError struct {
+	code int
+	msg  string
+}
+
+func (e *adminError) Error() string {
+	return e.msg
+}
+
+func newAdminError(code int, format string, args ...interface{}) *adminError {
+	return &adminError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// StartAdminServer start the admin api on unix socket which only root can access, it is stopped when ctx is done
+func (hdm *HwDevManager) StartAdminServer(ctx context.Context, socketPath string) {
+	listener, err := createAdminListener(socketPath)
+	if err != nil {
+		hwlog.RunLog.Errorf("create admin socket failed, %v", err)
+		return
+	}
+	serveHTTP(ctx, listener, hdm.newAdminHandler())
+}
+
+func createAdminListener(socketPath string) (net.Listener, error) {
+	socketDir := filepath.Dir(socketPath)
+	if err := os.MkdirAll(socketDir, common.AdminSocketDirMode); err != nil {
+		return nil, fmt.Errorf("create admin socket dir failed, %v", err)
+	}
+	if err := os.Chmod(socketDir, common.AdminSocketDirMode); err != nil {
+		return nil, fmt.Errorf("change mode of admin socket dir failed, %v", err)
+	}
+	if fileInfo, err := os.Lstat(socketPath); err == nil {
+		if fileInfo.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", socketPath)
+		}
+		if err = os.Remove(socketPath); err != nil {
+			return nil, fmt.Errorf("remove stale admin socket failed, %v", err)
+		}
+	}
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Chmod(socketPath, common.AdminSocketMode); err != nil {
+		if closeErr := listener.Close(); closeErr != nil {
+			hwlog.RunLog.Warnf("close admin socket failed, %v", closeErr)
+		}
+		return nil, fmt.Errorf("change mode of admin socket failed, %v", err)
+	}
+	return listener, nil
+}
+
+func (hdm *HwDevManager) newAdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(common.AdminDevicesPath, newAdminHandlerFunc(http.MethodGet,
+		func(r *http.Request) (interface{}, error) {
+			return hdm.listAdminDevices(), nil
+		}))
+	mux.HandleFunc(common.AdminExplainPath, newAdminHandlerFunc(http.MethodGet,
+		func(r *http.Request) (interface{}, error) {
+			return hdm.explainDevice(r.URL.Query().Get("deviceName"))
+		}))
+	mux.HandleFunc(common.AdminSeparatePath, newAdminOperationHandler(hdm.separateDevice))
+	mux.HandleFunc(common.AdminUnseparatePath, newAdminOperationHandler(hdm.unseparateDevice))
+	mux.HandleFunc(common.AdminResetPath, newAdminOperationHandler(hdm.resetIdleDevice))
+	return mux
+}
+
+func newAdminOperationHandler(operate func(common.AdminRequest) (string, error)) http.HandlerFunc {
+	return newAdminHandlerFunc(http.MethodPost, func(r *http.Request) (interface{}, error) {
+		var req common.AdminRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, common.AdminMaxRequestSize)).Decode(&req); err != nil {
+			return nil, newAdminError(http.StatusBadRequest, "decode request failed, %v", err)
+		}
+		msg, err := operate(req)
+		if err != nil {
+			return nil, err
+		}
+		hwlog.RunLog.Infof("admin %s of device %s: %s", r.URL.Path, req.DeviceName, msg)
+		return common.AdminResponse{Message: msg}, nil
+	})
+}
+
+func newAdminHandlerFunc(method string, handle func(*http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		resp, err := handle(r)
+		if err != nil {
+			code := http.StatusInternalServerError
+			if adminErr, ok := err.(*adminError); ok {
+				code = adminErr.code
+			}
+			hwlog.RunLog.Warnf("admin request %s failed, %v", r.URL.Path, err)
+			http.Error(w, err.Error(), code)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(resp); err != nil {
+			hwlog.RunLog.Warnf("write admin response failed, %v", err)
+		}
+	}
+}
+
+func (hdm *HwDevManager) listAdminDevices() []common.AdminDeviceInfo {
+	common.LockAllDeviceInfo()
+	defer common.UnlockAllDeviceInfo()
+	var devInfos []common.AdminDeviceInfo
+	for devType, devices := range hdm.groupDevice {
+		if common.IsVirtualDev(devType) {
+			continue
+		}
+		for _, dev := range devices {
+			devInfos = append(devInfos, getAdminDeviceInfo(dev))
+		}
+	}
+	sort.Slice(devInfos, func(i, j int) bool {
+		return devInfos[i].LogicID < devInfos[j].LogicID
+	})
+	return devInfos
+}
+
+func (hdm *HwDevManager) explainDevice(deviceName string) (common.AdminDeviceInfo, error) {
+	common.LockAllDeviceInfo()
+	defer common.UnlockAllDeviceInfo()
+	_, dev, err := hdm.findAdminDevice(deviceName)
+	if err != nil {
+		return common.AdminDeviceInfo{}, err
+	}
+	return getAdminDeviceInfo(dev), nil
+}
+
+func (hdm *HwDevManager) separateDevice(req common.AdminRequest) (string, error) {
+	if strings.TrimSpace(req.Reason) == "" {
+		return "", newAdminError(http.StatusBadRequest, "the reason of separation is required")
+	}
+	// the manual separation is handled by updateDevice with the lock of device info held, see
+	// handleManuallySeparateNPUFaultInfo, so it is changed with the lock held too
+	common.LockAllDeviceInfo()
+	defer common.UnlockAllDeviceInfo()
+	_, dev, err := hdm.findAdminDevice(req.DeviceName)
+	if err != nil {
+		return "", err
+	}
+	common.SaveManuallyFaultInfoWithReason(dev.LogicID, req.Reason)
+	common.TriggerDeviceUpdate(common.AdminSeparatePath)
+	return fmt.Sprintf("device %s is manually separated", dev.DeviceName), nil
+}
+
+func (hdm *HwDevManager) unseparateDevice(req common.AdminRequest) (string, error) {
+	common.LockAllDeviceInfo()
+	defer common.UnlockAllDeviceInfo()
+	_, dev, err := hdm.findAdminDevice(req.DeviceName)
+	if err != nil {
+		return "", err
+	}
+	if !common.QueryManuallyFaultInfoByLogicID(dev.LogicID) {
+		return "", newAdminError(http.StatusConflict, "device %s is not manually separated", dev.DeviceName)
+	}
+	common.DeleteManuallyFaultInfo(dev.LogicID)
+	common.TriggerDeviceUpdate(common.AdminUnseparatePath)
+	return fmt.Sprintf("manual separation of device %s is removed", dev.DeviceName), nil
+}
+
+// resetIdleDevice hot reset an inference device which is not used by any pod, the lock of device info is held
+// during the reset as the periodic hot reset does
+func (hdm *HwDevManager) resetIdleDevice(req common.AdminRequest) (string, error) {
+	common.LockAllDeviceInfo()
+	defer common.UnlockAllDeviceInfo()
+	devType, dev, err := hdm.findAdminDevice(req.DeviceName)
+	if err != nil {
+		return "", err
+	}
+	// the chip family is decided by the device type, the node may have both training and inference chips
+	if hdm.getRunMode(hdm.getManager(devType)) == common.Ascend910 {
+		return "", newAdminError(http.StatusBadRequest, "hot reset by admin is only supported by inference device")
+	}
+	resetDevices := []*common.NpuDevice{dev}
+	if common.IsContainAtlas300IDuo() {
+		resetDevices = hdm.getSameCardDevices(devType, dev.CardID)
+	}
+	// admin request is out of device update cycle, so the latest pod resource is read
+	podDevice, err := sharedPodResource.GetPodResource()
+	if err != nil {
+		return "", fmt.Errorf("get pod resource failed, %v", err)
+	}
+	for _, resetDevice := range resetDevices {
+		if !hdm.isPodRemove(devType, resetDevice, podDevice) {
+			return "", newAdminError(http.StatusConflict, "device %s is in use", resetDevice.DeviceName)
+		}
+	}
+	// the chips of card are reset and claimed by the first one, as chipHotReset does
+	if err = hdm.hotReset(resetDevices[0], nil); err != nil {
+		return "", fmt.Errorf("hot reset device %s failed, %v", dev.DeviceName, err)
+	}
+	common.TriggerDeviceUpdate(common.AdminResetPath)
+	return fmt.Sprintf("device %s is hot reset", dev.DeviceName), nil
+}
+
+func (hdm *HwDevManager) getSameCardDevices(devType string, cardID int32) []*common.NpuDevice {
+	var devices []*common.NpuDevice
+	for _, dev := range hdm.groupDevice[devType] {
+		if dev.CardID == cardID {
+			devices = append(devices, dev)
+		}
+	}
+	return devices
+}
+
+// findAdminDevice find the physical device by name, the caller should hold the lock of device info
+func (hdm *HwDevManager) findAdminDevice(deviceName string) (string, *common.NpuDevice, error) {
+	if deviceName == "" {
+		return "", nil, newAdminError(http.StatusBadRequest, "device name is required")
+	}
+	for devType, devices := range hdm.groupDevice {
+		if common.IsVirtualDev(devType) {
+			continue
+		}
+		for _, dev := range devices {
+			if dev.DeviceName == deviceName {
+				return devType, dev, nil
+			}
+		}
+	}
+	return "", nil, newAdminError(http.StatusNotFound, "device %s is not found", deviceName)
+}
+
+func getAdminDeviceInfo(dev *common.NpuDevice) common.AdminDeviceInfo {
+	devInfo := common.AdminDeviceInfo{
+		DeviceName:    dev.DeviceName,
+		DevType:       dev.DevType,
+		LogicID:       dev.LogicID,
+		PhyID:         dev.PhyID,
+		Health:        dev.Health,
+		NetworkHealth: dev.NetworkHealth,
+		FaultType:     common.GetCurrentFaultType(dev.FaultCodes, dev.LogicID),
+		FaultCodes:    strings.ToUpper(common.Int64Tool.ToHexString(dev.FaultCodes)),
+	}
+	if manuallyFaultInfo, ok := common.GetManuallyFaultInfoByLogicID(dev.LogicID); ok {
+		devInfo.ManuallySeparated = true
+		reason := fmt.Sprintf("manually separated at %s",
+			time.UnixMilli(manuallyFaultInfo.RecordTime).Format("2006-01-02 15:04:05"))
+		if manuallyFaultInfo.Reason != "" {
+			reason += ", reason: " + manuallyFaultInfo.Reason
+		}
+		devInfo.UnhealthyReasons = append(devInfo.UnhealthyReasons, reason)
+	}
+	if faultType := common.GetFaultTypeByCode(dev.FaultCodes); faultType != common.NormalNPU &&
+		faultType != common.NotHandleFault {
+		devInfo.UnhealthyReasons = append(devInfo.UnhealthyReasons, fmt.Sprintf(
+			"fault codes [%s] lead to fault level %s", devInfo.FaultCodes, faultType))
+	}
+	if dev.NetworkHealth != "" && dev.NetworkHealth != v1beta1.Healthy {
+		devInfo.UnhealthyReasons = append(devInfo.UnhealthyReasons, "network of device is unhealthy")
+	}
+	if dev.Health != v1beta1.Healthy && len(devInfo.UnhealthyReasons) == 0 {
+		devInfo.UnhealthyReasons = append(devInfo.UnhealthyReasons,
+			"no fault is recorded now, the device may be in reset or its fault has just recovered")
+	}
+	return devInfo
+}
@@ -0,0 +1,112 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+func serveAdmin(hdm *HwDevManager, method, path string, req *common.AdminRequest) *httptest.ResponseRecorder {
+	body := ""
+	if req != nil {
+		body = string(common.MarshalData(req))
+	}
+	recorder := httptest.NewRecorder()
+	hdm.newAdminHandler().ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return recorder
+}
+
+// TestAdminHandler for test newAdminHandler
+func TestAdminHandler(t *testing.T) {
+	const logicID = 3
+	convey.Convey("test admin handler", t, func() {
+		npuDevice := &common.NpuDevice{DevType: common.Ascend910, DeviceName: "Ascend910-3", LogicID: logicID,
+			PhyID: logicID, Health: v1beta1.Healthy, NetworkHealth: v1beta1.Healthy}
+		hdm := &HwDevManager{RunMode: common.Ascend910,
+			groupDevice: map[string][]*common.NpuDevice{common.Ascend910: {npuDevice}}}
+		defer common.DeleteManuallyFaultInfo(logicID)
+		convey.Convey("list devices", func() {
+			recorder := serveAdmin(hdm, http.MethodGet, common.AdminDevicesPath, nil)
+			convey.So(recorder.Code, convey.ShouldEqual, http.StatusOK)
+			var devInfos []common.AdminDeviceInfo
+			convey.So(json.Unmarshal(recorder.Body.Bytes(), &devInfos), convey.ShouldBeNil)
+			convey.So(len(devInfos), convey.ShouldEqual, 1)
+			convey.So(devInfos[0].DeviceName, convey.ShouldEqual, npuDevice.DeviceName)
+		})
+		convey.Convey("separate without reason", func() {
+			recorder := serveAdmin(hdm, http.MethodPost, common.AdminSeparatePath,
+				&common.AdminRequest{DeviceName: npuDevice.DeviceName})
+			convey.So(recorder.Code, convey.ShouldEqual, http.StatusBadRequest)
+		})
+		convey.Convey("separate, explain and unseparate", func() {
+			recorder := serveAdmin(hdm, http.MethodPost, common.AdminSeparatePath,
+				&common.AdminRequest{DeviceName: npuDevice.DeviceName, Reason: "hbm error"})
+			convey.So(recorder.Code, convey.ShouldEqual, http.StatusOK)
+			convey.So(common.QueryManuallyFaultInfoByLogicID(logicID), convey.ShouldBeTrue)
+			recorder = serveAdmin(hdm, http.MethodGet, common.AdminExplainPath+"?deviceName="+
+				npuDevice.DeviceName, nil)
+			var devInfo common.AdminDeviceInfo
+			convey.So(json.Unmarshal(recorder.Body.Bytes(), &devInfo), convey.ShouldBeNil)
+			convey.So(devInfo.ManuallySeparated, convey.ShouldBeTrue)
+			convey.So(devInfo.FaultType, convey.ShouldEqual, common.ManuallySeparateNPU)
+			convey.So(strings.Contains(devInfo.UnhealthyReasons[0], "hbm error"), convey.ShouldBeTrue)
+			recorder = serveAdmin(hdm, http.MethodPost, common.AdminUnseparatePath,
+				&common.AdminRequest{DeviceName: npuDevice.DeviceName})
+			convey.So(recorder.Code, convey.ShouldEqual, http.StatusOK)
+			convey.So(common.QueryManuallyFaultInfoByLogicID(logicID), convey.ShouldBeFalse)
+		})
+		convey.Convey("device not found", func() {
+			recorder := serveAdmin(hdm, http.MethodGet, common.AdminExplainPath+"?deviceName=Ascend910-9", nil)
+			convey.So(recorder.Code, convey.ShouldEqual, http.StatusNotFound)
+		})
+		convey.Convey("reset is not supported by training device", func() {
+			recorder := serveAdmin(hdm, http.MethodPost, common.AdminResetPath,
+				&common.AdminRequest{DeviceName: npuDevice.DeviceName})
+			convey.So(recorder.Code, convey.ShouldEqual, http.StatusBadRequest)
+		})
+		convey.Convey("method not allowed", func() {
+			recorder := serveAdmin(hdm, http.MethodGet, common.AdminSeparatePath, nil)
+			convey.So(recorder.Code, convey.ShouldEqual, http.StatusMethodNotAllowed)
+		})
+	})
+	for len(common.DeviceUpdateTrigger()) != 0 {
+		<-common.DeviceUpdateTrigger()
+	}
+}
+
+// TestCreateAdminListener for test createAdminListener
+func TestCreateAdminListener(t *testing.T) {
+	convey.Convey("test createAdminListener", t, func() {
+		socketPath := filepath.Join(t.TempDir(), "admin", "admin.sock")
+		listener, err := createAdminListener(socketPath)
+		convey.So(err, convey.ShouldBeNil)
+		fileInfo, err := os.Stat(socketPath)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(fileInfo.Mode().Perm(), convey.ShouldEqual, os.FileMode(common.AdminSocketMode))
+		convey.So(listener.Close(), convey.ShouldBeNil)
+	})
+}
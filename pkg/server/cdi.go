@@ -0,0 +1,185 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+var (
+	cdiLock        sync.Mutex
+	cdiDeviceNodes = make(map[string]*CDIDeviceNode, common.MaxDevicesNum)
+	// cdiVNPUNodes the vnpu created dynamically when allocate, they are not in the device list of node
+	cdiVNPUNodes   = make(map[string]*CDIDeviceNode, common.MaxDevicesNum)
+	cdiDefaultDevs []string
+	cdiSpecHash    string
+)
+
+// refreshCDISpec rebuild the cdi spec by all devices on node and the existing dynamic vnpu, and write it into file
+// when it is changed. The dynamic vnpu in spec is kept when vNPUIDs is nil, which means they are unknown
+func refreshCDISpec(devices []common.NpuDevice, vNPUIDs sets.Int, defaultDevs []string) error {
+	deviceNodes := make(map[string]*CDIDeviceNode, len(devices))
+	for _, dev := range devices {
+		if dev.DevType == common.AiCoreResourceName {
+			continue
+		}
+		ascendRuntimeOptions := ""
+		if common.IsVirtualDev(dev.DeviceName) {
+			ascendRuntimeOptions = common.VirtualDev
+		}
+		phyID, virID, err := common.GetDeviceID(dev.DeviceName, ascendRuntimeOptions)
+		if err != nil {
+			hwlog.RunLog.Warnf("get device id of %s failed, it will not be written into cdi spec, %v",
+				dev.DeviceName, err)
+			continue
+		}
+		id := phyID
+		if ascendRuntimeOptions == common.VirtualDev {
+			id = virID
+		}
+		name, deviceNode := newCDIDeviceNode(id, ascendRuntimeOptions)
+		deviceNodes[name] = deviceNode
+	}
+	cdiLock.Lock()
+	defer cdiLock.Unlock()
+	cdiDeviceNodes = deviceNodes
+	if vNPUIDs != nil {
+		vNPUNodes := make(map[string]*CDIDeviceNode, vNPUIDs.Len())
+		for _, id := range vNPUIDs.List() {
+			name, deviceNode := newCDIDeviceNode(id, common.VirtualDev)
+			vNPUNodes[name] = deviceNode
+		}
+		cdiVNPUNodes = vNPUNodes
+	}
+	cdiDefaultDevs = defaultDevs
+	return writeCDISpec()
+}
+
+// ensureCDIDevices make sure the allocated devices, like the vnpu created when allocate, are in the cdi spec
+func ensureCDIDevices(deviceIDs []int, ascendRuntimeOptions string, defaultDevs []string) error {
+	cdiLock.Lock()
+	defer cdiLock.Unlock()
+	for _, id := range deviceIDs {
+		name, deviceNode := newCDIDeviceNode(id, ascendRuntimeOptions)
+		if ascendRuntimeOptions == common.VirtualDev && !common.ParamOption.PresetVDevice {
+			cdiVNPUNodes[name] = deviceNode
+			continue
+		}
+		cdiDeviceNodes[name] = deviceNode
+	}
+	if len(cdiDefaultDevs) == 0 {
+		cdiDefaultDevs = defaultDevs
+	}
+	return writeCDISpec()
+}
+
+func newCDIDeviceNode(id int, ascendRuntimeOptions string) (string, *CDIDeviceNode) {
+	containerPath, hostPath := getDevPath(fmt.Sprintf("%d", id), ascendRuntimeOptions)
+	return filepath.Base(hostPath), &CDIDeviceNode{
+		Path:        containerPath,
+		HostPath:    hostPath,
+		Permissions: "rw",
+	}
+}
+
+func buildCDISpec() CDISpec {
+	allDeviceNodes := make(map[string]*CDIDeviceNode, len(cdiDeviceNodes)+len(cdiVNPUNodes))
+	for name, deviceNode := range cdiVNPUNodes {
+		allDeviceNodes[name] = deviceNode
+	}
+	for name, deviceNode := range cdiDeviceNodes {
+		allDeviceNodes[name] = deviceNode
+	}
+	spec := CDISpec{
+		CDIVersion: common.CDIVersion,
+		Kind:       common.CDIKind,
+		Devices:    make([]CDIDevice, 0, len(allDeviceNodes)),
+	}
+	for _, name := range sets.StringKeySet(allDeviceNodes).List() {
+		spec.Devices = append(spec.Devices, CDIDevice{
+			Name:           name,
+			ContainerEdits: CDIContainerEdits{DeviceNodes: []*CDIDeviceNode{allDeviceNodes[name]}},
+		})
+	}
+	for _, hostPath := range cdiDefaultDevs {
+		spec.ContainerEdits.DeviceNodes = append(spec.ContainerEdits.DeviceNodes, &CDIDeviceNode{
+			Path:        getDeviceContainerPath(hostPath),
+			HostPath:    hostPath,
+			Permissions: "rw",
+		})
+	}
+	return spec
+}
+
+// writeCDISpec write the cdi spec into file, the caller should hold cdiLock
+func writeCDISpec() error {
+	spec := buildCDISpec()
+	specHash := common.MakeDataHash(spec)
+	if specHash != "" && specHash == cdiSpecHash {
+		return nil
+	}
+	data := common.MarshalData(spec)
+	if len(data) == 0 {
+		return fmt.Errorf("marshal cdi spec failed")
+	}
+	specDir := common.ParamOption.CDISpecDir
+	if specDir == "" {
+		specDir = common.DefaultCDISpecDir
+	}
+	if err := os.MkdirAll(specDir, common.CDISpecDirMode); err != nil {
+		return fmt.Errorf("create cdi spec dir failed, %v", err)
+	}
+	specPath := filepath.Join(specDir, common.CDISpecFileName)
+	tmpPath := specPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, common.CDISpecFileMode); err != nil {
+		return fmt.Errorf("write cdi spec failed, %v", err)
+	}
+	if err := os.Rename(tmpPath, specPath); err != nil {
+		return fmt.Errorf("rename cdi spec failed, %v", err)
+	}
+	cdiSpecHash = specHash
+	hwlog.RunLog.Infof("cdi spec %s updated, device num: %d", specPath, len(spec.Devices))
+	return nil
+}
+
+// setCDIDevices return cdi device references by annotation, the devices will be injected by container runtime
+func (ps *PluginServer) setCDIDevices(resp *v1beta1.ContainerAllocateResponse, deviceIDs []int) error {
+	if err := ensureCDIDevices(deviceIDs, ps.ascendRuntimeOptions, ps.defaultDevs); err != nil {
+		return err
+	}
+	deviceRefs := make([]string, 0, len(deviceIDs))
+	for _, id := range deviceIDs {
+		name, _ := newCDIDeviceNode(id, ps.ascendRuntimeOptions)
+		deviceRefs = append(deviceRefs, fmt.Sprintf("%s=%s", common.CDIKind, name))
+	}
+	if resp.Annotations == nil {
+		resp.Annotations = make(map[string]string, 1)
+	}
+	annotationKey := fmt.Sprintf("%s%s_%s", common.CDIAnnotationPrefix, common.CDIAnnotationPluginName,
+		ps.deviceType)
+	resp.Annotations[annotationKey] = strings.Join(deviceRefs, common.CommaSepDev)
+	return nil
+}
@@ -0,0 +1,100 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+func readCDISpec(specDir string) CDISpec {
+	var spec CDISpec
+	data, err := os.ReadFile(filepath.Join(specDir, common.CDISpecFileName))
+	convey.So(err, convey.ShouldBeNil)
+	convey.So(json.Unmarshal(data, &spec), convey.ShouldBeNil)
+	return spec
+}
+
+// TestRefreshCDISpec for test refreshCDISpec
+func TestRefreshCDISpec(t *testing.T) {
+	common.ParamOption.CDISpecDir = t.TempDir()
+	defaultDevs := []string{common.HiAIManagerDeviceDocker, common.HiAIHDCDevice}
+	convey.Convey("test refreshCDISpec", t, func() {
+		convey.Convey("physical and virtual devices are written", func() {
+			devs := []common.NpuDevice{{DevType: common.Ascend910, DeviceName: "Ascend910-0"},
+				{DevType: common.Ascend910c2, DeviceName: "Ascend910-2c-100-1"},
+				{DevType: common.AiCoreResourceName, DeviceName: "npu-core-0"}}
+			convey.So(refreshCDISpec(devs, sets.NewInt(), defaultDevs), convey.ShouldBeNil)
+			spec := readCDISpec(common.ParamOption.CDISpecDir)
+			convey.So(spec.Kind, convey.ShouldEqual, common.CDIKind)
+			convey.So(len(spec.Devices), convey.ShouldEqual, 2)
+			convey.So(spec.Devices[0].Name, convey.ShouldEqual, "davinci0")
+			convey.So(spec.Devices[1].Name, convey.ShouldEqual, "vdavinci100")
+			convey.So(spec.Devices[1].ContainerEdits.DeviceNodes[0].Path, convey.ShouldEqual, "/dev/davinci100")
+			convey.So(len(spec.ContainerEdits.DeviceNodes), convey.ShouldEqual, len(defaultDevs))
+			convey.So(spec.ContainerEdits.DeviceNodes[0].Path, convey.ShouldEqual, common.HiAIManagerDevice)
+		})
+		convey.Convey("removed device is removed from spec", func() {
+			devs := []common.NpuDevice{{DevType: common.Ascend910, DeviceName: "Ascend910-0"}}
+			convey.So(refreshCDISpec(devs, sets.NewInt(), defaultDevs), convey.ShouldBeNil)
+			convey.So(len(readCDISpec(common.ParamOption.CDISpecDir).Devices), convey.ShouldEqual, 1)
+		})
+		convey.Convey("existing dynamic vnpu is kept", func() {
+			presetVDevice := common.ParamOption.PresetVDevice
+			defer func() {
+				common.ParamOption.PresetVDevice = presetVDevice
+			}()
+			common.ParamOption.PresetVDevice = false
+			devs := []common.NpuDevice{{DevType: common.Ascend910, DeviceName: "Ascend910-0"}}
+			convey.So(refreshCDISpec(devs, sets.NewInt(100), defaultDevs), convey.ShouldBeNil)
+			convey.So(ensureCDIDevices([]int{101}, common.VirtualDev, defaultDevs), convey.ShouldBeNil)
+			convey.So(refreshCDISpec(devs, nil, defaultDevs), convey.ShouldBeNil)
+			spec := readCDISpec(common.ParamOption.CDISpecDir)
+			convey.So(len(spec.Devices), convey.ShouldEqual, len([]int{0, 100, 101}))
+			convey.So(refreshCDISpec(devs, sets.NewInt(101), defaultDevs), convey.ShouldBeNil)
+			spec = readCDISpec(common.ParamOption.CDISpecDir)
+			convey.So(len(spec.Devices), convey.ShouldEqual, len([]int{0, 101}))
+			convey.So(spec.Devices[1].Name, convey.ShouldEqual, "vdavinci101")
+		})
+	})
+}
+
+// TestSetCDIDevices for test setCDIDevices
+func TestSetCDIDevices(t *testing.T) {
+	common.ParamOption.CDISpecDir = t.TempDir()
+	cdiDeviceNodes, cdiDefaultDevs, cdiSpecHash = make(map[string]*CDIDeviceNode, common.MaxDevicesNum), nil, ""
+	cdiVNPUNodes = make(map[string]*CDIDeviceNode, common.MaxDevicesNum)
+	ps := NewPluginServer(common.Ascend910, devices, []string{common.HiAIManagerDevice}, nil)
+	convey.Convey("test setCDIDevices", t, func() {
+		resp := new(v1beta1.ContainerAllocateResponse)
+		convey.So(ps.setCDIDevices(resp, []int{0, 1}), convey.ShouldBeNil)
+		annotationKey := common.CDIAnnotationPrefix + common.CDIAnnotationPluginName + "_" + common.Ascend910
+		convey.So(resp.Annotations[annotationKey], convey.ShouldEqual,
+			"huawei.com/npu=davinci0,huawei.com/npu=davinci1")
+		convey.So(len(resp.Devices), convey.ShouldEqual, 0)
+		spec := readCDISpec(common.ParamOption.CDISpecDir)
+		convey.So(len(spec.Devices), convey.ShouldEqual, len([]int{0, 1}))
+		convey.So(len(spec.ContainerEdits.DeviceNodes), convey.ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,229 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/common-utils/utils"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+const (
+	kubeletCheckpointPath = v1beta1.DevicePluginPath + "kubelet_internal_checkpoint"
+	virtualDevPathPrefix  = "/dev/vdavinci"
+	devPathPrefix         = "/dev/davinci"
+	cdiDevPathPrefix      = "/dev/"
+)
+
+// readKubeletCheckpoint read the pod device entries from kubelet device manager checkpoint
+func readKubeletCheckpoint(checkpointPath string) ([]PodDeviceEntry, error) {
+	data, err := utils.LoadFile(checkpointPath)
+	if err != nil {
+		return nil, fmt.Errorf("load kubelet checkpoint failed, %v", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("kubelet checkpoint is empty")
+	}
+	var checkpoint KubeletCheckpoint
+	if err = json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("unmarshal kubelet checkpoint failed, %v", err)
+	}
+	return checkpoint.Data.PodDeviceEntries, nil
+}
+
+// recoverAllocMapFromCheckpoint rebuild klt2RealDevMap of all plugin servers after restart by pod annotation,
+// kubelet checkpoint and pod resource
+func (hdm *HwDevManager) recoverAllocMapFromCheckpoint() {
+	if !common.ParamOption.UseVolcanoType || hdm.manager.GetKubeClient() == nil {
+		return
+	}
+	entries, err := readKubeletCheckpoint(kubeletCheckpointPath)
+	if err != nil {
+		hwlog.RunLog.Warnf("real allocate devices can only be recovered by pod annotation, %v", err)
+	}
+	podList, err := hdm.manager.GetKubeClient().GetActivePodList()
+	if err != nil {
+		hwlog.RunLog.Errorf("recover allocate map failed, get active pod list failed, %v", err)
+		return
+	}
+	podDevice, err := sharedPodResource.GetPodResource()
+	if err != nil {
+		hwlog.RunLog.Errorf("recover allocate map failed, get pod resource failed, %v", err)
+		return
+	}
+	for deviceType, element := range hdm.getServers() {
+		pluginServer, ok := element.(*PluginServer)
+		if !ok {
+			continue
+		}
+		unknownPods := pluginServer.recoverAllocMap(podList, podDevice, entries)
+		if len(unknownPods) != 0 {
+			hwlog.RunLog.Errorf("real allocate devices of %s of pods %v can not be determined, these devices "+
+				"may be allocated again", deviceType, unknownPods)
+		}
+	}
+}
+
+// recoverAllocMap recover klt2RealDevMap, return the pods whose real allocate devices can not be determined
+func (ps *PluginServer) recoverAllocMap(podList []v1.Pod, podDevice map[string]PodDevice,
+	entries []PodDeviceEntry) []string {
+	resourceName := common.ResourceNamePrefix + ps.deviceType
+	allocResps := make(map[string][]*v1beta1.ContainerAllocateResponse, len(entries))
+	for _, entry := range entries {
+		if entry.ResourceName != resourceName || len(entry.AllocResp) == 0 {
+			continue
+		}
+		resp := new(v1beta1.ContainerAllocateResponse)
+		if err := resp.Unmarshal(entry.AllocResp); err != nil {
+			hwlog.RunLog.Warnf("unmarshal allocate response of pod %s failed, %v", entry.PodUID, err)
+			continue
+		}
+		allocResps[entry.PodUID] = append(allocResps[entry.PodUID], resp)
+	}
+	var unknownPods []string
+	for _, pod := range podList {
+		podKey := pod.Namespace + common.UnderLine + pod.Name
+		podResource, exist := podDevice[podKey]
+		if !exist || podResource.ResourceName != resourceName {
+			continue
+		}
+		realDevices := ps.getRealDevicesFromAnnotation(pod)
+		if len(realDevices) == 0 {
+			realDevices = ps.getRealDevicesFromAllocResp(allocResps[string(pod.UID)])
+		}
+		if len(realDevices) == 0 {
+			unknownPods = append(unknownPods, podKey)
+			continue
+		}
+		ps.updateAllocMap(realDevices, podResource.DeviceIds)
+		hwlog.RunLog.Infof("recover allocate map of pod %s, kubelet devices: %v, real devices: %v", podKey,
+			podResource.DeviceIds, realDevices)
+	}
+	return unknownPods
+}
+
+func (ps *PluginServer) getRealDevicesFromAnnotation(pod v1.Pod) []string {
+	realDevice, exist := pod.Annotations[common.ResourceNamePrefix+common.PodRealAlloc]
+	if !exist || realDevice == "" {
+		return nil
+	}
+	return strings.Split(realDevice, common.CommaSepDev)
+}
+
+// getRealDevicesFromAllocResp get real devices by the env or device spec in allocate response
+func (ps *PluginServer) getRealDevicesFromAllocResp(resps []*v1beta1.ContainerAllocateResponse) []string {
+	var ids []int
+	isVirtual := false
+	for _, resp := range resps {
+		if visibleDevices, exist := resp.Envs[common.AscendVisibleDevicesEnv]; exist {
+			isVirtual = isVirtual || resp.Envs[common.AscendRuntimeOptionsEnv] == common.VirtualDev
+			for _, idStr := range strings.Split(visibleDevices, common.CommaSepDev) {
+				id, err := strconv.Atoi(idStr)
+				if err != nil {
+					return nil
+				}
+				ids = append(ids, id)
+			}
+			continue
+		}
+		for _, devSpec := range resp.Devices {
+			id, virtual, ok := getDeviceIDFromPath(devSpec.HostPath)
+			if ok {
+				ids = append(ids, id)
+				isVirtual = isVirtual || virtual
+			}
+		}
+		for _, cdiRef := range ps.getCDIDeviceRefs(resp) {
+			id, virtual, ok := getDeviceIDFromPath(cdiDevPathPrefix + strings.TrimPrefix(cdiRef, common.CDIKind+"="))
+			if ok {
+				ids = append(ids, id)
+				isVirtual = isVirtual || virtual
+			}
+		}
+	}
+	return ps.getDeviceNamesByID(ids, isVirtual)
+}
+
+func (ps *PluginServer) getCDIDeviceRefs(resp *v1beta1.ContainerAllocateResponse) []string {
+	annotationKey := fmt.Sprintf("%s%s_%s", common.CDIAnnotationPrefix, common.CDIAnnotationPluginName,
+		ps.deviceType)
+	deviceRefs, exist := resp.Annotations[annotationKey]
+	if !exist || deviceRefs == "" {
+		return nil
+	}
+	return strings.Split(deviceRefs, common.CommaSepDev)
+}
+
+// getDeviceIDFromPath parse the device id from host path like /dev/davinci0 or /dev/vdavinci100
+func getDeviceIDFromPath(hostPath string) (int, bool, bool) {
+	prefix, virtual := devPathPrefix, false
+	if strings.HasPrefix(hostPath, virtualDevPathPrefix) {
+		prefix, virtual = virtualDevPathPrefix, true
+	}
+	if !strings.HasPrefix(hostPath, prefix) {
+		return 0, false, false
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(hostPath, prefix))
+	if err != nil {
+		return 0, false, false
+	}
+	return id, virtual, true
+}
+
+// getDeviceNamesByID find the name of device whose physical id or virtual id is in ids, nil means not all found
+func (ps *PluginServer) getDeviceNamesByID(ids []int, isVirtual bool) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+	ascendRuntimeOptions := ""
+	if isVirtual {
+		ascendRuntimeOptions = common.VirtualDev
+	}
+	idSet := sets.NewInt(ids...)
+	names := sets.NewString()
+	ps.cachedLock.RLock()
+	defer ps.cachedLock.RUnlock()
+	for _, dev := range ps.cachedDevices {
+		phyID, virID, err := common.GetDeviceID(dev.DeviceName, ascendRuntimeOptions)
+		if err != nil {
+			continue
+		}
+		id := phyID
+		if isVirtual {
+			id = virID
+		}
+		if idSet.Has(id) {
+			names.Insert(dev.DeviceName)
+			idSet.Delete(id)
+		}
+	}
+	if idSet.Len() != 0 {
+		hwlog.RunLog.Warnf("device %v in allocate response not found in %s", idSet.List(),
+			filepath.Base(ps.deviceType))
+		return nil
+	}
+	return names.List()
+}
@@ -0,0 +1,104 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+func writeKubeletCheckpoint(t *testing.T, entries []PodDeviceEntry) string {
+	data, err := json.Marshal(KubeletCheckpoint{Data: KubeletCheckpointData{PodDeviceEntries: entries}})
+	convey.So(err, convey.ShouldBeNil)
+	checkpointPath := filepath.Join(t.TempDir(), "kubelet_internal_checkpoint")
+	convey.So(os.WriteFile(checkpointPath, data, common.CDISpecFileMode), convey.ShouldBeNil)
+	return checkpointPath
+}
+
+func marshalAllocResp(resp *v1beta1.ContainerAllocateResponse) []byte {
+	data, err := resp.Marshal()
+	convey.So(err, convey.ShouldBeNil)
+	return data
+}
+
+// TestRecoverAllocMap for test recoverAllocMap
+func TestRecoverAllocMap(t *testing.T) {
+	useVolcanoType := common.ParamOption.UseVolcanoType
+	common.ParamOption.UseVolcanoType = true
+	defer func() { common.ParamOption.UseVolcanoType = useVolcanoType }()
+	convey.Convey("test recoverAllocMap", t, func() {
+		ps := NewPluginServer(common.Ascend910, devices, nil, nil)
+		resourceName := common.ResourceNamePrefix + common.Ascend910
+		envResp := &v1beta1.ContainerAllocateResponse{Envs: map[string]string{
+			common.AscendVisibleDevicesEnv: "2,3"}}
+		mountResp := &v1beta1.ContainerAllocateResponse{Devices: []*v1beta1.DeviceSpec{
+			{HostPath: "/dev/davinci5"}, {HostPath: common.HiAIManagerDevice}}}
+		checkpointPath := writeKubeletCheckpoint(t, []PodDeviceEntry{
+			{PodUID: "uid-1", ResourceName: resourceName, AllocResp: marshalAllocResp(envResp)},
+			{PodUID: "uid-2", ResourceName: resourceName, AllocResp: marshalAllocResp(mountResp)}})
+		entries, err := readKubeletCheckpoint(checkpointPath)
+		convey.So(err, convey.ShouldBeNil)
+		podList := []v1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod1", UID: "uid-1"}},
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod2", UID: "uid-2"}},
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod3", UID: "uid-3", Annotations: map[string]string{
+				common.ResourceNamePrefix + common.PodRealAlloc: "Ascend910-6"}}},
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod4", UID: "uid-4"}},
+		}
+		podDevice := map[string]PodDevice{
+			"default_pod1": {ResourceName: resourceName, DeviceIds: []string{"Ascend910-0", "Ascend910-1"}},
+			"default_pod2": {ResourceName: resourceName, DeviceIds: []string{"Ascend910-4"}},
+			"default_pod3": {ResourceName: resourceName, DeviceIds: []string{"Ascend910-7"}},
+			"default_pod4": {ResourceName: resourceName, DeviceIds: []string{"Ascend910-2"}},
+		}
+		unknownPods := ps.recoverAllocMap(podList, podDevice, entries)
+		convey.So(unknownPods, convey.ShouldResemble, []string{"default_pod4"})
+		realDevices, err := ps.GetRealAllocateDevicesFromMap([]string{"Ascend910-0", "Ascend910-1"})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(realDevices, convey.ShouldResemble, []string{"Ascend910-2", "Ascend910-3"})
+		realDevices, err = ps.GetRealAllocateDevicesFromMap([]string{"Ascend910-4"})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(realDevices, convey.ShouldResemble, []string{"Ascend910-5"})
+		realDevices, err = ps.GetRealAllocateDevicesFromMap([]string{"Ascend910-7"})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(realDevices, convey.ShouldResemble, []string{"Ascend910-6"})
+	})
+}
+
+// TestReadKubeletCheckpoint for test readKubeletCheckpoint
+func TestReadKubeletCheckpoint(t *testing.T) {
+	convey.Convey("test readKubeletCheckpoint", t, func() {
+		convey.Convey("checkpoint not exist", func() {
+			_, err := readKubeletCheckpoint(filepath.Join(t.TempDir(), "not-exist"))
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+		convey.Convey("checkpoint is invalid", func() {
+			checkpointPath := filepath.Join(t.TempDir(), "kubelet_internal_checkpoint")
+			convey.So(os.WriteFile(checkpointPath, []byte("{"), common.CDISpecFileMode), convey.ShouldBeNil)
+			_, err := readKubeletCheckpoint(checkpointPath)
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,160 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+// updateNodeCondition publish the npu health condition to node, and add NoSchedule taint to node when the
+// number of separated npu reaches the threshold, node is only patched when the status is changed
+func (hdm *HwDevManager) updateNodeCondition() {
+	kubeClient := hdm.manager.GetKubeClient()
+	if kubeClient == nil {
+		return
+	}
+	statusSet, separatedNum := hdm.getNodeNPUStatus()
+	condition := newNPUHealthyCondition(statusSet)
+	needTaint := common.ParamOption.SeparateTaintNum > 0 && separatedNum >= common.ParamOption.SeparateTaintNum
+	conditionKey := fmt.Sprintf("%s-%s-%s-%v", condition.Status, condition.Reason, condition.Message, needTaint)
+	if conditionKey == hdm.nodeConditionKey {
+		return
+	}
+	curNode, err := kubeClient.GetNode()
+	if err != nil {
+		hwlog.RunLog.Errorf("get node failed when update npu condition, %v", err)
+		return
+	}
+	newNode := curNode.DeepCopy()
+	setNodeCondition(newNode, condition)
+	if _, _, err = kubeClient.PatchNodeState(curNode, newNode); err != nil {
+		hwlog.RunLog.Errorf("patch npu condition to node failed, %v", err)
+		return
+	}
+	newNode = curNode.DeepCopy()
+	if setSeparateTaint(newNode, needTaint) {
+		if _, err = kubeClient.PatchNode(curNode, newNode); err != nil {
+			hwlog.RunLog.Errorf("update npu separate taint of node failed, %v", err)
+			return
+		}
+		hwlog.RunLog.Infof("npu separate taint of node is updated, separated npu num: %d, taint: %v",
+			separatedNum, needTaint)
+	}
+	hdm.nodeConditionKey = conditionKey
+}
+
+// getNodeNPUStatus get the unhealthy and network unhealthy physical npu, and the number of separated npu
+func (hdm *HwDevManager) getNodeNPUStatus() (common.DevStatusSet, int) {
+	statusSet := common.DevStatusSet{UnHealthyDevice: sets.String{}, NetUnHealthyDevice: sets.String{}}
+	separatedNum := 0
+	for _, devices := range hdm.groupDevice {
+		for _, dev := range devices {
+			if common.IsVirtualDev(dev.DeviceName) {
+				continue
+			}
+			if dev.Health != v1beta1.Healthy {
+				statusSet.UnHealthyDevice.Insert(dev.DeviceName)
+			}
+			if dev.NetworkHealth != v1beta1.Healthy {
+				statusSet.NetUnHealthyDevice.Insert(dev.DeviceName)
+			}
+			faultType := common.GetCurrentFaultType(dev.FaultCodes, dev.LogicID)
+			if faultType == common.SeparateNPU || faultType == common.ManuallySeparateNPU {
+				separatedNum++
+			}
+		}
+	}
+	return statusSet, separatedNum
+}
+
+func newNPUHealthyCondition(statusSet common.DevStatusSet) v1.NodeCondition {
+	condition := v1.NodeCondition{
+		Type:    common.NPUHealthyConditionType,
+		Status:  v1.ConditionTrue,
+		Reason:  common.NPUHealthyReason,
+		Message: "all npu are healthy",
+	}
+	if statusSet.UnHealthyDevice.Len() == 0 && statusSet.NetUnHealthyDevice.Len() == 0 {
+		return condition
+	}
+	condition.Status = v1.ConditionFalse
+	condition.Reason = common.NPUUnhealthyReason
+	if statusSet.UnHealthyDevice.Len() == 0 {
+		condition.Reason = common.NPUNetworkUnhealthyReason
+	}
+	var messages []string
+	if statusSet.UnHealthyDevice.Len() != 0 {
+		messages = append(messages, fmt.Sprintf("unhealthy npu: %s",
+			strings.Join(statusSet.UnHealthyDevice.List(), common.CommaSepDev)))
+	}
+	if statusSet.NetUnHealthyDevice.Len() != 0 {
+		messages = append(messages, fmt.Sprintf("network unhealthy npu: %s",
+			strings.Join(statusSet.NetUnHealthyDevice.List(), common.CommaSepDev)))
+	}
+	condition.Message = strings.Join(messages, "; ")
+	return condition
+}
+
+// setNodeCondition set condition into node, the transition time is kept when the status is not changed
+func setNodeCondition(node *v1.Node, condition v1.NodeCondition) {
+	now := metav1.Now()
+	condition.LastHeartbeatTime = now
+	condition.LastTransitionTime = now
+	for i, existCondition := range node.Status.Conditions {
+		if existCondition.Type != condition.Type {
+			continue
+		}
+		if existCondition.Status == condition.Status {
+			condition.LastTransitionTime = existCondition.LastTransitionTime
+		}
+		node.Status.Conditions[i] = condition
+		return
+	}
+	node.Status.Conditions = append(node.Status.Conditions, condition)
+}
+
+// setSeparateTaint add or remove the npu separate taint of node, return whether the taints is changed
+func setSeparateTaint(node *v1.Node, needTaint bool) bool {
+	for i, taint := range node.Spec.Taints {
+		if taint.Key != common.NPUSeparateTaintKey {
+			continue
+		}
+		if needTaint {
+			return false
+		}
+		node.Spec.Taints = append(node.Spec.Taints[:i], node.Spec.Taints[i+1:]...)
+		return true
+	}
+	if !needTaint {
+		return false
+	}
+	now := metav1.Now()
+	node.Spec.Taints = append(node.Spec.Taints, v1.Taint{
+		Key:       common.NPUSeparateTaintKey,
+		Effect:    v1.TaintEffectNoSchedule,
+		TimeAdded: &now,
+	})
+	return true
+}
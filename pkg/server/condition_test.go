@@ -0,0 +1,85 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/smartystreets/goconvey/convey"
+	"k8s.io/api/core/v1"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"Ascend-device-plugin/pkg/common"
+	"Ascend-device-plugin/pkg/device"
+	"Ascend-device-plugin/pkg/kubeclient"
+)
+
+// TestUpdateNodeCondition for test updateNodeCondition
+func TestUpdateNodeCondition(t *testing.T) {
+	node := &v1.Node{}
+	patchTimes := 0
+	mockGetNode := gomonkey.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "GetNode",
+		func(_ *kubeclient.ClientK8s) (*v1.Node, error) {
+			return node.DeepCopy(), nil
+		})
+	defer mockGetNode.Reset()
+	mockPatchState := gomonkey.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "PatchNodeState",
+		func(_ *kubeclient.ClientK8s, _, newNode *v1.Node) (*v1.Node, []byte, error) {
+			patchTimes++
+			node.Status = newNode.Status
+			return node, nil, nil
+		})
+	defer mockPatchState.Reset()
+	mockPatchNode := gomonkey.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "PatchNode",
+		func(_ *kubeclient.ClientK8s, _, newNode *v1.Node) (*v1.Node, error) {
+			node.Spec = newNode.Spec
+			return node, nil
+		})
+	defer mockPatchNode.Reset()
+	common.ParamOption.SeparateTaintNum = 1
+	defer func() { common.ParamOption.SeparateTaintNum = 0 }()
+	convey.Convey("test updateNodeCondition", t, func() {
+		manager := device.NewHwAscend910Manager()
+		manager.SetKubeClient(&kubeclient.ClientK8s{})
+		hdm := &HwDevManager{manager: manager}
+		hdm.groupDevice = map[string][]*common.NpuDevice{common.Ascend910: {
+			{DeviceName: "Ascend910-0", Health: v1beta1.Unhealthy, NetworkHealth: v1beta1.Healthy,
+				FaultCodes: []int64{common.LinkDownFaultCode}},
+			{DeviceName: "Ascend910-1", Health: v1beta1.Healthy, NetworkHealth: v1beta1.Unhealthy}}}
+		hdm.updateNodeCondition()
+		convey.So(len(node.Status.Conditions), convey.ShouldEqual, 1)
+		condition := node.Status.Conditions[0]
+		convey.So(condition.Status, convey.ShouldEqual, v1.ConditionFalse)
+		convey.So(condition.Reason, convey.ShouldEqual, common.NPUUnhealthyReason)
+		convey.So(condition.Message, convey.ShouldEqual,
+			"unhealthy npu: Ascend910-0; network unhealthy npu: Ascend910-1")
+		convey.So(len(node.Spec.Taints), convey.ShouldEqual, 1)
+		convey.So(node.Spec.Taints[0].Effect, convey.ShouldEqual, v1.TaintEffectNoSchedule)
+
+		hdm.updateNodeCondition()
+		convey.So(patchTimes, convey.ShouldEqual, 1)
+
+		hdm.groupDevice = map[string][]*common.NpuDevice{common.Ascend910: {
+			{DeviceName: "Ascend910-0", Health: v1beta1.Healthy, NetworkHealth: v1beta1.Healthy},
+			{DeviceName: "Ascend910-1", Health: v1beta1.Healthy, NetworkHealth: v1beta1.Healthy}}}
+		hdm.updateNodeCondition()
+		convey.So(node.Status.Conditions[0].Status, convey.ShouldEqual, v1.ConditionTrue)
+		convey.So(node.Status.Conditions[0].Reason, convey.ShouldEqual, common.NPUHealthyReason)
+		convey.So(len(node.Spec.Taints), convey.ShouldEqual, 0)
+	})
+}
@@ -0,0 +1,129 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+// newDebugHandler return the read-only handler of debug http server, pprof is served when enablePprof is true
+func (hdm *HwDevManager) newDebugHandler(enablePprof bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(common.DebugStatePath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(hdm.getDebugInfo()); err != nil {
+			hwlog.RunLog.Warnf("write debug info failed, %v", err)
+		}
+	})
+	if enablePprof {
+		mux.HandleFunc(common.DebugPprofPath, pprof.Index)
+		mux.HandleFunc(common.DebugPprofPath+"cmdline", pprof.Cmdline)
+		mux.HandleFunc(common.DebugPprofPath+"profile", pprof.Profile)
+		mux.HandleFunc(common.DebugPprofPath+"symbol", pprof.Symbol)
+		mux.HandleFunc(common.DebugPprofPath+"trace", pprof.Trace)
+	}
+	return mux
+}
+
+// deviceDebugSnapshot the device info of HwDevManager saved at the end of each updateDevice
+type deviceDebugSnapshot struct {
+	groupDevice        map[string][]*common.NpuDevice
+	hotReset           map[string]*common.HotResetCacheInfo
+	graceTolerancePlan map[string]*common.GraceTolerancePlan
+}
+
+// saveDebugSnapshot save the device info for debug http server, the caller should hold the lock of device info
+func (hdm *HwDevManager) saveDebugSnapshot() {
+	snapshot := deviceDebugSnapshot{
+		groupDevice:        deepCopyGroupDevice(hdm.groupDevice),
+		hotReset:           make(map[string]*common.HotResetCacheInfo, len(hdm.getManagers())),
+		graceTolerancePlan: make(map[string]*common.GraceTolerancePlan, len(hdm.getManagers())),
+	}
+	for _, devices := range snapshot.groupDevice {
+		for _, dev := range devices {
+			dev.FaultCodes = append([]int64{}, dev.FaultCodes...)
+		}
+	}
+	for _, manager := range hdm.getManagers() {
+		if manager == nil {
+			continue
+		}
+		if hotReset := manager.GetHotResetCacheInfo(); hotReset != nil {
+			snapshot.hotReset[manager.GetName()] = hotReset
+		}
+		if plan := manager.GetGraceTolerancePlan(); plan != nil {
+			snapshot.graceTolerancePlan[manager.GetName()] = plan
+		}
+	}
+	hdm.debugLock.Lock()
+	hdm.debugSnapshot = snapshot
+	hdm.debugLock.Unlock()
+}
+
+// getDebugInfo get the snapshot of internal caches, the device info is the one saved by the last updateDevice, so it
+// is served while updateDevice is blocked by hot reset
+func (hdm *HwDevManager) getDebugInfo() DebugInfo {
+	servers := hdm.getServers()
+	info := DebugInfo{
+		Servers:             make(map[string]ServerDebugInfo, len(servers)),
+		ManuallySeparateNPU: common.GetManuallyFaultInfo(),
+		FaultFrequency:      common.GetFaultFrequencyInfo(),
+		FaultTypeCode:       common.GetFaultTypeCode(),
+	}
+	for deviceType, element := range servers {
+		if pluginServer, ok := element.(*PluginServer); ok {
+			info.Servers[deviceType] = pluginServer.getDebugInfo()
+		}
+	}
+	hdm.debugLock.RLock()
+	defer hdm.debugLock.RUnlock()
+	info.GroupDevice = hdm.debugSnapshot.groupDevice
+	info.HotReset = hdm.debugSnapshot.hotReset
+	info.GraceTolerancePlan = hdm.debugSnapshot.graceTolerancePlan
+	return info
+}
+
+// getDebugInfo get the snapshot of cached devices and allocate map
+func (ps *PluginServer) getDebugInfo() ServerDebugInfo {
+	ps.allocMapLock.RLock()
+	defer ps.allocMapLock.RUnlock()
+	ps.cachedLock.RLock()
+	defer ps.cachedLock.RUnlock()
+	info := ServerDebugInfo{
+		CachedDevices:  make([]common.NpuDevice, 0, len(ps.cachedDevices)),
+		Klt2RealDevMap: make(map[string]string, len(ps.klt2RealDevMap)),
+	}
+	for _, dev := range ps.cachedDevices {
+		dev.FaultCodes = append([]int64{}, dev.FaultCodes...)
+		info.CachedDevices = append(info.CachedDevices, dev)
+	}
+	for kltDev, realDev := range ps.klt2RealDevMap {
+		info.Klt2RealDevMap[kltDev] = realDev
+	}
+	return info
+}
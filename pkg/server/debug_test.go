@@ -0,0 +1,77 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"Ascend-device-plugin/pkg/common"
+	"Ascend-device-plugin/pkg/device"
+)
+
+// TestNewDebugHandler for test newDebugHandler
+func TestNewDebugHandler(t *testing.T) {
+	convey.Convey("test newDebugHandler", t, func() {
+		npuDevice := &common.NpuDevice{DevType: common.Ascend910, DeviceName: "Ascend910-0",
+			Health: v1beta1.Unhealthy, FaultCodes: []int64{0x80E01801}, AlarmRaisedTime: 1}
+		ps := NewPluginServer(common.Ascend910, []*common.NpuDevice{npuDevice}, nil, nil)
+		ps.klt2RealDevMap["Ascend910-1"] = "Ascend910-0"
+		hdm := &HwDevManager{
+			ServerMap:   map[string]InterfaceServer{common.Ascend910: ps},
+			groupDevice: map[string][]*common.NpuDevice{common.Ascend910: {npuDevice}},
+			manager:     device.NewHwAscend910Manager(),
+		}
+		hdm.saveDebugSnapshot()
+		convey.Convey("dump debug info while device info is locked by update", func() {
+			recorder := httptest.NewRecorder()
+			common.LockAllDeviceInfo()
+			hdm.newDebugHandler(false).ServeHTTP(recorder,
+				httptest.NewRequest(http.MethodGet, common.DebugStatePath, nil))
+			common.UnlockAllDeviceInfo()
+			convey.So(recorder.Code, convey.ShouldEqual, http.StatusOK)
+			var info DebugInfo
+			convey.So(json.Unmarshal(recorder.Body.Bytes(), &info), convey.ShouldBeNil)
+			convey.So(info.Servers[common.Ascend910].Klt2RealDevMap["Ascend910-1"], convey.ShouldEqual,
+				"Ascend910-0")
+			convey.So(info.Servers[common.Ascend910].CachedDevices[0].FaultCodes, convey.ShouldResemble,
+				npuDevice.FaultCodes)
+			convey.So(info.GroupDevice[common.Ascend910][0].AlarmRaisedTime, convey.ShouldEqual, 1)
+			convey.So(info.HotReset, convey.ShouldBeEmpty)
+		})
+		convey.Convey("debug info is read-only", func() {
+			recorder := httptest.NewRecorder()
+			hdm.newDebugHandler(false).ServeHTTP(recorder,
+				httptest.NewRequest(http.MethodPost, common.DebugStatePath, nil))
+			convey.So(recorder.Code, convey.ShouldEqual, http.StatusMethodNotAllowed)
+		})
+		convey.Convey("pprof is served only when it is enabled", func() {
+			recorder := httptest.NewRecorder()
+			hdm.newDebugHandler(false).ServeHTTP(recorder,
+				httptest.NewRequest(http.MethodGet, common.DebugPprofPath, nil))
+			convey.So(recorder.Code, convey.ShouldEqual, http.StatusNotFound)
+			recorder = httptest.NewRecorder()
+			hdm.newDebugHandler(true).ServeHTTP(recorder,
+				httptest.NewRequest(http.MethodGet, common.DebugPprofPath, nil))
+			convey.So(recorder.Code, convey.ShouldEqual, http.StatusOK)
+		})
+	})
+}
@@ -0,0 +1,248 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/smartystreets/goconvey/convey"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"Ascend-device-plugin/pkg/common"
+	"Ascend-device-plugin/pkg/device"
+	"Ascend-device-plugin/pkg/fakekubelet"
+	"Ascend-device-plugin/pkg/kubeclient"
+	"Ascend-device-plugin/pkg/simulator"
+)
+
+const (
+	e2eTimeout       = 10 * time.Second
+	e2eContainerName = "c0"
+	e2eNamespace     = "default"
+	e2eScenario910   = `
+devType: Ascend910
+chips:
+- {logicId: 0, phyId: 0, cardId: 0, ip: 192.168.100.100}
+- {logicId: 1, phyId: 1, cardId: 1, ip: 192.168.101.100}
+- {logicId: 2, phyId: 2, cardId: 2, ip: 192.168.102.100}
+- {logicId: 3, phyId: 3, cardId: 3, ip: 192.168.103.100}
+`
+	e2eScenario310P = `
+devType: Ascend310P
+chips:
+- {logicId: 0, phyId: 0, cardId: 0}
+`
+)
+
+// e2eEnv is the fake kubelet and device manager which the plugin server under test runs with
+type e2eEnv struct {
+	kubelet *fakekubelet.Kubelet
+	sim     *simulator.Simulator
+	manager device.DevManager
+	watcher *common.FileWatch
+	patches *gomonkey.Patches
+	option  common.Option
+}
+
+// newE2EEnv start the fake kubelet and point the sockets of plugin to it, the owner check of socket path is
+// skipped as the temp dir is not owned by root in every test environment
+func newE2EEnv(t *testing.T, scenarioData string, manager device.DevManager) *e2eEnv {
+	kubelet, err := fakekubelet.NewKubelet(t.TempDir())
+	convey.So(err, convey.ShouldBeNil)
+	scenario, err := simulator.ParseScenario([]byte(scenarioData))
+	convey.So(err, convey.ShouldBeNil)
+	sim, err := simulator.NewSimulator(scenario)
+	convey.So(err, convey.ShouldBeNil)
+	watcher, err := common.NewFileWatch()
+	convey.So(err, convey.ShouldBeNil)
+	manager.SetDmgr(sim)
+	manager.SetKubeClient(&kubeclient.ClientK8s{Clientset: kubelet.Clientset, NodeName: fakekubelet.NodeName,
+		DeviceInfoName: common.DeviceInfoCMNamePrefix + fakekubelet.NodeName})
+	env := &e2eEnv{kubelet: kubelet, sim: sim, manager: manager, watcher: watcher, option: common.ParamOption}
+	devicePluginPath, kubeletSocket, socketPath = kubelet.PluginDir, kubelet.KubeletSocket,
+		kubelet.PodResourceSocket
+	sharedPodResource = NewPodResource()
+	env.patches = gomonkey.ApplyFunc(common.VerifyPathAndPermission, func(verifyPath string,
+		_ int) (string, bool) {
+		_, err := os.Stat(verifyPath)
+		return verifyPath, err == nil
+	}).ApplyFunc(os.Lchown, func(_ string, _, _ int) error {
+		return nil
+	})
+	return env
+}
+
+func (env *e2eEnv) close() {
+	env.patches.Reset()
+	env.kubelet.Stop()
+	if err := env.watcher.FileWatcher.Close(); err != nil {
+		convey.So(err, convey.ShouldBeNil)
+	}
+	devicePluginPath, kubeletSocket = v1beta1.DevicePluginPath, v1beta1.KubeletSocket
+	socketPath = "/var/lib/kubelet/pod-resources/kubelet.sock"
+	sharedPodResource = NewPodResource()
+	common.ParamOption = env.option
+}
+
+func (env *e2eEnv) startPlugin(devType string, devs []*common.NpuDevice) (*PluginServer, *fakekubelet.Plugin) {
+	ps := NewPluginServer(devType, devs, []string{common.HiAIManagerDevice}, env.manager)
+	convey.So(ps.Start(env.watcher), convey.ShouldBeNil)
+	ps.SetRestartFlag(false)
+	plugin, err := env.kubelet.WaitForPlugin(common.ResourceNamePrefix+devType, e2eTimeout)
+	convey.So(err, convey.ShouldBeNil)
+	_, err = plugin.WaitForDevices(e2eTimeout, func(advertised []*v1beta1.Device) bool {
+		return len(advertised) == len(devs)
+	})
+	convey.So(err, convey.ShouldBeNil)
+	return ps, plugin
+}
+
+func (env *e2eEnv) chipNum() int {
+	num, _, err := env.sim.GetDeviceList()
+	convey.So(err, convey.ShouldBeNil)
+	return int(num)
+}
+
+func newE2EPod(name, resourceName string, num int64, annotations map[string]string) *v1.Pod {
+	limits := v1.ResourceList{v1.ResourceName(resourceName): *resource.NewQuantity(num, resource.DecimalExponent)}
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: e2eNamespace, UID: types.UID("uid-" + name),
+		Annotations: annotations}, Spec: v1.PodSpec{Containers: []v1.Container{{Name: e2eContainerName,
+		Resources: v1.ResourceRequirements{Limits: limits}}}}}
+}
+
+func newE2EDevices(devType string, num int) []*common.NpuDevice {
+	devs := make([]*common.NpuDevice, 0, num)
+	for i := 0; i < num; i++ {
+		devs = append(devs, &common.NpuDevice{DevType: devType, DeviceName: devType + "-" + strconv.Itoa(i),
+			Health: v1beta1.Healthy, PhyID: int32(i), LogicID: int32(i)})
+	}
+	return devs
+}
+
+// TestPluginServerE2E for test the registration, ListAndWatch and Allocate of plugin server with fake kubelet
+func TestPluginServerE2E(t *testing.T) {
+	convey.Convey("test plugin server with fake kubelet", t, func() {
+		env := newE2EEnv(t, e2eScenario910, device.NewHwAscend910Manager())
+		defer env.close()
+		common.ParamOption.PresetVDevice, common.ParamOption.UseVolcanoType = true, false
+		common.ParamOption.UseAscendDocker, common.ParamOption.UseCDI = true, false
+		devs := newE2EDevices(common.Ascend910, env.chipNum())
+		ps, plugin := env.startPlugin(common.Ascend910, devs)
+		defer ps.Stop()
+		convey.So(plugin.Options.GetPreferredAllocationAvailable, convey.ShouldBeTrue)
+		convey.So(ps.IsReady(), convey.ShouldBeTrue)
+
+		devs[1].Health = v1beta1.Unhealthy
+		convey.So(ps.Notify(devs), convey.ShouldBeTrue)
+		_, err := plugin.WaitForDevices(e2eTimeout, func(advertised []*v1beta1.Device) bool {
+			for _, dev := range advertised {
+				if dev.ID == devs[1].DeviceName {
+					return dev.Health == v1beta1.Unhealthy
+				}
+			}
+			return false
+		})
+		convey.So(err, convey.ShouldBeNil)
+
+		pod, err := env.kubelet.CreatePod(newE2EPod("normal", plugin.ResourceName, 2, nil))
+		convey.So(err, convey.ShouldBeNil)
+		resp, err := env.kubelet.Allocate(pod, e2eContainerName, plugin.ResourceName,
+			[]string{"Ascend910-0", "Ascend910-2"})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(resp.Envs[common.AscendVisibleDevicesEnv], convey.ShouldEqual, "0,2")
+		_, err = env.kubelet.Allocate(pod, e2eContainerName, plugin.ResourceName, []string{"Ascend910-9"})
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+}
+
+// TestVolcanoAllocateE2E for test the allocation by the annotation of volcano with fake kubelet
+func TestVolcanoAllocateE2E(t *testing.T) {
+	convey.Convey("test volcano allocate with fake kubelet", t, func() {
+		env := newE2EEnv(t, e2eScenario910, device.NewHwAscend910Manager())
+		defer env.close()
+		common.ParamOption.PresetVDevice, common.ParamOption.UseVolcanoType = true, true
+		common.ParamOption.UseAscendDocker, common.ParamOption.UseCDI = true, false
+		ps, plugin := env.startPlugin(common.Ascend910, newE2EDevices(common.Ascend910, env.chipNum()))
+		defer ps.Stop()
+		convey.So(plugin.Options.GetPreferredAllocationAvailable, convey.ShouldBeFalse)
+
+		pod, err := env.kubelet.CreatePod(newE2EPod("volcano", plugin.ResourceName, 1, map[string]string{
+			common.PodPredicateTime: "1",
+			common.HuaweiAscend910:  "Ascend910-3"}))
+		convey.So(err, convey.ShouldBeNil)
+		kltDevice := plugin.Devices()[0].ID
+		resp, err := env.kubelet.Allocate(pod, e2eContainerName, plugin.ResourceName, []string{kltDevice})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(resp.Envs[common.AscendVisibleDevicesEnv], convey.ShouldEqual, "3")
+		pod, err = env.kubelet.GetPod(e2eNamespace, pod.Name)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(pod.Annotations[common.PodPredicateTime], convey.ShouldEqual,
+			strconv.FormatUint(math.MaxUint64, common.BaseDec))
+
+		podDevice, err := sharedPodResource.GetPodResource()
+		convey.So(err, convey.ShouldBeNil)
+		podDeviceInfo, err := ps.GetKltAndRealAllocateDev([]v1.Pod{*pod}, podDevice)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(len(podDeviceInfo), convey.ShouldEqual, 1)
+		convey.So(podDeviceInfo[0].KltDevice, convey.ShouldResemble, []string{kltDevice})
+		convey.So(podDeviceInfo[0].RealDevice, convey.ShouldResemble, []string{"Ascend910-3"})
+		convey.So(env.manager.AddPodAnnotation(pod, podDeviceInfo[0].KltDevice, podDeviceInfo[0].RealDevice,
+			common.Ascend910, ""), convey.ShouldBeNil)
+		pod, err = env.kubelet.GetPod(e2eNamespace, pod.Name)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(pod.Annotations[common.ResourceNamePrefix+common.PodRealAlloc], convey.ShouldEqual, "Ascend910-3")
+		convey.So(pod.Annotations[common.Pod910DeviceKey], convey.ShouldContainSubstring, "192.168.103.100")
+	})
+}
+
+// TestDynamicVNPUAllocateE2E for test the dynamic vnpu created when allocate with fake kubelet
+func TestDynamicVNPUAllocateE2E(t *testing.T) {
+	convey.Convey("test dynamic vnpu allocate with fake kubelet", t, func() {
+		env := newE2EEnv(t, e2eScenario310P, device.NewHwAscend310PManager())
+		defer env.close()
+		common.ParamOption.PresetVDevice, common.ParamOption.UseVolcanoType = false, true
+		common.ParamOption.UseAscendDocker, common.ParamOption.UseCDI = true, false
+		common.ParamOption.AiCoreCount = common.MinAICoreNum
+		var aiCoreDevs []*common.NpuDevice
+		common.FakeAiCoreDevice(common.DavinCiDev{}, &aiCoreDevs)
+		ps, plugin := env.startPlugin(common.AiCoreResourceName, aiCoreDevs)
+		defer ps.Stop()
+
+		pod, err := env.kubelet.CreatePod(newE2EPod("vnpu", plugin.ResourceName, 2, map[string]string{
+			common.PodPredicateTime:                               "1",
+			common.ResourceNamePrefix + common.AiCoreResourceName: "0-" + common.Vir02}))
+		convey.So(err, convey.ShouldBeNil)
+		resp, err := env.kubelet.Allocate(pod, e2eContainerName, plugin.ResourceName,
+			[]string{aiCoreDevs[0].DeviceName, aiCoreDevs[1].DeviceName})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(resp.Envs[common.AscendRuntimeOptionsEnv], convey.ShouldEqual, common.VirtualDev)
+		vDevInfo, err := env.sim.GetVirtualDeviceInfo(0)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(len(vDevInfo.VDevInfo), convey.ShouldEqual, 1)
+		convey.So(vDevInfo.VDevInfo[0].QueryInfo.Name, convey.ShouldEqual, common.Vir02)
+		convey.So(resp.Envs[common.AscendVisibleDevicesEnv], convey.ShouldEqual,
+			strconv.Itoa(int(vDevInfo.VDevInfo[0].VDevID)))
+	})
+}
@@ -0,0 +1,142 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"strings"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"k8s.io/api/core/v1"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+// recordDeviceEvents emit events on node when the health or fault level of device changed, and emit events on the
+// pods which use the device when it turns unhealthy
+func (hdm *HwDevManager) recordDeviceEvents(oldGroupDevice map[string][]*common.NpuDevice) {
+	kubeClient := hdm.manager.GetKubeClient()
+	if kubeClient == nil {
+		return
+	}
+	if hdm.faultLevels == nil {
+		hdm.faultLevels = make(map[string]string, common.MaxDevicesNum)
+	}
+	oldHealth := make(map[string]string, common.MaxDevicesNum)
+	for _, oldDevices := range oldGroupDevice {
+		for _, oldDev := range oldDevices {
+			oldHealth[oldDev.DeviceName] = oldDev.Health
+		}
+	}
+	for _, devices := range hdm.groupDevice {
+		for _, dev := range devices {
+			if common.IsVirtualDev(dev.DeviceName) {
+				continue
+			}
+			faultCodes := strings.ToUpper(common.Int64Tool.ToHexString(dev.FaultCodes))
+			faultLevel := common.GetCurrentFaultType(dev.FaultCodes, dev.LogicID)
+			hdm.recordFaultLevelEvent(dev.DeviceName, faultLevel, faultCodes)
+			if health, exist := oldHealth[dev.DeviceName]; !exist || health == dev.Health {
+				continue
+			}
+			if dev.Health == v1beta1.Healthy {
+				kubeClient.RecordNodeEvent(v1.EventTypeNormal, common.EventReasonNPURecovered,
+					"device %s is recovered to healthy", dev.DeviceName)
+				continue
+			}
+			kubeClient.RecordNodeEvent(v1.EventTypeWarning, common.EventReasonNPUUnhealthy,
+				"device %s is unhealthy, fault level: %s, fault codes: %s", dev.DeviceName, faultLevel, faultCodes)
+			for _, pod := range hdm.getPodsUsingDevice(dev.DeviceName) {
+				kubeClient.RecordPodEvent(&pod, v1.EventTypeWarning, common.EventReasonNPUIsolated,
+					"device %s used by pod is unhealthy and isolated, fault level: %s, fault codes: %s",
+					dev.DeviceName, faultLevel, faultCodes)
+			}
+		}
+	}
+}
+
+func (hdm *HwDevManager) recordFaultLevelEvent(deviceName, faultLevel, faultCodes string) {
+	lastFaultLevel, exist := hdm.faultLevels[deviceName]
+	if !exist {
+		lastFaultLevel = common.NormalNPU
+	}
+	hdm.faultLevels[deviceName] = faultLevel
+	if lastFaultLevel == faultLevel {
+		return
+	}
+	kubeClient := hdm.manager.GetKubeClient()
+	switch faultLevel {
+	case common.ManuallySeparateNPU:
+		kubeClient.RecordNodeEvent(v1.EventTypeWarning, common.EventReasonNPUManuallySeparated,
+			"device %s is manually separated, fault codes: %s", deviceName, faultCodes)
+	case common.NormalNPU:
+		kubeClient.RecordNodeEvent(v1.EventTypeNormal, common.EventReasonNPUFaultLevelChanged,
+			"fault level of device %s changed from %s to %s", deviceName, lastFaultLevel, faultLevel)
+	default:
+		kubeClient.RecordNodeEvent(v1.EventTypeWarning, common.EventReasonNPUFaultLevelChanged,
+			"fault level of device %s changed from %s to %s, fault codes: %s", deviceName, lastFaultLevel,
+			faultLevel, faultCodes)
+	}
+}
+
+// getPodsUsingDevice get the pods using the device by the real allocated devices annotated by volcano, the pod
+// without the annotation is matched by the devices allocated by kubelet, see getPodResourceRealDevices
+func (hdm *HwDevManager) getPodsUsingDevice(deviceName string) []v1.Pod {
+	var pods []v1.Pod
+	var podDevice map[string]PodDevice
+	for _, pod := range hdm.manager.GetKubeClient().GetActivePodListCache() {
+		realDevice, exist := pod.Annotations[common.ResourceNamePrefix+common.PodRealAlloc]
+		if exist {
+			if common.StringTool.Index(strings.Split(realDevice, common.CommaSepDev), deviceName) != -1 {
+				pods = append(pods, pod)
+			}
+			continue
+		}
+		if podDevice == nil {
+			var err error
+			if podDevice, err = hdm.getCyclePodResource(); err != nil {
+				hwlog.RunLog.Warnf("get pod resource failed, %v", err)
+				podDevice = make(map[string]PodDevice)
+			}
+		}
+		if common.StringTool.Index(hdm.getPodResourceRealDevices(pod, podDevice), deviceName) != -1 {
+			pods = append(pods, pod)
+		}
+	}
+	return pods
+}
+
+// getPodResourceRealDevices get the real devices of pod by the devices allocated by kubelet and klt2RealDevMap
+func (hdm *HwDevManager) getPodResourceRealDevices(pod v1.Pod, podDevice map[string]PodDevice) []string {
+	podResource, exist := podDevice[pod.Namespace+common.UnderLine+pod.Name]
+	if !exist {
+		return nil
+	}
+	element, exist := hdm.getServer(strings.TrimPrefix(podResource.ResourceName, common.ResourceNamePrefix))
+	if !exist {
+		return nil
+	}
+	pluginServer, ok := element.(*PluginServer)
+	if !ok {
+		return nil
+	}
+	realDevices, err := pluginServer.GetRealAllocateDevicesFromMap(podResource.DeviceIds)
+	if err != nil {
+		hwlog.RunLog.Debugf("get real devices of pod %s failed, %v", pod.Name, err)
+		return nil
+	}
+	return realDevices
+}
@@ -0,0 +1,138 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"fmt"
+
+	"huawei.com/npu-exporter/v5/devmanager"
+
+	"Ascend-device-plugin/pkg/common"
+	"Ascend-device-plugin/pkg/device"
+)
+
+// newDevManager create the device manager of the device type, and return the run mode of it
+func newDevManager(devType string) (device.DevManager, string, error) {
+	switch devType {
+	case common.Ascend310, common.Ascend310B:
+		return device.NewHwAscend310Manager(), common.Ascend310, nil
+	case common.Ascend910, common.Ascend910B:
+		return device.NewHwAscend910Manager(), common.Ascend910, nil
+	case common.Ascend310P:
+		return device.NewHwAscend310PManager(), common.Ascend310P, nil
+	default:
+		return nil, "", fmt.Errorf("an unsupported device type %s", devType)
+	}
+}
+
+// getManagers get the device managers of all chip families, the first is the manager of hdm
+func (hdm *HwDevManager) getManagers() []device.DevManager {
+	if len(hdm.managers) == 0 {
+		return []device.DevManager{hdm.manager}
+	}
+	return hdm.managers
+}
+
+// getManager get the device manager which owns the device type, the ai core resource and unknown device type are
+// owned by the first manager
+func (hdm *HwDevManager) getManager(deviceType string) device.DevManager {
+	if manager, ok := hdm.devTypeManager[deviceType]; ok {
+		return manager
+	}
+	return hdm.manager
+}
+
+// getRunMode get the run mode of the device manager
+func (hdm *HwDevManager) getRunMode(manager device.DevManager) string {
+	if manager == hdm.manager {
+		return hdm.RunMode
+	}
+	_, runMode, err := newDevManager(manager.GetDmgr().GetDevType())
+	if err != nil {
+		return hdm.RunMode
+	}
+	return runMode
+}
+
+// getGroupDeviceOf get the devices owned by the device manager
+func (hdm *HwDevManager) getGroupDeviceOf(manager device.DevManager,
+	groupDevice map[string][]*common.NpuDevice) map[string][]*common.NpuDevice {
+	if len(hdm.getManagers()) == 1 {
+		return groupDevice
+	}
+	managerGroupDevice := make(map[string][]*common.NpuDevice, len(groupDevice))
+	for devType, devices := range groupDevice {
+		if hdm.getManager(devType) == manager {
+			managerGroupDevice[devType] = devices
+		}
+	}
+	return managerGroupDevice
+}
+
+// getAllNPUs get the npus of all chip families, and the device manager of each device type on node with mixed chip
+// families
+func (hdm *HwDevManager) getAllNPUs() (common.NpuAllInfo, map[string]device.DevManager, error) {
+	managers := hdm.getManagers()
+	if len(managers) == 1 {
+		allInfo, err := managers[0].GetNPUs()
+		return allInfo, nil, err
+	}
+	var allInfo common.NpuAllInfo
+	devTypeManager := make(map[string]device.DevManager, len(managers))
+	for _, manager := range managers {
+		familyInfo, err := manager.GetNPUs()
+		if err != nil {
+			return common.NpuAllInfo{}, nil, fmt.Errorf("get npus of %s failed, %v",
+				manager.GetDmgr().GetDevType(), err)
+		}
+		for _, devType := range familyInfo.AllDevTypes {
+			if _, exist := devTypeManager[devType]; exist {
+				return common.NpuAllInfo{}, nil, fmt.Errorf("device type %s is found in more than one chip "+
+					"family", devType)
+			}
+			devTypeManager[devType] = manager
+		}
+		allInfo.AllDevTypes = append(allInfo.AllDevTypes, familyInfo.AllDevTypes...)
+		allInfo.AllDevs = append(allInfo.AllDevs, familyInfo.AllDevs...)
+		allInfo.AICoreDevs = append(allInfo.AICoreDevs, familyInfo.AICoreDevs...)
+	}
+	return allInfo, devTypeManager, nil
+}
+
+// setFamilyManagers create a device manager for each chip family, the training chip family is the first
+func (hdm *HwDevManager) setFamilyManagers(dmgr devmanager.DeviceInterface) error {
+	familyDmgrs := device.SplitChipFamilies(dmgr)
+	if len(familyDmgrs) > 1 && !common.ParamOption.PresetVDevice {
+		return fmt.Errorf("presetVirtualDevice false is not supported on node with mixed chip families")
+	}
+	hdm.managers = make([]device.DevManager, 0, len(familyDmgrs))
+	mixedCardTypes := make([]string, 0, len(familyDmgrs))
+	for index, familyDmgr := range familyDmgrs {
+		manager, runMode, err := newDevManager(familyDmgr.GetDevType())
+		if err != nil {
+			return err
+		}
+		manager.SetDmgr(familyDmgr)
+		hdm.managers = append(hdm.managers, manager)
+		if index == 0 {
+			hdm.manager, hdm.RunMode = manager, runMode
+			continue
+		}
+		mixedCardTypes = append(mixedCardTypes, familyDmgr.GetDevType())
+	}
+	common.ParamOption.MixedCardTypes = mixedCardTypes
+	return nil
+}
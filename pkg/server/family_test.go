@@ -0,0 +1,107 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/smartystreets/goconvey/convey"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"Ascend-device-plugin/pkg/common"
+	"Ascend-device-plugin/pkg/simulator"
+)
+
+const mixedFamilyScenario = `
+devType: Ascend910B
+chipName: 910B3
+chips:
+- {logicId: 0, phyId: 0, cardId: 0, ip: 192.168.100.100}
+- {logicId: 1, phyId: 1, cardId: 1, chipName: 310P3}
+- {logicId: 2, phyId: 2, cardId: 2, chipName: 310P3}
+`
+
+// TestMixedChipFamilies for test HwDevManager on node with mixed chip families
+func TestMixedChipFamilies(t *testing.T) {
+	option, subscribeFailed := common.ParamOption, common.SubscribeFailed
+	defer func() {
+		common.ParamOption, common.SubscribeFailed = option, subscribeFailed
+	}()
+	patches := gomonkey.ApplyMethod(reflect.TypeOf(new(HwDevManager)), "UpdateNodeLabel",
+		func(_ *HwDevManager) error {
+			return nil
+		})
+	defer patches.Reset()
+	convey.Convey("test HwDevManager with mixed chip families", t, func() {
+		scenario, err := simulator.ParseScenario([]byte(mixedFamilyScenario))
+		convey.So(err, convey.ShouldBeNil)
+		sim, err := simulator.NewSimulator(scenario)
+		convey.So(err, convey.ShouldBeNil)
+		convey.Convey("dynamic vnpu is not supported", func() {
+			common.ParamOption.PresetVDevice = false
+			convey.So(NewHwDevManager(sim), convey.ShouldBeNil)
+		})
+		convey.Convey("each chip family owns its device types", func() {
+			common.ParamOption.PresetVDevice, common.ParamOption.HotReset = true, common.HotResetClose
+			hdm := NewHwDevManager(sim)
+			convey.So(hdm, convey.ShouldNotBeNil)
+			convey.So(len(hdm.managers), convey.ShouldEqual, 2)
+			convey.So(hdm.RunMode, convey.ShouldEqual, common.Ascend910)
+			convey.So(common.ParamOption.RealCardType, convey.ShouldEqual, common.Ascend910B)
+			convey.So(common.ParamOption.MixedCardTypes, convey.ShouldResemble, []string{common.Ascend310P})
+			convey.So(len(hdm.ServerMap), convey.ShouldEqual, 2)
+			convey.So(hdm.getManager(common.Ascend910), convey.ShouldEqual, hdm.managers[0])
+			convey.So(hdm.getManager(common.Ascend310P), convey.ShouldEqual, hdm.managers[1])
+			convey.So(hdm.getRunMode(hdm.managers[1]), convey.ShouldEqual, common.Ascend310P)
+			convey.So(len(hdm.getGroupDeviceOf(hdm.managers[1], hdm.groupDevice)[common.Ascend310P]),
+				convey.ShouldEqual, 2)
+			ps, ok := hdm.ServerMap[common.Ascend310P].(*PluginServer)
+			convey.So(ok, convey.ShouldBeTrue)
+			convey.So(ps.manager, convey.ShouldEqual, hdm.managers[1])
+
+			convey.So(sim.ApplyEvent(simulator.Event{Action: simulator.ActionFaultOccur, LogicID: 1,
+				FaultCode: 0x80C98008}), convey.ShouldBeNil)
+			common.SubscribeFailed = true
+			convey.So(hdm.updateAllInfo(), convey.ShouldBeNil)
+			initTime := time.Now()
+			hdm.notifyToK8s(&initTime)
+			convey.So(hdm.groupDevice[common.Ascend310P][0].Health, convey.ShouldEqual, v1beta1.Unhealthy)
+			convey.So(hdm.groupDevice[common.Ascend910][0].Health, convey.ShouldEqual, v1beta1.Healthy)
+		})
+		convey.Convey("admin reset is decided by the chip family of device", func() {
+			common.ParamOption.PresetVDevice, common.ParamOption.HotReset = true, common.HotResetClose
+			hdm := NewHwDevManager(sim)
+			convey.So(hdm, convey.ShouldNotBeNil)
+			mockPodResource := gomonkey.ApplyMethod(reflect.TypeOf(new(PodResource)), "GetPodResource",
+				func(_ *PodResource) (map[string]PodDevice, error) {
+					return nil, errors.New("kubelet is not running")
+				})
+			defer mockPodResource.Reset()
+			_, err := hdm.resetIdleDevice(common.AdminRequest{DeviceName: "Ascend910-0"})
+			adminErr, ok := err.(*adminError)
+			convey.So(ok && adminErr.code == http.StatusBadRequest, convey.ShouldBeTrue)
+			_, err = hdm.resetIdleDevice(common.AdminRequest{DeviceName: "Ascend310P-1"})
+			convey.So(err, convey.ShouldNotBeNil)
+			_, ok = err.(*adminError)
+			convey.So(ok, convey.ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,195 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"k8s.io/api/core/v1"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+// handleFaultPolicy act on the fault levels which the chip can recover from without isolation. The chip of
+// FreeRestartNPU stays unhealthy and is reset as soon as no pod uses it, the pods using the chip of RestartBusiness
+// are signaled to restart their business and the chip is not reset
+func (hdm *HwDevManager) handleFaultPolicy() {
+	if common.ParamOption.BuildScene == common.EdgeScene || hdm.manager.GetKubeClient() == nil {
+		return
+	}
+	var restartBusinessDevs []*common.NpuDevice
+	for devType, devices := range hdm.groupDevice {
+		if common.IsVirtualDev(devType) {
+			continue
+		}
+		for _, dev := range devices {
+			switch common.GetCurrentFaultType(dev.FaultCodes, dev.LogicID) {
+			case common.FreeRestartNPU:
+				hdm.resetFreeDevice(devType, dev, devices)
+			case common.RestartBusiness:
+				restartBusinessDevs = append(restartBusinessDevs, dev)
+			default:
+			}
+		}
+	}
+	hdm.signalRestartBusiness(restartBusinessDevs)
+}
+
+// isInferResetDevType whether the unhealthy chips of the device type are reset by chipHotReset
+func (hdm *HwDevManager) isInferResetDevType(devType string) bool {
+	return common.ParamOption.HotReset == common.HotResetInfer &&
+		hdm.getRunMode(hdm.getManager(devType)) != common.Ascend910
+}
+
+// resetFreeDevice reset the chip of FreeRestartNPU when no pod uses it. The chips reset together should be all free,
+// they are the chips of the card on Atlas 300I Duo and the chips of the HCCS ring on training chip. The reset takes
+// a long time, so it is executed in background
+func (hdm *HwDevManager) resetFreeDevice(devType string, dev *common.NpuDevice, devices []*common.NpuDevice) {
+	if common.ParamOption.HotReset == common.HotResetClose || common.ParamOption.HotResetDryRun ||
+		hdm.isInferResetDevType(devType) || common.CheckDeviceResetBudget(dev.LogicID) != nil {
+		return
+	}
+	resetDevices := []*common.NpuDevice{dev}
+	if common.IsContainAtlas300IDuo() {
+		resetDevices = getCardDevices(dev.CardID, devices)
+	} else if topology := hdm.getManager(devType).GetChipTopology(); topology != nil {
+		var ok bool
+		if resetDevices, ok = getRingDevices(topology.GetRing(dev.LogicID), devices); !ok {
+			hwlog.RunLog.Warnf("not all chips on the ring of device %s are found, skip hot reset", dev.DeviceName)
+			return
+		}
+	}
+	if len(resetDevices) == 0 {
+		return
+	}
+	// the chips reset together are reset and claimed by the first one, as chipHotReset and admin reset do
+	resetDev := resetDevices[0]
+	for _, ringDev := range resetDevices {
+		if hdm.isDeviceInTrainReset(devType, ringDev.LogicID) || hdm.isDeviceInHotReset(ringDev.LogicID) {
+			return
+		}
+	}
+	podDevice, err := hdm.getCyclePodResource()
+	if err != nil {
+		hwlog.RunLog.Errorf("get pod resource failed, %v", err)
+		return
+	}
+	if !hdm.isDuoRemove(devType, resetDevices, podDevice) {
+		return
+	}
+	if !hdm.hotResetInBackground(resetDev) {
+		return
+	}
+	hwlog.RunLog.Infof("devices %v reset with %s of %s are not used by any pod, start hot reset",
+		getDeviceNames(resetDevices), dev.DeviceName, common.FreeRestartNPU)
+}
+
+// isDeviceInTrainReset whether the chip is being reset by the hot reset of training task
+func (hdm *HwDevManager) isDeviceInTrainReset(devType string, logicID int32) bool {
+	cacheInfo := hdm.getManager(devType).GetHotResetCacheInfo()
+	if cacheInfo == nil {
+		return false
+	}
+	for _, resetLogicID := range cacheInfo.ResetDevices {
+		if resetLogicID == logicID {
+			return true
+		}
+	}
+	return false
+}
+
+// getRingDevices get the devices of the chips on ring, false means some chip is not found
+func getRingDevices(ring []int32, devices []*common.NpuDevice) ([]*common.NpuDevice, bool) {
+	ringDevices := make([]*common.NpuDevice, 0, len(ring))
+	for _, logicID := range ring {
+		found := false
+		for _, dev := range devices {
+			if dev.LogicID == logicID {
+				ringDevices = append(ringDevices, dev)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return ringDevices, len(ringDevices) != 0
+}
+
+func getDeviceNames(devices []*common.NpuDevice) []string {
+	deviceNames := make([]string, 0, len(devices))
+	for _, dev := range devices {
+		deviceNames = append(deviceNames, dev.DeviceName)
+	}
+	return deviceNames
+}
+
+func getCardDevices(cardID int32, devices []*common.NpuDevice) []*common.NpuDevice {
+	var cardDevices []*common.NpuDevice
+	for _, dev := range devices {
+		if dev.CardID == cardID {
+			cardDevices = append(cardDevices, dev)
+		}
+	}
+	return cardDevices
+}
+
+// signalRestartBusiness annotate the restart signal on the pods using the chips of RestartBusiness, the pod is
+// annotated again only when its faulty devices or the raised time of fault changed
+func (hdm *HwDevManager) signalRestartBusiness(devices []*common.NpuDevice) {
+	pods := make(map[string]v1.Pod, len(devices))
+	signals := make(map[string]*common.RestartBusinessSignal, len(devices))
+	for _, dev := range devices {
+		for _, pod := range hdm.getPodsUsingDevice(dev.DeviceName) {
+			podKey := pod.Namespace + common.UnderLine + pod.Name
+			signal, exist := signals[podKey]
+			if !exist {
+				signal = &common.RestartBusinessSignal{}
+				signals[podKey], pods[podKey] = signal, pod
+			}
+			signal.Devices = append(signal.Devices, dev.DeviceName)
+			if dev.AlarmRaisedTime > signal.RaisedTime {
+				signal.RaisedTime = dev.AlarmRaisedTime
+			}
+		}
+	}
+	kubeClient := hdm.manager.GetKubeClient()
+	for podKey, signal := range signals {
+		sort.Strings(signal.Devices)
+		data, err := json.Marshal(signal)
+		if err != nil {
+			hwlog.RunLog.Errorf("marshal restart business signal of pod %s failed, %v", podKey, err)
+			continue
+		}
+		pod := pods[podKey]
+		if pod.Annotations[common.PodRestartBusinessKey] == string(data) {
+			continue
+		}
+		if err = kubeClient.TryUpdatePodAnnotation(&pod,
+			map[string]string{common.PodRestartBusinessKey: string(data)}); err != nil {
+			hwlog.RunLog.Errorf("signal pod %s to restart business failed, %v", podKey, err)
+			continue
+		}
+		hwlog.RunLog.Infof("signal pod %s to restart business, devices: %v", podKey, signal.Devices)
+		kubeClient.RecordPodEvent(&pod, v1.EventTypeWarning, common.EventReasonNPURestartBusiness,
+			"device %s used by pod raised %s fault, business of pod should be restarted",
+			strings.Join(signal.Devices, common.CommaSepDev), common.RestartBusiness)
+	}
+}
@@ -0,0 +1,221 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/smartystreets/goconvey/convey"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"Ascend-device-plugin/pkg/common"
+	"Ascend-device-plugin/pkg/device"
+	"Ascend-device-plugin/pkg/kubeclient"
+	"Ascend-device-plugin/pkg/simulator"
+)
+
+const faultPolicyScenario = `
+devType: Ascend310P
+chips:
+- {logicId: 0, phyId: 0, cardId: 0}
+- {logicId: 1, phyId: 1, cardId: 1}
+`
+
+const faultPolicyRingScenario = `
+devType: Ascend910
+chips:
+- {logicId: 0, phyId: 0, cardId: 0}
+- {logicId: 1, phyId: 1, cardId: 1}
+- {logicId: 2, phyId: 2, cardId: 2}
+- {logicId: 3, phyId: 3, cardId: 3}
+`
+
+type faultPolicyEnv struct {
+	hdm          *HwDevManager
+	pods         []v1.Pod
+	moveComplete bool
+	usedDevices  map[string]bool
+	resetTimes   int
+	annotations  []string
+	podReasons   []string
+}
+
+func newFaultPolicyEnv() (*faultPolicyEnv, *gomonkey.Patches) {
+	env := &faultPolicyEnv{pods: []v1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default",
+		Annotations: map[string]string{common.ResourceNamePrefix + common.PodRealAlloc: "Ascend310P-1"}}}}}
+	patches := gomonkey.ApplyFunc(common.GetCurrentFaultType, func(_ []int64, logicID int32) string {
+		if logicID == 0 {
+			return common.FreeRestartNPU
+		}
+		return common.RestartBusiness
+	})
+	patches.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "GetActivePodListCache",
+		func(_ *kubeclient.ClientK8s) []v1.Pod { return env.pods })
+	patches.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "GetAllPodListCache",
+		func(_ *kubeclient.ClientK8s) []v1.Pod { return env.pods })
+	patches.ApplyMethod(reflect.TypeOf(new(PodResource)), "IsPodMoveComplete",
+		func(_ *PodResource, deviceName string, _ []v1.Pod, _ *PluginServer, _ map[string]PodDevice) bool {
+			return env.moveComplete && !env.usedDevices[deviceName]
+		})
+	patches.ApplyMethod(reflect.TypeOf(new(PodResource)), "GetPodResource",
+		func(_ *PodResource) (map[string]PodDevice, error) { return map[string]PodDevice{}, nil })
+	patches.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "TryUpdatePodAnnotation",
+		func(_ *kubeclient.ClientK8s, pod *v1.Pod, annotation map[string]string) error {
+			env.annotations = append(env.annotations, annotation[common.PodRestartBusinessKey])
+			return nil
+		})
+	patches.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "RecordPodEvent",
+		func(_ *kubeclient.ClientK8s, _ *v1.Pod, _, reason, _ string, _ ...interface{}) {
+			env.podReasons = append(env.podReasons, reason)
+		})
+	patches.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "RecordNodeEvent",
+		func(_ *kubeclient.ClientK8s, _, _, _ string, _ ...interface{}) {})
+	patches.ApplyMethod(reflect.TypeOf(new(simulator.Simulator)), "SetDeviceReset",
+		func(_ *simulator.Simulator, _, _ int32) error {
+			env.resetTimes++
+			return nil
+		})
+	return env, patches
+}
+
+// waitFreeReset wait the hot reset executed in background by fault policy
+func (env *faultPolicyEnv) waitFreeReset() {
+	const maxWaitTimes = 100
+	for i := 0; i < maxWaitTimes; i++ {
+		inReset := false
+		env.hdm.resetDevs.Range(func(_, _ interface{}) bool {
+			inReset = true
+			return false
+		})
+		if !inReset {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestHandleFaultPolicy for test handleFaultPolicy
+func TestHandleFaultPolicy(t *testing.T) {
+	option := common.ParamOption
+	defer func() {
+		common.ParamOption = option
+	}()
+	env, patches := newFaultPolicyEnv()
+	defer patches.Reset()
+	convey.Convey("test handleFaultPolicy", t, func() {
+		scenario, err := simulator.ParseScenario([]byte(faultPolicyScenario))
+		convey.So(err, convey.ShouldBeNil)
+		sim, err := simulator.NewSimulator(scenario)
+		convey.So(err, convey.ShouldBeNil)
+		manager := device.NewHwAscend310PManager()
+		manager.SetDmgr(sim)
+		manager.SetKubeClient(&kubeclient.ClientK8s{})
+		devices := []*common.NpuDevice{
+			{DevType: common.Ascend310P, DeviceName: "Ascend310P-0", Health: v1beta1.Unhealthy},
+			{DevType: common.Ascend310P, DeviceName: "Ascend310P-1", LogicID: 1, PhyID: 1, CardID: 1,
+				Health: v1beta1.Unhealthy, AlarmRaisedTime: 1}}
+		env.hdm = &HwDevManager{manager: manager, RunMode: common.Ascend310P,
+			groupDevice: map[string][]*common.NpuDevice{common.Ascend310P: devices},
+			ServerMap: map[string]InterfaceServer{common.Ascend310P: NewPluginServer(common.Ascend310P, devices,
+				nil, manager)}}
+		env.resetTimes, env.annotations, env.podReasons, env.moveComplete = 0, nil, nil, false
+		common.ParamOption.HotReset = common.HotResetTrain
+		convey.Convey("chip of FreeRestartNPU is reset when no pod uses it", func() {
+			env.hdm.handleFaultPolicy()
+			convey.So(env.resetTimes, convey.ShouldEqual, 0)
+			env.moveComplete = true
+			env.hdm.handleFaultPolicy()
+			env.waitFreeReset()
+			convey.So(env.resetTimes, convey.ShouldEqual, 1)
+		})
+		convey.Convey("chip being hot reset is not reset by other callers", func() {
+			env.moveComplete = true
+			env.hdm.resetDevs.Store(env.hdm.groupDevice[common.Ascend310P][0].LogicID, struct{}{})
+			env.hdm.handleFaultPolicy()
+			convey.So(env.hdm.hotReset(env.hdm.groupDevice[common.Ascend310P][0], nil), convey.ShouldNotBeNil)
+			convey.So(env.resetTimes, convey.ShouldEqual, 0)
+			env.hdm.resetDevs.Delete(env.hdm.groupDevice[common.Ascend310P][0].LogicID)
+		})
+		convey.Convey("chip of FreeRestartNPU is not reset when hot reset is closed or done by infer reset", func() {
+			env.moveComplete = true
+			common.ParamOption.HotReset = common.HotResetClose
+			env.hdm.handleFaultPolicy()
+			common.ParamOption.HotReset = common.HotResetInfer
+			env.hdm.handleFaultPolicy()
+			common.ParamOption.HotReset, common.ParamOption.HotResetDryRun = common.HotResetTrain, true
+			env.hdm.handleFaultPolicy()
+			common.ParamOption.HotResetDryRun = false
+			env.waitFreeReset()
+			convey.So(env.resetTimes, convey.ShouldEqual, 0)
+		})
+		convey.Convey("pod using chip of RestartBusiness is signaled once", func() {
+			env.hdm.handleFaultPolicy()
+			convey.So(env.annotations, convey.ShouldResemble, []string{`{"devices":["Ascend310P-1"],"raisedTime":1}`})
+			convey.So(env.podReasons, convey.ShouldResemble, []string{common.EventReasonNPURestartBusiness})
+			env.pods[0].Annotations[common.PodRestartBusinessKey] = env.annotations[0]
+			defer delete(env.pods[0].Annotations, common.PodRestartBusinessKey)
+			env.hdm.handleFaultPolicy()
+			convey.So(len(env.annotations), convey.ShouldEqual, 1)
+		})
+	})
+}
+
+// TestResetFreeDeviceOnRing for test the chips on the ring of FreeRestartNPU chip should be all free before reset
+func TestResetFreeDeviceOnRing(t *testing.T) {
+	option := common.ParamOption
+	defer func() {
+		common.ParamOption = option
+	}()
+	env, patches := newFaultPolicyEnv()
+	defer patches.Reset()
+	convey.Convey("test resetFreeDevice on ring", t, func() {
+		scenario, err := simulator.ParseScenario([]byte(faultPolicyRingScenario))
+		convey.So(err, convey.ShouldBeNil)
+		sim, err := simulator.NewSimulator(scenario)
+		convey.So(err, convey.ShouldBeNil)
+		common.ParamOption.RealCardType, common.ParamOption.HotReset = common.Ascend910, common.HotResetTrain
+		manager := device.NewHwAscend910Manager()
+		manager.SetDmgr(sim)
+		manager.SetKubeClient(&kubeclient.ClientK8s{})
+		var devices []*common.NpuDevice
+		for logicID := int32(0); logicID < common.Ascend910RingsNum; logicID++ {
+			devices = append(devices, &common.NpuDevice{DevType: common.Ascend910, LogicID: logicID,
+				PhyID: logicID, DeviceName: fmt.Sprintf("%s-%d", common.Ascend910, logicID), Health: v1beta1.Healthy})
+		}
+		env.hdm = &HwDevManager{manager: manager, RunMode: common.Ascend910,
+			groupDevice: map[string][]*common.NpuDevice{common.Ascend910: devices},
+			ServerMap: map[string]InterfaceServer{common.Ascend910: NewPluginServer(common.Ascend910, devices,
+				nil, manager)}}
+		env.resetTimes, env.moveComplete = 0, true
+		env.usedDevices = map[string]bool{"Ascend910-2": true}
+		defer func() {
+			env.usedDevices = nil
+		}()
+		env.hdm.resetFreeDevice(common.Ascend910, devices[1], devices)
+		env.waitFreeReset()
+		convey.So(env.resetTimes, convey.ShouldEqual, 0)
+		env.usedDevices = nil
+		env.hdm.resetFreeDevice(common.Ascend910, devices[1], devices)
+		env.waitFreeReset()
+		convey.So(env.resetTimes, convey.ShouldEqual, 1)
+	})
+}
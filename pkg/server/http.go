@@ -0,0 +1,126 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+
+	"Ascend-device-plugin/pkg/common"
+	"Ascend-device-plugin/pkg/metrics"
+)
+
+// StartHTTPServer start the http server which serves the metrics and probes, it is stopped when ctx is done
+func (hdm *HwDevManager) StartHTTPServer(ctx context.Context, addr string) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		hwlog.RunLog.Errorf("listen http address %s failed, %v", addr, err)
+		return
+	}
+	serveHTTP(ctx, listener, hdm.newHTTPHandler())
+}
+
+// StartDebugServer start the debug http server which dumps the internal caches, it is stopped when ctx is done
+func (hdm *HwDevManager) StartDebugServer(ctx context.Context, addr string, enablePprof bool) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		hwlog.RunLog.Errorf("listen debug address %s failed, %v", addr, err)
+		return
+	}
+	serveHTTP(ctx, listener, hdm.newDebugHandler(enablePprof))
+}
+
+func serveHTTP(ctx context.Context, listener net.Listener, handler http.Handler) {
+	httpServer := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: common.HTTPReadHeaderTimeout * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), common.HTTPShutdownTimeout*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			hwlog.RunLog.Warnf("shutdown http server failed, %v", err)
+		}
+	}()
+	hwlog.RunLog.Infof("http server is listening on %s", listener.Addr())
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		hwlog.RunLog.Errorf("http server stopped, %v", err)
+	}
+}
+
+func (hdm *HwDevManager) newHTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(common.MetricsPath, metrics.Handler())
+	mux.HandleFunc(common.HealthzPath, newProbeHandler(hdm.checkLiveness))
+	mux.HandleFunc(common.ReadyzPath, newProbeHandler(hdm.checkReadiness))
+	return mux
+}
+
+func newProbeHandler(check func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := check(); err != nil {
+			hwlog.RunLog.Warnf("probe %s failed, %v", r.URL.Path, err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write([]byte("ok")); err != nil {
+			hwlog.RunLog.Debugf("write probe response failed, %v", err)
+		}
+	}
+}
+
+// checkLiveness check whether ListenDevice is stuck, it is alive before ListenDevice is started or while it is waiting
+// for the hot reset, see refreshListenTime
+func (hdm *HwDevManager) checkLiveness() error {
+	listenTime := atomic.LoadInt64(&hdm.listenTime)
+	if listenTime == 0 {
+		return nil
+	}
+	timeout := time.Duration(common.ListenDeviceStuckPeriods*common.ParamOption.ListAndWatchPeriod) * time.Second
+	if elapsed := time.Since(time.Unix(listenTime, 0)); elapsed > timeout {
+		return fmt.Errorf("listen device has no iteration for %v", elapsed.Truncate(time.Second))
+	}
+	return nil
+}
+
+// checkReadiness check whether all servers are registered to kubelet and send device info successfully
+func (hdm *HwDevManager) checkReadiness() error {
+	servers := hdm.getServers()
+	if len(servers) == 0 {
+		return fmt.Errorf("no device plugin server")
+	}
+	var notReady []string
+	for deviceType, serverInterface := range servers {
+		if !serverInterface.IsReady() {
+			notReady = append(notReady, deviceType)
+		}
+	}
+	if len(notReady) != 0 {
+		sort.Strings(notReady)
+		return fmt.Errorf("device plugin server %v is not ready", notReady)
+	}
+	return nil
+}
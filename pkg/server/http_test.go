@@ -0,0 +1,98 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+
+	"Ascend-device-plugin/pkg/common"
+	"Ascend-device-plugin/pkg/metrics"
+)
+
+// TestNewHTTPHandler for test newHTTPHandler
+func TestNewHTTPHandler(t *testing.T) {
+	convey.Convey("test newHTTPHandler", t, func() {
+		hdm := &HwDevManager{}
+		metrics.IncListAndWatchSendFailure(common.Ascend910)
+		recorder := httptest.NewRecorder()
+		hdm.newHTTPHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, common.MetricsPath, nil))
+		convey.So(recorder.Code, convey.ShouldEqual, http.StatusOK)
+		convey.So(strings.Contains(recorder.Body.String(),
+			`ascend_device_plugin_list_and_watch_send_failures_total{device_type="Ascend910"} 1`), convey.ShouldBeTrue)
+	})
+}
+
+func serveProbe(hdm *HwDevManager, path string) int {
+	recorder := httptest.NewRecorder()
+	hdm.newHTTPHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	return recorder.Code
+}
+
+// TestHealthz for test the liveness probe
+func TestHealthz(t *testing.T) {
+	convey.Convey("test healthz", t, func() {
+		common.ParamOption.ListAndWatchPeriod = 5
+		hdm := &HwDevManager{}
+		convey.Convey("listen device is not started", func() {
+			convey.So(serveProbe(hdm, common.HealthzPath), convey.ShouldEqual, http.StatusOK)
+		})
+		convey.Convey("listen device is running", func() {
+			hdm.setListenTime(time.Now())
+			convey.So(serveProbe(hdm, common.HealthzPath), convey.ShouldEqual, http.StatusOK)
+		})
+		convey.Convey("listen device is stuck", func() {
+			hdm.setListenTime(time.Now().Add(-time.Minute))
+			convey.So(serveProbe(hdm, common.HealthzPath), convey.ShouldEqual, http.StatusServiceUnavailable)
+		})
+		convey.Convey("listen device is waiting for hot reset", func() {
+			hdm.setListenTime(time.Now().Add(-time.Minute))
+			hdm.refreshListenTime()
+			convey.So(serveProbe(hdm, common.HealthzPath), convey.ShouldEqual, http.StatusOK)
+		})
+		convey.Convey("hot reset before listen device is started", func() {
+			hdm.refreshListenTime()
+			convey.So(hdm.listenTime, convey.ShouldBeZeroValue)
+		})
+	})
+}
+
+// TestReadyz for test the readiness probe
+func TestReadyz(t *testing.T) {
+	convey.Convey("test readyz", t, func() {
+		ps := NewPluginServer(common.Ascend910, nil, nil, nil)
+		hdm := &HwDevManager{ServerMap: map[string]InterfaceServer{common.Ascend910: ps}}
+		convey.Convey("server is not registered", func() {
+			convey.So(serveProbe(hdm, common.ReadyzPath), convey.ShouldEqual, http.StatusServiceUnavailable)
+		})
+		convey.Convey("last send to kubelet failed", func() {
+			ps.SetRestartFlag(false)
+			ps.isRunning.Store(true)
+			convey.So(serveProbe(hdm, common.ReadyzPath), convey.ShouldEqual, http.StatusServiceUnavailable)
+		})
+		convey.Convey("server is ready", func() {
+			ps.SetRestartFlag(false)
+			ps.isRunning.Store(true)
+			ps.lastSendStatus.Store(true)
+			convey.So(serveProbe(hdm, common.ReadyzPath), convey.ShouldEqual, http.StatusOK)
+		})
+	})
+}
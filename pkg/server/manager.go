@@ -23,6 +23,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -31,12 +33,14 @@ import (
 	"huawei.com/npu-exporter/v5/devmanager"
 	npuCommon "huawei.com/npu-exporter/v5/devmanager/common"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	"Ascend-device-plugin/pkg/common"
 	"Ascend-device-plugin/pkg/device"
 	"Ascend-device-plugin/pkg/kubeclient"
+	"Ascend-device-plugin/pkg/metrics"
 )
 
 var lastStatus = common.NewAtomicBool(false)
@@ -49,6 +53,41 @@ type HwDevManager struct {
 	manager     device.DevManager
 	RunMode     string
 	WorkMode    string
+	defaultDevs []string
+	faultLevels map[string]string
+	// nodeConditionKey the last npu condition and taint published to node
+	nodeConditionKey string
+	// listenTime the unix time of the last iteration of ListenDevice, 0 means ListenDevice is not started
+	listenTime int64
+	// serverLock protect ServerMap, which is changed when device types are added or vanished at runtime
+	serverLock sync.RWMutex
+	// serverChange notify Serve to start the plugin servers added at runtime
+	serverChange chan struct{}
+	// retiringServers the vanished device types and the number of cycles since they vanished
+	retiringServers map[string]int
+	// changedServers the device types whose devices are added or removed, they are notified by notifyToK8s
+	changedServers sets.String
+	// managers the device managers of each chip family on node with mixed chip families, manager is the first
+	managers []device.DevManager
+	// devTypeManager the device manager which owns each device type on node with mixed chip families
+	devTypeManager map[string]device.DevManager
+	// resetDevs key: logic id of the chip being hot reset, it is claimed by hotReset and hotResetInBackground
+	resetDevs sync.Map
+	// podResource the pod resource of current updateDevice, it is read from kubelet at most once in a cycle
+	podResource cyclePodResource
+	// debugLock protect debugSnapshot
+	debugLock sync.RWMutex
+	// debugSnapshot the device info saved at the end of updateDevice, it is served by debug http server without the
+	// lock of device info
+	debugSnapshot deviceDebugSnapshot
+}
+
+// cyclePodResource the pod resource shared by the annotation, virtual device destroying and hot reset of a device
+// update cycle
+type cyclePodResource struct {
+	read      bool
+	podDevice map[string]PodDevice
+	err       error
 }
 
 // NewHwDevManager function is used to new a dev manager.
@@ -77,28 +116,21 @@ func NewHwDevManager(devM devmanager.DeviceInterface) *HwDevManager {
 	return &hdm
 }
 
+// setAscendManager create the device managers, on node with mixed chip families each chip family owns a device
+// manager, and the real card type is the first of them
 func (hdm *HwDevManager) setAscendManager(dmgr devmanager.DeviceInterface) error {
-	devType := dmgr.GetDevType()
+	if err := hdm.setFamilyManagers(dmgr); err != nil {
+		hwlog.RunLog.Errorf("found an unsupported device type, %v", err)
+		return err
+	}
+	devType := hdm.manager.GetDmgr().GetDevType()
 	if !common.ParamOption.PresetVDevice && devType != common.Ascend310P {
 		return fmt.Errorf("only 310p support to set presetVirtualDevice false")
 	}
-	switch devType {
-	case common.Ascend310, common.Ascend310B:
-		hdm.RunMode = common.Ascend310
-		hdm.manager = device.NewHwAscend310Manager()
-	case common.Ascend910, common.Ascend910B:
-		hdm.RunMode = common.Ascend910
-		hdm.manager = device.NewHwAscend910Manager()
+	if hdm.RunMode == common.Ascend910 {
 		hdm.WorkMode = dmgr.GetNpuWorkMode()
-	case common.Ascend310P:
-		hdm.RunMode = common.Ascend310P
-		hdm.manager = device.NewHwAscend310PManager()
-	default:
-		hwlog.RunLog.Error("found an unsupported device type")
-		return fmt.Errorf("an unsupported device type")
 	}
 	common.ParamOption.RealCardType = devType
-	hdm.manager.SetDmgr(dmgr)
 	productTypes, err := hdm.manager.GetDmgr().GetAllProductType()
 	if err != nil {
 		return err
@@ -130,14 +162,16 @@ func (hdm *HwDevManager) UpdateNodeLabel() error {
 		hwlog.RunLog.Errorf("init k8s client failed err: %v", err.Error())
 		return err
 	}
-	hdm.manager.SetKubeClient(kubeClient)
+	for _, manager := range hdm.getManagers() {
+		manager.SetKubeClient(kubeClient)
+	}
 	hdm.manager.GetKubeClient().InitPodInformer()
 	hwlog.RunLog.Info("init kube client success")
 
-	return hdm.updateNodeLabels(common.ParamOption.AiCoreCount)
+	return hdm.updateNodeLabels()
 }
 
-func (hdm *HwDevManager) updateNodeLabels(aiCoreCount int32) error {
+func (hdm *HwDevManager) updateNodeLabels() error {
 	oldNode, err := hdm.manager.GetKubeClient().GetNode()
 	if err != nil {
 		hwlog.RunLog.Errorf("failed to get node, err: %v", err)
@@ -148,13 +182,19 @@ func (hdm *HwDevManager) updateNodeLabels(aiCoreCount int32) error {
 		return fmt.Errorf("invalid node")
 	}
 	newLabelMap := make(map[string]string)
-	if _, ok := oldNode.Labels[common.ServerTypeLabelKey]; !ok {
-		newLabelMap[common.ServerTypeLabelKey] = common.ParamOption.RealCardType +
-			common.MiddelLine + strconv.Itoa(int(aiCoreCount))
+	serverType := common.GetServerTypeLabel(common.ParamOption.RealCardType)
+	if oldNode.Labels[common.ServerTypeLabelKey] != serverType {
+		newLabelMap[common.ServerTypeLabelKey] = serverType
 	}
 
-	if common.ParamOption.RealCardType == common.Ascend910B && hdm.manager.GetDeviceUsage() == common.Infer {
-		newLabelMap[common.AcceleratorTypeKey] = common.A300IA2Label
+	for _, manager := range hdm.getManagers() {
+		if manager.GetDmgr().GetDevType() == common.Ascend910B && manager.GetDeviceUsage() == common.Infer {
+			newLabelMap[common.AcceleratorTypeKey] = common.A300IA2Label
+		}
+	}
+	if topology := hdm.manager.GetChipTopology(); topology != nil &&
+		oldNode.Labels[common.HCCSRingSizeLabelKey] != strconv.Itoa(topology.RingSize()) {
+		newLabelMap[common.HCCSRingSizeLabelKey] = strconv.Itoa(topology.RingSize())
 	}
 
 	if len(newLabelMap) == 0 {
@@ -179,27 +219,56 @@ func (hdm *HwDevManager) updateNodeLabels(aiCoreCount int32) error {
 
 func (hdm *HwDevManager) setAllDeviceAndType() error {
 	var err error
-	if hdm.allInfo, err = hdm.manager.GetNPUs(); err != nil {
+	if hdm.allInfo, hdm.devTypeManager, err = hdm.getAllNPUs(); err != nil {
 		return err
 	}
 	if len(hdm.allInfo.AllDevTypes) == 0 {
 		return fmt.Errorf("no devices type found")
 	}
-	if err = hdm.manager.SetDeviceUsage(hdm.allInfo.AllDevs[0].LogicID); err != nil {
-		return err
+	if common.ParamOption.BuildScene != common.EdgeScene {
+		hdm.setAiCoreRange()
+	}
+	for _, manager := range hdm.getManagers() {
+		for _, dev := range hdm.allInfo.AllDevs {
+			if hdm.getManager(dev.DevType) != manager {
+				continue
+			}
+			if err = manager.SetDeviceUsage(dev.LogicID); err != nil {
+				return err
+			}
+			break
+		}
 	}
-
 	return nil
 }
 
+// setAiCoreRange set the min and max ai core count by the chips of the real card type, the chips of the other chip
+// families are not counted
+func (hdm *HwDevManager) setAiCoreRange() {
+	devices := make([]common.NpuDevice, 0, len(hdm.allInfo.AllDevs))
+	for _, dev := range hdm.allInfo.AllDevs {
+		if hdm.getManager(dev.DevType) == hdm.manager {
+			devices = append(devices, dev)
+		}
+	}
+	common.ParamOption.AiCoreCount, common.ParamOption.MaxAiCoreCount = common.GetAICoreRange(devices)
+	if common.ParamOption.MaxAiCoreCount > common.ParamOption.AiCoreCount {
+		hwlog.RunLog.Warnf("ai core count of chips is different, min %d, max %d", common.ParamOption.AiCoreCount,
+			common.ParamOption.MaxAiCoreCount)
+	}
+}
+
 func (hdm *HwDevManager) initPluginServer() error {
 	hdm.ServerMap = make(map[string]InterfaceServer, len(hdm.allInfo.AllDevTypes))
+	hdm.serverChange = make(chan struct{}, 1)
 	hdm.groupDevice = device.ClassifyDevices(hdm.allInfo.AllDevs, hdm.allInfo.AllDevTypes)
 	defaultDevices, err := common.GetDefaultDevices(common.ParamOption.GetFdFlag)
 	if err != nil {
 		hwlog.RunLog.Error("get default device error")
 		return err
 	}
+	hdm.defaultDevs = defaultDevices
+	hdm.updateCDISpec()
 	if !common.ParamOption.PresetVDevice {
 		hdm.ServerMap[common.AiCoreResourceName] = NewPluginServer(common.AiCoreResourceName,
 			hdm.allInfo.AICoreDevs, defaultDevices, hdm.manager)
@@ -207,7 +276,7 @@ func (hdm *HwDevManager) initPluginServer() error {
 	}
 	for _, deviceType := range hdm.allInfo.AllDevTypes {
 		hdm.ServerMap[deviceType] = NewPluginServer(deviceType, hdm.groupDevice[deviceType], defaultDevices,
-			hdm.manager)
+			hdm.getManager(deviceType))
 	}
 	return nil
 }
@@ -229,17 +298,39 @@ func (hdm *HwDevManager) updateDeviceHealth(curAllDevs []common.NpuDevice) {
 		if index, exist := lastAllDevs[dev.DeviceName]; exist && index < len(hdm.allInfo.AllDevs) {
 			curAllDevs[i].Health = hdm.allInfo.AllDevs[index].Health
 			curAllDevs[i].NetworkHealth = hdm.allInfo.AllDevs[index].NetworkHealth
+			curAllDevs[i].NetworkRealHealth = hdm.allInfo.AllDevs[index].NetworkRealHealth
 			curAllDevs[i].FaultCodes = hdm.allInfo.AllDevs[index].FaultCodes
 			curAllDevs[i].AlarmRaisedTime = hdm.allInfo.AllDevs[index].AlarmRaisedTime
 		}
 	}
 }
 
+// updateAllInfo get all devices again, so that the device types added or vanished at runtime, such as preset
+// vnpu of new template or replaced card, are reconciled to plugin servers
 func (hdm *HwDevManager) updateAllInfo() error {
-	if common.ParamOption.PresetVDevice {
-		return nil
+	if !common.ParamOption.PresetVDevice {
+		if err := hdm.destroyNotUsedVNPU(); err != nil {
+			return err
+		}
+		if err := hdm.manager.CheckDeviceTypeLabel(); err != nil {
+			hwlog.RunLog.Warnf("device type label may not correct, %v", err)
+		}
+	}
+	lastServerDevices := hdm.getServerDevices()
+	allInfo, devTypeManager, err := hdm.getAllNPUs()
+	if err != nil {
+		return err
 	}
-	element, exist := hdm.ServerMap[common.AiCoreResourceName]
+	hdm.devTypeManager = devTypeManager
+	hdm.updateDeviceHealth(allInfo.AllDevs)
+	hdm.groupDevice = device.ClassifyDevices(allInfo.AllDevs, allInfo.AllDevTypes)
+	hdm.allInfo = allInfo
+	hdm.reconcilePluginServers(lastServerDevices)
+	return nil
+}
+
+func (hdm *HwDevManager) destroyNotUsedVNPU() error {
+	element, exist := hdm.getServer(common.AiCoreResourceName)
 	if !exist {
 		return fmt.Errorf("not found %s plugin server", common.AiCoreResourceName)
 	}
@@ -247,21 +338,20 @@ func (hdm *HwDevManager) updateAllInfo() error {
 	if !ok {
 		return fmt.Errorf("serverMap convert %s failed", common.AiCoreResourceName)
 	}
-	err := pluginServer.DestroyNotUsedVNPU()
+	podDevice, err := hdm.getCyclePodResource()
 	if err != nil {
-		return err
-	}
-	if err := hdm.manager.CheckDeviceTypeLabel(); err != nil {
-		hwlog.RunLog.Warnf("device type label may not correct, %v", err)
+		return fmt.Errorf("get pod resource failed, %v", err)
 	}
-	allInfo, err := hdm.manager.GetNPUs()
-	if err != nil {
-		return err
+	return pluginServer.DestroyNotUsedVNPU(podDevice)
+}
+
+// getCyclePodResource get the pod resource of current updateDevice, kubelet is called at the first use in the cycle
+func (hdm *HwDevManager) getCyclePodResource() (map[string]PodDevice, error) {
+	if !hdm.podResource.read {
+		podDevice, err := sharedPodResource.GetPodResource()
+		hdm.podResource = cyclePodResource{read: true, podDevice: podDevice, err: err}
 	}
-	hdm.updateDeviceHealth(allInfo.AllDevs)
-	hdm.groupDevice = device.ClassifyDevices(allInfo.AllDevs, allInfo.AllDevTypes)
-	hdm.allInfo = allInfo
-	return nil
+	return hdm.podResource.podDevice, hdm.podResource.err
 }
 
 func (hdm *HwDevManager) separateNPUIDFromDeviceInfoIntoCache() {
@@ -286,9 +376,17 @@ func (hdm *HwDevManager) ListenDevice(ctx context.Context) {
 	// when device-plugin is started, the value of ManuallySeparateNPU in device info configmap needs to be written into
 	// cache to prevent manually separate npu IDs in cache from been lost
 	hdm.separateNPUIDFromDeviceInfoIntoCache()
+	// klt2RealDevMap is lost when device-plugin is restarted, recover it before serving allocate request
+	hdm.recoverAllocMapFromCheckpoint()
 	go hdm.pollFaultCodeCM(ctx)
 	go hdm.Serve(ctx)
 	initTime := time.Now()
+	hdm.setListenTime(initTime)
+	lastUpdate := initTime
+	// the periodic update is the safety net, device status is updated immediately when it is triggered by
+	// fault event, fault code configmap change or pod deletion
+	ticker := time.NewTicker(time.Duration(common.ParamOption.ListAndWatchPeriod) * time.Second)
+	defer ticker.Stop()
 	for {
 		select {
 		case _, ok := <-ctx.Done():
@@ -297,23 +395,100 @@ func (hdm *HwDevManager) ListenDevice(ctx context.Context) {
 			}
 			hwlog.RunLog.Info("listen device stop")
 			return
+		case reason := <-common.DeviceUpdateTrigger():
+			hwlog.RunLog.Debugf("device update is triggered by %s", reason)
+			debounceDeviceUpdate(lastUpdate)
+		case <-ticker.C:
+		}
+		hdm.updateDevice(&initTime)
+		lastUpdate = time.Now()
+		hdm.setListenTime(lastUpdate)
+	}
+}
+
+func (hdm *HwDevManager) setListenTime(listenTime time.Time) {
+	atomic.StoreInt64(&hdm.listenTime, listenTime.Unix())
+}
+
+// refreshListenTime is the heartbeat of ListenDevice while it is waiting for the hot reset done in updateDevice, which
+// takes up to one minute per device, so the reset is not considered as stuck. It must only be called by ListenDevice,
+// otherwise a stuck ListenDevice is masked
+func (hdm *HwDevManager) refreshListenTime() {
+	if atomic.LoadInt64(&hdm.listenTime) != 0 {
+		hdm.setListenTime(time.Now())
+	}
+}
+
+// debounceDeviceUpdate wait for a short time and drop the triggers in it, so that a burst of events only lead to
+// one device update. The wait lasts until DeviceUpdateMinInterval after the last update, so the events which keep
+// coming can not make the updates back to back
+func debounceDeviceUpdate(lastUpdate time.Time) {
+	waitTime := common.DeviceUpdateDebounceTime * time.Millisecond
+	if left := common.DeviceUpdateMinInterval*time.Millisecond - time.Since(lastUpdate); left > waitTime {
+		waitTime = left
+	}
+	time.Sleep(waitTime)
+	for {
+		select {
+		case reason := <-common.DeviceUpdateTrigger():
+			hwlog.RunLog.Debugf("device update is triggered by %s, merged", reason)
 		default:
-			time.Sleep(time.Duration(common.ParamOption.ListAndWatchPeriod) * time.Second)
-			common.LockAllDeviceInfo()
-			if err := hdm.updateAllInfo(); err != nil {
-				hwlog.RunLog.Error(err)
-				common.UnlockAllDeviceInfo()
-				continue
-			}
-			hdm.notifyToK8s(&initTime)
-			hdm.useVolcanoNotify()
-			hdm.chipHotReset()
-			common.DelOnceRecoverFault(hdm.groupDevice)
-			common.UnlockAllDeviceInfo()
+			return
 		}
 	}
 }
 
+func (hdm *HwDevManager) updateDevice(initTime *time.Time) {
+	common.LockAllDeviceInfo()
+	defer common.UnlockAllDeviceInfo()
+	common.SavePendingResetSeparations()
+	hdm.podResource = cyclePodResource{}
+	if err := hdm.updateAllInfo(); err != nil {
+		hwlog.RunLog.Error(err)
+		return
+	}
+	hdm.updateCDISpec()
+	hdm.notifyToK8s(initTime)
+	metrics.SetDeviceStatus(hdm.groupDevice)
+	hdm.updateNodeCondition()
+	hdm.useVolcanoNotify()
+	hdm.chipHotReset()
+	hdm.handleFaultPolicy()
+	common.DelOnceRecoverFault(hdm.groupDevice)
+	hdm.saveDebugSnapshot()
+}
+
+func (hdm *HwDevManager) updateCDISpec() {
+	if !common.ParamOption.UseCDI {
+		return
+	}
+	if err := refreshCDISpec(hdm.allInfo.AllDevs, hdm.getDynamicVNPUIDs(), hdm.defaultDevs); err != nil {
+		hwlog.RunLog.Errorf("refresh cdi spec failed, %v", err)
+	}
+}
+
+// getDynamicVNPUIDs get the id of vnpu created dynamically on all chips, nil means they can not be got
+func (hdm *HwDevManager) getDynamicVNPUIDs() sets.Int {
+	vNPUIDs := sets.NewInt()
+	if common.ParamOption.PresetVDevice {
+		return vNPUIDs
+	}
+	for _, dev := range hdm.allInfo.AllDevs {
+		if dev.DevType == common.AiCoreResourceName || common.IsVirtualDev(dev.DeviceName) {
+			continue
+		}
+		vDevInfo, err := hdm.getManager(dev.DevType).GetDmgr().GetVirtualDeviceInfo(dev.LogicID)
+		if err != nil {
+			hwlog.RunLog.Warnf("get virtual device of %s failed, keep vnpu in cdi spec, %v", dev.DeviceName, err)
+			return nil
+		}
+		for _, vDev := range vDevInfo.VDevInfo {
+			vNPUIDs.Insert(int(vDev.VDevID))
+		}
+	}
+	return vNPUIDs
+}
+
 func deepCopyGroupDevice(groupDevice map[string][]*common.NpuDevice) map[string][]*common.NpuDevice {
 	newGroupDevice := make(map[string][]*common.NpuDevice, len(groupDevice))
 	for deviceType, npuDevices := range groupDevice {
@@ -330,6 +505,7 @@ func deepCopyGroupDevice(groupDevice map[string][]*common.NpuDevice) map[string]
 				LogicID:         npuDevice.LogicID,
 				PhyID:           npuDevice.PhyID,
 				CardID:          npuDevice.CardID,
+				Topology:        npuDevice.Topology,
 			}
 			newNpuDevices = append(newNpuDevices, newNpuDevice)
 		}
@@ -339,7 +515,7 @@ func deepCopyGroupDevice(groupDevice map[string][]*common.NpuDevice) map[string]
 }
 
 func (hdm *HwDevManager) pluginNotify(classifyDev []*common.NpuDevice, devType string) {
-	serverMap, ok := hdm.ServerMap[devType]
+	serverMap, ok := hdm.getServer(devType)
 	if !ok {
 		hwlog.RunLog.Warnf("server map (%s) not exist", devType)
 		return
@@ -356,11 +532,25 @@ func (hdm *HwDevManager) pluginNotify(classifyDev []*common.NpuDevice, devType s
 
 func (hdm *HwDevManager) notifyToK8s(initTime *time.Time) {
 	oldGroupDevice := deepCopyGroupDevice(hdm.groupDevice)
-	hdm.manager.UpdateHealth(hdm.groupDevice, hdm.allInfo.AICoreDevs, hdm.RunMode)
+	for _, manager := range hdm.getManagers() {
+		manager.UpdateHealth(hdm.getGroupDeviceOf(manager, hdm.groupDevice), hdm.allInfo.AICoreDevs,
+			hdm.getRunMode(manager))
+	}
 
 	// If hot reset is used, the health of the device being reset is set here to healthy
-	hdm.graceTolerance(hdm.groupDevice)
-	isDevStateChange := hdm.manager.GetChange(hdm.groupDevice, oldGroupDevice)
+	hdm.graceTolerance(hdm.getGroupDeviceOf(hdm.manager, hdm.groupDevice))
+	isDevStateChange := make(map[string]bool, len(hdm.groupDevice))
+	for _, manager := range hdm.getManagers() {
+		for devType, isChanged := range manager.GetChange(hdm.getGroupDeviceOf(manager, hdm.groupDevice),
+			oldGroupDevice) {
+			isDevStateChange[devType] = isChanged
+		}
+	}
+	hdm.recordDeviceEvents(oldGroupDevice)
+	for devType := range hdm.changedServers {
+		isDevStateChange[devType] = true
+	}
+	hdm.changedServers = nil
 
 	for devType, isChanged := range isDevStateChange {
 		if !isChanged && (time.Now().Sub(*initTime) < time.Minute || lastStatus.Load()) {
@@ -376,57 +566,67 @@ func (hdm *HwDevManager) notifyToK8s(initTime *time.Time) {
 }
 
 func (hdm *HwDevManager) chipHotReset() {
-	if hdm.RunMode == common.Ascend910 {
+	if hdm.RunMode == common.Ascend910 && len(hdm.getManagers()) == 1 {
 		return
 	}
 	if common.ParamOption.HotReset != common.HotResetInfer {
 		hwlog.RunLog.Debugf("infer device hot reset mode error: %d", common.ParamOption.HotReset)
 		return
 	}
-	prClient := NewPodResource()
 	for devType, devices := range hdm.groupDevice {
-		if common.IsVirtualDev(devType) || len(devices) == 0 {
+		if common.IsVirtualDev(devType) || len(devices) == 0 || !hdm.isInferResetDevType(devType) {
 			continue
 		}
 		if common.IsContainAtlas300IDuo() {
-			hdm.resetDuoCard(devType, devices, prClient)
+			hdm.resetDuoCard(devType, devices)
 			continue
 		}
-		hdm.resetCommonInferCard(devType, devices, prClient)
+		hdm.resetCommonInferCard(devType, devices)
 	}
 }
 
-func (hdm *HwDevManager) resetCommonInferCard(devType string, devices []*common.NpuDevice, prClient *PodResource) {
+func (hdm *HwDevManager) resetCommonInferCard(devType string, devices []*common.NpuDevice) {
 	for _, device := range devices {
-		if device.Health == v1beta1.Healthy {
+		if device.Health == v1beta1.Healthy || common.CheckDeviceResetBudget(device.LogicID) != nil {
 			continue
 		}
-		if !hdm.isPodRemove(devType, device, prClient) {
+		podDevice, err := hdm.getCyclePodResource()
+		if err != nil {
+			hwlog.RunLog.Errorf("get pod resource failed, %v", err)
+			return
+		}
+		if !hdm.isPodRemove(devType, device, podDevice) {
 			continue
 		}
-		hdm.hotReset(device)
+		hdm.hotReset(device, hdm.refreshListenTime)
 	}
 }
 
-func (hdm *HwDevManager) resetDuoCard(devType string, devices []*common.NpuDevice, prClient *PodResource) {
+func (hdm *HwDevManager) resetDuoCard(devType string, devices []*common.NpuDevice) {
 	var cardResetOnce = make(map[int32][]*common.NpuDevice, 1)
 	for _, device := range devices {
 		cardResetOnce[device.CardID] = append(cardResetOnce[device.CardID], device)
 	}
 	for _, deviceChip := range cardResetOnce {
-		if hdm.isDuoCardChipHealthy(deviceChip) {
+		if hdm.isDuoCardChipHealthy(deviceChip) || common.CheckDeviceResetBudget(deviceChip[0].LogicID) != nil {
 			continue
 		}
-		if !hdm.isDuoRemove(devType, deviceChip, prClient) {
+		podDevice, err := hdm.getCyclePodResource()
+		if err != nil {
+			hwlog.RunLog.Errorf("get pod resource failed, %v", err)
+			return
+		}
+		if !hdm.isDuoRemove(devType, deviceChip, podDevice) {
 			continue
 		}
-		hdm.hotReset(deviceChip[0])
+		hdm.hotReset(deviceChip[0], hdm.refreshListenTime)
 	}
 }
 
-func (hdm *HwDevManager) isDuoRemove(devType string, deviceChip []*common.NpuDevice, prClient *PodResource) bool {
+func (hdm *HwDevManager) isDuoRemove(devType string, deviceChip []*common.NpuDevice,
+	podDevice map[string]PodDevice) bool {
 	for _, dev := range deviceChip {
-		if !hdm.isPodRemove(devType, dev, prClient) {
+		if !hdm.isPodRemove(devType, dev, podDevice) {
 			return false
 		}
 	}
@@ -458,7 +658,9 @@ func (hdm *HwDevManager) useVolcanoNotify() {
 	if err := hdm.updatePodAnnotation(); err != nil {
 		hwlog.RunLog.Error(err)
 	}
-	hdm.manager.DoWithVolcanoListAndWatch(hdm.groupDevice)
+	for _, manager := range hdm.getManagers() {
+		manager.DoWithVolcanoListAndWatch(hdm.getGroupDeviceOf(manager, hdm.groupDevice))
+	}
 }
 
 // SignCatch stop system sign catch
@@ -531,12 +733,14 @@ func (hdm *HwDevManager) handleEvents(ctx context.Context, restartSignal chan os
 			hwlog.RunLog.Infof("restart signal %s received, restart device plugin", sig)
 			hdm.setRestartForAll()
 		}
+	case <-hdm.serverChange:
+		hwlog.RunLog.Info("plugin server added, start it")
 	case event := <-watcher.FileWatcher.Events:
 		if event.Op&fsnotify.Remove == fsnotify.Remove {
 			_, deleteFile := filepath.Split(event.Name)
 			hdm.handleDeleteEvent(deleteFile)
 		}
-		if event.Name == v1beta1.KubeletSocket && event.Op&fsnotify.Create == fsnotify.Create {
+		if event.Name == kubeletSocket && event.Op&fsnotify.Create == fsnotify.Create {
 			hwlog.RunLog.Info("notify: kubelet.sock file created, restarting.")
 			hdm.setRestartForAll()
 		}
@@ -545,22 +749,22 @@ func (hdm *HwDevManager) handleEvents(ctx context.Context, restartSignal chan os
 }
 
 func (hdm *HwDevManager) stopAllSever() {
-	for deviceType := range hdm.ServerMap {
+	for deviceType, serverInterface := range hdm.getServers() {
 		hwlog.RunLog.Infof("stop server type %s", deviceType)
-		hdm.ServerMap[deviceType].Stop()
+		serverInterface.Stop()
 	}
 	hwlog.RunLog.Info("stop all server done")
 }
 
 func (hdm *HwDevManager) setRestartForAll() {
-	for deviceType := range hdm.ServerMap {
-		hdm.ServerMap[deviceType].SetRestartFlag(true)
+	for _, serverInterface := range hdm.getServers() {
+		serverInterface.SetRestartFlag(true)
 	}
 }
 
 func (hdm *HwDevManager) startAllServer(socketWatcher *common.FileWatch) bool {
 	success := true
-	for deviceType, serverInterface := range hdm.ServerMap {
+	for deviceType, serverInterface := range hdm.getServers() {
 		if !serverInterface.GetRestartFlag() {
 			continue
 		}
@@ -576,7 +780,7 @@ func (hdm *HwDevManager) startAllServer(socketWatcher *common.FileWatch) bool {
 }
 
 func (hdm *HwDevManager) handleDeleteEvent(deleteFile string) {
-	for deviceType := range hdm.ServerMap {
+	for deviceType := range hdm.getServers() {
 		candidateSocketFilename := fmt.Sprintf("%s.sock", deviceType)
 		if candidateSocketFilename == deleteFile {
 			hwlog.RunLog.Warnf("notify: sock file %s deleted, please check !", deleteFile)
@@ -646,7 +850,7 @@ func (hdm *HwDevManager) tryToClearResetInfoCM(pod v1.Pod) error {
 // updateSpecTypePodAnnotation will update annotation of pod and
 // try to clear reset info config map which may not be initialized after rescheduling
 func (hdm *HwDevManager) updateSpecTypePodAnnotation(deviceType, serverID string) error {
-	element, exist := hdm.ServerMap[deviceType]
+	element, exist := hdm.getServer(deviceType)
 	if !exist {
 		return fmt.Errorf("not found %s plugin server", deviceType)
 	}
@@ -655,7 +859,11 @@ func (hdm *HwDevManager) updateSpecTypePodAnnotation(deviceType, serverID string
 		return fmt.Errorf("serverMap convert %s failed", deviceType)
 	}
 	podList := hdm.manager.GetKubeClient().GetActivePodListCache()
-	podDeviceInfo, err := pluginServer.GetKltAndRealAllocateDev(podList)
+	podDevice, err := hdm.getCyclePodResource()
+	if err != nil {
+		return fmt.Errorf("get pod resource failed, %v", err)
+	}
+	podDeviceInfo, err := pluginServer.GetKltAndRealAllocateDev(podList, podDevice)
 	if err != nil {
 		return err
 	}
@@ -672,15 +880,15 @@ func (hdm *HwDevManager) updateSpecTypePodAnnotation(deviceType, serverID string
 			continue
 		}
 		hwlog.RunLog.Debugf("%s, %d, %v", deviceInfo.Pod.Name, len(deviceInfo.KltDevice), deviceInfo.RealDevice)
-		if err := hdm.manager.AddPodAnnotation(&deviceInfo.Pod, deviceInfo.KltDevice, deviceInfo.RealDevice,
-			deviceType, serverID); err != nil {
+		if err := hdm.getManager(deviceType).AddPodAnnotation(&deviceInfo.Pod, deviceInfo.KltDevice,
+			deviceInfo.RealDevice, deviceType, serverID); err != nil {
 			hwlog.RunLog.Errorf("update pod %s_%s annotation failed, %v", deviceInfo.Pod.Namespace,
 				deviceInfo.Pod.Name, err)
 		} else {
 			hwlog.RunLog.Infof("update pod %s_%s annotation success", deviceInfo.Pod.Namespace, deviceInfo.Pod.Name)
 		}
 
-		if common.ParamOption.HotReset != common.HotResetTrain {
+		if common.ParamOption.HotReset != common.HotResetTrain || common.ParamOption.HotResetDryRun {
 			continue
 		}
 
@@ -692,9 +900,53 @@ func (hdm *HwDevManager) updateSpecTypePodAnnotation(deviceType, serverID string
 	return nil
 }
 
-func (hdm *HwDevManager) hotReset(device *common.NpuDevice) {
+// hotReset reset the chip and wait for it to boot, the chip is escalated to ManuallySeparateNPU when the hot reset
+// keeps failing, see RecordDeviceResetResult
+// hotReset hot reset the chip and wait for it to boot up. The chip is claimed during the reset, so it is never reset
+// by two callers at the same time. heartbeat is called in each poll of the boot status, it may be nil
+func (hdm *HwDevManager) hotReset(device *common.NpuDevice, heartbeat func()) error {
+	if _, loaded := hdm.resetDevs.LoadOrStore(device.LogicID, struct{}{}); loaded {
+		return fmt.Errorf("device %s is being hot reset", device.DeviceName)
+	}
+	defer hdm.resetDevs.Delete(device.LogicID)
+	return hdm.execHotReset(device, heartbeat)
+}
+
+// hotResetInBackground claim the chip and hot reset it in background, false means the chip is being hot reset
+func (hdm *HwDevManager) hotResetInBackground(device *common.NpuDevice) bool {
+	if _, loaded := hdm.resetDevs.LoadOrStore(device.LogicID, struct{}{}); loaded {
+		return false
+	}
+	go func() {
+		defer hdm.resetDevs.Delete(device.LogicID)
+		if err := hdm.execHotReset(device, nil); err != nil {
+			hwlog.RunLog.Warnf("hot reset device %s failed, %v", device.DeviceName, err)
+		}
+	}()
+	return true
+}
+
+// isDeviceInHotReset whether the chip is being hot reset
+func (hdm *HwDevManager) isDeviceInHotReset(logicID int32) bool {
+	_, ok := hdm.resetDevs.Load(logicID)
+	return ok
+}
+
+// execHotReset is only called by hotReset and hotResetInBackground, which claim the chip
+func (hdm *HwDevManager) execHotReset(device *common.NpuDevice, heartbeat func()) error {
+	if err := common.CheckDeviceResetBudget(device.LogicID); err != nil {
+		hwlog.RunLog.Debugf("skip hot reset of device %s, %v", device.DeviceName, err)
+		return err
+	}
 	var isResetExec = false
+	hdm.manager.GetKubeClient().RecordNodeEvent(v1.EventTypeNormal, common.EventReasonNPUResetStarted,
+		"start hot reset of device %s", device.DeviceName)
+	metrics.IncHotResetAttempt()
+	startTime := time.Now()
 	if err := wait.PollImmediate(time.Second, time.Minute, func() (bool, error) {
+		if heartbeat != nil {
+			heartbeat()
+		}
 		if err := hdm.execResetChip(device.LogicID, &isResetExec); err != nil {
 			hwlog.RunLog.Errorf("get device boot status failed, err: %v", err)
 			return false, err
@@ -712,14 +964,27 @@ func (hdm *HwDevManager) hotReset(device *common.NpuDevice) {
 		return true, nil
 	}); err != nil {
 		hwlog.RunLog.Warnf("hot reset failed, timeout or err: %v", err)
-		return
+		metrics.ObserveHotReset(startTime, err)
+		hdm.manager.GetKubeClient().RecordNodeEvent(v1.EventTypeWarning, common.EventReasonNPUResetFailed,
+			"hot reset of device %s failed, %v", device.DeviceName, err)
+		if common.RecordDeviceResetResult(device.LogicID, err) {
+			hdm.manager.GetKubeClient().RecordNodeEvent(v1.EventTypeWarning,
+				common.EventReasonNPUManuallySeparated, "device %s is separated as hot reset keeps failing",
+				device.DeviceName)
+		}
+		return err
 	}
+	common.RecordDeviceResetResult(device.LogicID, nil)
 	hwlog.RunLog.Info("hot reset success")
+	metrics.ObserveHotReset(startTime, nil)
+	hdm.manager.GetKubeClient().RecordNodeEvent(v1.EventTypeNormal, common.EventReasonNPUResetSucceeded,
+		"hot reset of device %s succeeded", device.DeviceName)
+	return nil
 }
 
-func (hdm *HwDevManager) isPodRemove(devType string, device *common.NpuDevice, prClient *PodResource) bool {
+func (hdm *HwDevManager) isPodRemove(devType string, device *common.NpuDevice, podDevice map[string]PodDevice) bool {
 	podList := hdm.manager.GetKubeClient().GetAllPodListCache()
-	element, exist := hdm.ServerMap[devType]
+	element, exist := hdm.getServer(devType)
 	if !exist {
 		hwlog.RunLog.Errorf("not found %s plugin server", devType)
 		return false
@@ -729,7 +994,7 @@ func (hdm *HwDevManager) isPodRemove(devType string, device *common.NpuDevice, p
 		hwlog.RunLog.Errorf("serverMap convert %s failed", devType)
 		return false
 	}
-	if !prClient.IsPodMoveComplete(device.DeviceName, podList, pluginServer) {
+	if !sharedPodResource.IsPodMoveComplete(device.DeviceName, podList, pluginServer, podDevice) {
 		hwlog.RunLog.Warn("service pod has not been migrated or destroyed, wait for scanning again.")
 		return false
 	}
@@ -791,7 +1056,7 @@ func (hdm *HwDevManager) graceTolerance(groupDevice map[string][]*common.NpuDevi
 		hwlog.RunLog.Debugf("grace tolerance only support training chip")
 		return
 	}
-	if common.ParamOption.HotReset != common.HotResetTrain {
+	if common.ParamOption.HotReset != common.HotResetTrain && !common.ParamOption.HotResetDryRun {
 		hwlog.RunLog.Debugf("train device hot reset mode error: %d", common.ParamOption.HotReset)
 		return
 	}
@@ -841,6 +1106,7 @@ func (hdm *HwDevManager) pollFaultCodeCM(ctx context.Context) {
 				loadFaultCode(configMap)
 				loadFaultCustomization(configMap)
 				hwlog.RunLog.Infof("handling '%s' configmap change complete", common.FaultCodeCMName)
+				common.TriggerDeviceUpdate("fault code configmap changed")
 			}
 			time.Sleep(time.Duration(interval) * time.Second)
 		}
@@ -18,6 +18,7 @@ package server
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
@@ -27,6 +28,7 @@ import (
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	"Ascend-device-plugin/pkg/common"
 	"Ascend-device-plugin/pkg/device"
@@ -130,7 +132,7 @@ func TestUpdatePodAnnotation(t *testing.T) {
 					return node, nil
 				})
 			mockPodDeviceInfo := gomonkey.ApplyMethod(reflect.TypeOf(new(PluginServer)), "GetKltAndRealAllocateDev",
-				func(_ *PluginServer, _ []v1.Pod) ([]PodDeviceInfo, error) {
+				func(_ *PluginServer, _ []v1.Pod, _ map[string]PodDevice) ([]PodDeviceInfo, error) {
 					return podDeviceInfo, nil
 				})
 			mockManager := gomonkey.ApplyMethod(reflect.TypeOf(new(device.AscendTools)), "AddPodAnnotation",
@@ -151,6 +153,7 @@ func TestUpdatePodAnnotation(t *testing.T) {
 			defer mockPodDeviceInfo.Reset()
 			defer mockClearCM.Reset()
 			hdm := NewHwDevManager(&devmanager.DeviceManagerMock{})
+			hdm.podResource = cyclePodResource{read: true, podDevice: map[string]PodDevice{}}
 			err := hdm.updatePodAnnotation()
 			convey.So(err, convey.ShouldBeNil)
 		})
@@ -177,7 +180,7 @@ func TestUpdateDevice(t *testing.T) {
 					return nil
 				})
 			mockDestroy := gomonkey.ApplyMethod(reflect.TypeOf(new(PluginServer)), "DestroyNotUsedVNPU",
-				func(_ *PluginServer) error {
+				func(_ *PluginServer, _ map[string]PodDevice) error {
 					return nil
 				})
 			defer mockDestroy.Reset()
@@ -185,6 +188,7 @@ func TestUpdateDevice(t *testing.T) {
 			common.ParamOption.PresetVDevice = true
 			hdm := NewHwDevManager(&devmanager.DeviceManagerMock{})
 			hdm.ServerMap[common.AiCoreResourceName] = NewPluginServer(common.Ascend310P, nil, nil, nil)
+			hdm.podResource = cyclePodResource{read: true, podDevice: map[string]PodDevice{}}
 			err := hdm.updateAllInfo()
 			convey.So(err, convey.ShouldBeNil)
 		})
@@ -280,3 +284,136 @@ func getAddresses() []v1.NodeAddress {
 		},
 	}
 }
+
+// TestRecordDeviceEvents for test recordDeviceEvents
+func TestRecordDeviceEvents(t *testing.T) {
+	var nodeReasons, podReasons []string
+	mockNodeEvent := gomonkey.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "RecordNodeEvent",
+		func(_ *kubeclient.ClientK8s, _, reason, _ string, _ ...interface{}) {
+			nodeReasons = append(nodeReasons, reason)
+		})
+	defer mockNodeEvent.Reset()
+	mockPodEvent := gomonkey.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "RecordPodEvent",
+		func(_ *kubeclient.ClientK8s, _ *v1.Pod, _, reason, _ string, _ ...interface{}) {
+			podReasons = append(podReasons, reason)
+		})
+	defer mockPodEvent.Reset()
+	mockPodList := gomonkey.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "GetActivePodListCache",
+		func(_ *kubeclient.ClientK8s) []v1.Pod {
+			return []v1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Annotations: map[string]string{
+				common.ResourceNamePrefix + common.PodRealAlloc: "Ascend910-0,Ascend910-1"}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Namespace: "default"}}}
+		})
+	defer mockPodList.Reset()
+	mockPodResource := gomonkey.ApplyMethod(reflect.TypeOf(new(PodResource)), "GetPodResource",
+		func(_ *PodResource) (map[string]PodDevice, error) {
+			return map[string]PodDevice{"default" + common.UnderLine + "pod2": {
+				ResourceName: common.ResourceNamePrefix + common.Ascend910, DeviceIds: []string{"Ascend910-0"}}}, nil
+		})
+	defer mockPodResource.Reset()
+	option := common.ParamOption
+	defer func() {
+		common.ParamOption = option
+	}()
+	convey.Convey("test recordDeviceEvents", t, func() {
+		common.ParamOption.UseVolcanoType = false
+		manager := device.NewHwAscend910Manager()
+		manager.SetKubeClient(&kubeclient.ClientK8s{})
+		hdm := &HwDevManager{manager: manager, ServerMap: map[string]InterfaceServer{
+			common.Ascend910: NewPluginServer(common.Ascend910, nil, nil, nil)}}
+		oldGroupDevice := map[string][]*common.NpuDevice{common.Ascend910: {
+			{DeviceName: "Ascend910-1", Health: v1beta1.Healthy},
+			{DeviceName: "Ascend910-0", Health: v1beta1.Healthy}}}
+		hdm.groupDevice = map[string][]*common.NpuDevice{common.Ascend910: {
+			{DeviceName: "Ascend910-0", Health: v1beta1.Unhealthy, FaultCodes: []int64{common.LinkDownFaultCode}},
+			{DeviceName: "Ascend910-1", Health: v1beta1.Healthy}}}
+		hdm.recordDeviceEvents(oldGroupDevice)
+		convey.So(nodeReasons, convey.ShouldResemble, []string{common.EventReasonNPUFaultLevelChanged,
+			common.EventReasonNPUUnhealthy})
+		convey.So(podReasons, convey.ShouldResemble, []string{common.EventReasonNPUIsolated,
+			common.EventReasonNPUIsolated})
+		nodeReasons, podReasons = nil, nil
+		oldGroupDevice = hdm.groupDevice
+		hdm.groupDevice = map[string][]*common.NpuDevice{common.Ascend910: {
+			{DeviceName: "Ascend910-0", Health: v1beta1.Healthy},
+			{DeviceName: "Ascend910-1", Health: v1beta1.Healthy}}}
+		hdm.recordDeviceEvents(oldGroupDevice)
+		convey.So(nodeReasons, convey.ShouldResemble, []string{common.EventReasonNPUFaultLevelChanged,
+			common.EventReasonNPURecovered})
+		convey.So(len(podReasons), convey.ShouldEqual, 0)
+	})
+}
+
+// TestDebounceDeviceUpdate for test debounceDeviceUpdate
+func TestDebounceDeviceUpdate(t *testing.T) {
+	convey.Convey("test debounceDeviceUpdate", t, func() {
+		convey.Convey("triggers in merge window are dropped", func() {
+			common.TriggerDeviceUpdate("pod deleted")
+			startTime := time.Now()
+			debounceDeviceUpdate(startTime.Add(-time.Hour))
+			convey.So(len(common.DeviceUpdateTrigger()), convey.ShouldEqual, 0)
+			convey.So(time.Since(startTime), convey.ShouldBeLessThan,
+				common.DeviceUpdateMinInterval*time.Millisecond)
+		})
+		convey.Convey("wait until min interval after last update", func() {
+			startTime := time.Now()
+			debounceDeviceUpdate(startTime)
+			convey.So(time.Since(startTime), convey.ShouldBeGreaterThanOrEqualTo,
+				common.DeviceUpdateMinInterval*time.Millisecond)
+		})
+	})
+}
+
+// TestUpdateNodeLabels for test updateNodeLabels
+func TestUpdateNodeLabels(t *testing.T) {
+	oldParam := common.ParamOption
+	defer func() { common.ParamOption = oldParam }()
+	common.ParamOption.RealCardType = common.Ascend910
+	mockGetChipAiCoreCount := gomonkey.ApplyMethod(reflect.TypeOf(new(device.AscendTools)), "GetChipAiCoreCount",
+		func(_ *device.AscendTools) (int32, error) {
+			return 8255, nil
+		})
+	mockUpdateNodeLabel := gomonkey.ApplyMethod(reflect.TypeOf(new(HwDevManager)), "UpdateNodeLabel",
+		func(_ *HwDevManager) error {
+			return nil
+		})
+	hdm := NewHwDevManager(&devmanager.DeviceManagerMock{})
+	mockUpdateNodeLabel.Reset()
+	mockGetChipAiCoreCount.Reset()
+	serverType := common.GetServerTypeLabel(common.Ascend910)
+	convey.Convey("test updateNodeLabels", t, func() {
+		var patchedNode *v1.Node
+		mockPatch := gomonkey.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "PatchNodeState",
+			func(_ *kubeclient.ClientK8s, _, newNode *v1.Node) (*v1.Node, []byte, error) {
+				patchedNode = newNode
+				return newNode, nil, nil
+			})
+		defer mockPatch.Reset()
+		convey.Convey("server type label differs, label is updated", func() {
+			node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+				common.ServerTypeLabelKey: common.Ascend910 + "-32"}}}
+			mockNode := gomonkey.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "GetNode",
+				func(_ *kubeclient.ClientK8s) (*v1.Node, error) {
+					return node, nil
+				})
+			defer mockNode.Reset()
+			convey.So(hdm.updateNodeLabels(), convey.ShouldBeNil)
+			convey.So(patchedNode, convey.ShouldNotBeNil)
+			convey.So(patchedNode.Labels[common.ServerTypeLabelKey], convey.ShouldEqual, serverType)
+		})
+		convey.Convey("server type label is same, node is not patched", func() {
+			node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+				common.ServerTypeLabelKey: serverType}}}
+			if topology := hdm.manager.GetChipTopology(); topology != nil {
+				node.Labels[common.HCCSRingSizeLabelKey] = strconv.Itoa(topology.RingSize())
+			}
+			mockNode := gomonkey.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "GetNode",
+				func(_ *kubeclient.ClientK8s) (*v1.Node, error) {
+					return node, nil
+				})
+			defer mockNode.Reset()
+			convey.So(hdm.updateNodeLabels(), convey.ShouldBeNil)
+			convey.So(patchedNode, convey.ShouldBeNil)
+		})
+	})
+}
@@ -19,6 +19,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -30,6 +31,7 @@ import (
 
 	"Ascend-device-plugin/pkg/common"
 	"Ascend-device-plugin/pkg/device"
+	"Ascend-device-plugin/pkg/metrics"
 )
 
 func (ps *PluginServer) stopListAndWatch() {
@@ -87,11 +89,11 @@ func (ps *PluginServer) getUnhealthyAICore() sets.String {
 		}
 		usedAICore.Insert(k)
 	}
-	if unhealthyPhyID.Len() > math.MaxInt/int(ps.manager.GetChipAICore()) {
-		hwlog.RunLog.Errorf("the num of unhealthy device %d is invalid", unhealthyPhyID.Len())
-		return unhealthyAICore
+	unhealthyChipAICoreNum := 0
+	for phyID := range unhealthyPhyID {
+		unhealthyChipAICoreNum += int(ps.getChipAICoreWithLock(int32(phyID)))
 	}
-	leftUnhealthyAICoreNum := unhealthyPhyID.Len()*int(ps.manager.GetChipAICore()) - unhealthyAICore.Len()
+	leftUnhealthyAICoreNum := unhealthyChipAICoreNum - unhealthyAICore.Len()
 	if leftUnhealthyAICoreNum < 0 {
 		hwlog.RunLog.Errorf("num of left unhealthy ai core %d is less than 0", leftUnhealthyAICoreNum)
 		return unhealthyAICore
@@ -113,9 +115,13 @@ func (ps *PluginServer) getUnhealthyAICore() sets.String {
 // GetRealUsedAICore get real used aicore from pod
 func (ps *PluginServer) GetRealUsedAICore() (map[string]string, error) {
 	podList := ps.manager.GetKubeClient().GetActivePodListCache()
-	podDeviceInfo, err := ps.GetKltAndRealAllocateDev(podList)
+	podDevice, err := sharedPodResource.GetPodResourceCache()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get klt and real allocate device, get pod resource failed, %w", err)
+	}
+	podDeviceInfo, err := ps.GetKltAndRealAllocateDev(podList, podDevice)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get klt and real allocate device, %w", err)
+		return nil, err
 	}
 	usedAICore := make(map[string]string, len(podDeviceInfo))
 	for _, deviceInfo := range podDeviceInfo {
@@ -182,7 +188,8 @@ func (ps *PluginServer) responseToKubelet() *v1beta1.ListAndWatchResponse {
 				device.Health = v1beta1.Healthy
 			}
 			hwlog.RunLog.Infof("ListAndWatch resp devices: %s %s", device.DeviceName, device.Health)
-			resp.Devices = append(resp.Devices, &v1beta1.Device{ID: device.DeviceName, Health: device.Health})
+			resp.Devices = append(resp.Devices, &v1beta1.Device{ID: device.DeviceName, Health: device.Health,
+				Topology: device.Topology})
 		}
 	} else if common.ParamOption.UseVolcanoType && !common.IsVirtualDev(ps.deviceType) {
 		vol2kltMap := ps.generateAllDeviceMap()
@@ -194,12 +201,14 @@ func (ps *PluginServer) responseToKubelet() *v1beta1.ListAndWatchResponse {
 			}
 			hwlog.RunLog.Infof("ListAndWatch resp devices: inner device: %s %s, real device: %s %s", d,
 				device.Health, device.DeviceName, device.Health)
-			resp.Devices = append(resp.Devices, &v1beta1.Device{ID: d, Health: device.Health})
+			resp.Devices = append(resp.Devices, &v1beta1.Device{ID: d, Health: device.Health,
+				Topology: device.Topology})
 		}
 	} else {
 		for _, device := range ps.cachedDevices {
 			hwlog.RunLog.Infof("ListAndWatch resp devices: %s %s", device.DeviceName, device.Health)
-			resp.Devices = append(resp.Devices, &v1beta1.Device{ID: device.DeviceName, Health: device.Health})
+			resp.Devices = append(resp.Devices, &v1beta1.Device{ID: device.DeviceName, Health: device.Health,
+				Topology: device.Topology})
 		}
 	}
 	ps.cachedLock.RUnlock()
@@ -209,11 +218,19 @@ func (ps *PluginServer) responseToKubelet() *v1beta1.ListAndWatchResponse {
 func (ps *PluginServer) deepCopyDevice(cachedDevices []*common.NpuDevice) {
 	ps.cachedLock.Lock()
 	ps.cachedDevices = ps.cachedDevices[:0]
+	ps.chipAICore = make(map[int32]int32, len(cachedDevices))
 	for _, dev := range cachedDevices {
+		if _, exist := ps.chipAICore[dev.PhyID]; !exist && dev.AICore > 0 {
+			ps.chipAICore[dev.PhyID] = dev.AICore
+		}
 		ps.cachedDevices = append(ps.cachedDevices, common.NpuDevice{
 			DeviceName: dev.DeviceName,
 			Health:     dev.Health,
 			PhyID:      dev.PhyID,
+			LogicID:    dev.LogicID,
+			AICore:     dev.AICore,
+			FaultCodes: append([]int64{}, dev.FaultCodes...),
+			Topology:   dev.Topology,
 		})
 	}
 	ps.cachedLock.Unlock()
@@ -228,9 +245,12 @@ func (ps *PluginServer) ListAndWatch(empty *v1beta1.Empty, stream v1beta1.Device
 				continue
 			}
 			lastStatus.Store(true)
+			ps.lastSendStatus.Store(true)
 			return
 		}
 		lastStatus.Store(false)
+		ps.lastSendStatus.Store(false)
+		metrics.IncListAndWatchSendFailure(ps.deviceType)
 		hwlog.RunLog.Errorf("the number of retries (%d) retries send failed.", common.RetryUpdateCount)
 	}
 	ps.isRunning.Store(true)
@@ -371,21 +391,47 @@ func (ps *PluginServer) updateDynamicAllocMap(realAlloc, kltAlloc []string) {
 		}
 		return
 	}
-	// for physical device, M ai core : N real device
+	// for physical device, M ai core : N real device, each real device owns the ai core count of its chip
 	// aicore-0,..., aicore-31 : Ascend910-0
 	// aicore-32,..., aicore-63 : Ascend910-1
-	chipAICore := ps.manager.GetChipAICore()
-	if int(chipAICore)*len(realAlloc) != len(kltAlloc) {
+	chipAICores := make([]int, 0, len(realAlloc))
+	allocAICoreNum := 0
+	for _, realDev := range realAlloc {
+		phyID, _, err := common.GetDeviceID(realDev, "")
+		if err != nil {
+			hwlog.RunLog.Warn(err)
+			return
+		}
+		chipAICores = append(chipAICores, int(ps.getChipAICore(int32(phyID))))
+		allocAICoreNum += chipAICores[len(chipAICores)-1]
+	}
+	if allocAICoreNum != len(kltAlloc) {
 		hwlog.RunLog.Warnf("klt allocate core not equal real allocate %v", realAlloc)
 		return
 	}
-	realIdx := 0
-	for kltIdx, id := range kltAlloc {
-		ps.klt2RealDevMap[id] = realAlloc[realIdx]
-		if ((kltIdx + 1) % int(chipAICore)) == 0 {
-			realIdx++
+	kltIdx := 0
+	for realIdx, chipAICore := range chipAICores {
+		for _, id := range kltAlloc[kltIdx : kltIdx+chipAICore] {
+			ps.klt2RealDevMap[id] = realAlloc[realIdx]
 		}
+		kltIdx += chipAICore
+	}
+}
+
+// getChipAICore get the ai core count of the chip by the cached devices, the ai core count of node is used when the
+// chip is not found
+func (ps *PluginServer) getChipAICore(phyID int32) int32 {
+	ps.cachedLock.RLock()
+	defer ps.cachedLock.RUnlock()
+	return ps.getChipAICoreWithLock(phyID)
+}
+
+// getChipAICoreWithLock is getChipAICore for the caller which already holds cachedLock
+func (ps *PluginServer) getChipAICoreWithLock(phyID int32) int32 {
+	if aiCore, exist := ps.chipAICore[phyID]; exist {
+		return aiCore
 	}
+	return ps.manager.GetChipAICore()
 }
 
 func (ps *PluginServer) updatePresetAllocMap(realAlloc, kltAlloc []string) {
@@ -472,16 +518,14 @@ func (ps *PluginServer) GetRealAllocateDevicesFromEnv(pod v1.Pod) []string {
 	return nil
 }
 
-// GetKltAndRealAllocateDev get kubelet and real allocate device of pod
-func (ps *PluginServer) GetKltAndRealAllocateDev(podList []v1.Pod) ([]PodDeviceInfo, error) {
+// GetKltAndRealAllocateDev get kubelet and real allocate device of pod by the pod resource read by caller
+func (ps *PluginServer) GetKltAndRealAllocateDev(podList []v1.Pod, podDevice map[string]PodDevice) ([]PodDeviceInfo,
+	error) {
 	if ps == nil {
 		return nil, fmt.Errorf("invalid interface receiver")
 	}
-
-	prClient := NewPodResource()
-	podDevice, err := prClient.GetPodResource()
-	if err != nil {
-		return nil, fmt.Errorf("get pod resource failed, %v", err)
+	if podDevice == nil {
+		return nil, fmt.Errorf("pod resource is not got")
 	}
 	var podDeviceInfo []PodDeviceInfo
 	for _, pod := range podList {
@@ -527,14 +571,15 @@ func (ps *PluginServer) GetKltAndRealAllocateDev(podList []v1.Pod) ([]PodDeviceI
 	return podDeviceInfo, nil
 }
 
-// DestroyNotUsedVNPU destroy not used virtual device
-func (ps *PluginServer) DestroyNotUsedVNPU() error {
+// DestroyNotUsedVNPU destroy virtual device not used by the pods of podDevice, which is the pod resource read by
+// caller
+func (ps *PluginServer) DestroyNotUsedVNPU(podDevice map[string]PodDevice) error {
 	allDevInfo, err := ps.manager.GetNPUs()
 	if err != nil {
 		return err
 	}
 	podList := ps.manager.GetKubeClient().GetAllPodListCache()
-	podDeviceInfo, err := ps.GetKltAndRealAllocateDev(podList)
+	podDeviceInfo, err := ps.GetKltAndRealAllocateDev(podList, podDevice)
 	if err != nil {
 		return err
 	}
@@ -573,7 +618,8 @@ func (ps *PluginServer) removeVGroup(podDeviceInfo []PodDeviceInfo) sets.String
 	return noVGroupDevice
 }
 
-func checkAnnotationAllocateValid(requestDevices []string, deviceType string, pod *v1.Pod, chipAICore int32) bool {
+func checkAnnotationAllocateValid(requestDevices []string, deviceType string, pod *v1.Pod,
+	getChipAICore func(int32) int32) bool {
 	if predicateTime, ok := pod.Annotations[common.PodPredicateTime]; ok {
 		if predicateTime == strconv.FormatUint(math.MaxUint64, common.BaseDec) {
 			hwlog.RunLog.Debugf("The pod has been mounted to a device, pod name: %s", pod.Name)
@@ -609,8 +655,16 @@ func checkAnnotationAllocateValid(requestDevices []string, deviceType string, po
 		return len(requestDevices) == aiCore
 	}
 	// for physical npu, huawei.com/npu-core:0,1,2,3
-	phyDevices := strings.Split(deviceInfos[0], common.CommaSepDev)
-	return len(requestDevices) == len(phyDevices)*int(chipAICore)
+	aiCoreNum := 0
+	for _, phyDevice := range strings.Split(deviceInfos[0], common.CommaSepDev) {
+		phyID, err := strconv.Atoi(phyDevice)
+		if err != nil {
+			hwlog.RunLog.Warnf("invalid physical id %s in annotation", phyDevice)
+			return false
+		}
+		aiCoreNum += int(getChipAICore(int32(phyID)))
+	}
+	return len(requestDevices) == aiCoreNum
 }
 
 // getAICoreFromPodAnnotation get ai core count from pod annotation
@@ -618,7 +672,12 @@ func checkAnnotationAllocateValid(requestDevices []string, deviceType string, po
 // huawei.com/npu-core:0,1,2,3
 // huawei.com/npu-core:0-vir02
 func (ps *PluginServer) getAICoreFromPodAnnotation(pod *v1.Pod, deviceType string) ([]string, error) {
-	if err := ps.DestroyNotUsedVNPU(); err != nil {
+	// allocation must see the latest pod resource, the one of device update cycle may be outdated
+	podDevice, err := sharedPodResource.GetPodResource()
+	if err != nil {
+		return nil, fmt.Errorf("get pod resource failed, %v", err)
+	}
+	if err = ps.DestroyNotUsedVNPU(podDevice); err != nil {
 		return nil, err
 	}
 	annotation, err := common.GetPodAnnotationByDeviceType(pod, deviceType)
@@ -676,7 +735,7 @@ func (ps *PluginServer) isValidPhyID(phyID string) bool {
 
 func (ps *PluginServer) doWithVolcanoSchedule(requestDevices []string) ([]string, error) {
 	conditionFunc := func(pod *v1.Pod) bool {
-		return checkAnnotationAllocateValid(requestDevices, ps.deviceType, pod, ps.manager.GetChipAICore())
+		return checkAnnotationAllocateValid(requestDevices, ps.deviceType, pod, ps.getChipAICore)
 	}
 	var filteredPods []v1.Pod
 	var allPods []v1.Pod
@@ -769,6 +828,15 @@ func getDeviceContainerPath(hostPath string) string {
 // Allocate is called by kubelet to mount device to k8s pod.
 func (ps *PluginServer) Allocate(ctx context.Context, requests *v1beta1.AllocateRequest) (*v1beta1.AllocateResponse,
 	error) {
+	startTime := time.Now()
+	// the cached pod resource does not contain the devices allocated now
+	defer sharedPodResource.InvalidateCache()
+	resps, err := ps.allocate(requests)
+	metrics.ObserveAllocate(ps.deviceType, startTime, err)
+	return resps, err
+}
+
+func (ps *PluginServer) allocate(requests *v1beta1.AllocateRequest) (*v1beta1.AllocateResponse, error) {
 	if err := ps.checkAllocateRequest(requests); err != nil {
 		hwlog.RunLog.Error(err)
 		return nil, err
@@ -796,7 +864,13 @@ func (ps *PluginServer) Allocate(ctx context.Context, requests *v1beta1.Allocate
 		}
 
 		resp := new(v1beta1.ContainerAllocateResponse)
-		if !common.ParamOption.UseAscendDocker {
+		if common.ParamOption.UseCDI {
+			if err = ps.setCDIDevices(resp, ascendVisibleDevices); err != nil {
+				hwlog.RunLog.Errorf("set cdi devices failed, %v", err)
+				return nil, err
+			}
+			hwlog.RunLog.Info("device-plugin will use cdi to mount")
+		} else if !common.ParamOption.UseAscendDocker {
 			hwlog.RunLog.Info("device-plugin will use origin mount way")
 			mountDefaultDevice(resp, ps.defaultDevs)
 			mountDevice(resp, ascendVisibleDevices, ps.ascendRuntimeOptions)
@@ -809,16 +883,184 @@ func (ps *PluginServer) Allocate(ctx context.Context, requests *v1beta1.Allocate
 	return resps, nil
 }
 
-// GetPreferredAllocation implement the kubelet device plugin interface
-func (ps *PluginServer) GetPreferredAllocation(context.Context, *v1beta1.PreferredAllocationRequest) (
+// GetPreferredAllocation implement the kubelet device plugin interface, prefer devices on the same hccs ring
+func (ps *PluginServer) GetPreferredAllocation(ctx context.Context, requests *v1beta1.PreferredAllocationRequest) (
 	*v1beta1.PreferredAllocationResponse, error) {
-	return nil, fmt.Errorf("not support")
+	if requests == nil {
+		return nil, fmt.Errorf("invalid requests")
+	}
+	if len(requests.ContainerRequests) > common.MaxContainerLimit {
+		return nil, fmt.Errorf("the number of container request %d exceeds the upper limit",
+			len(requests.ContainerRequests))
+	}
+	resps := new(v1beta1.PreferredAllocationResponse)
+	for _, rqt := range requests.ContainerRequests {
+		if len(rqt.AvailableDeviceIDs) > common.MaxDevicesNum*common.MinAICoreNum {
+			return nil, fmt.Errorf("the available devices can't bigger than %d",
+				common.MaxDevicesNum*common.MinAICoreNum)
+		}
+		preferDevices, err := ps.getPreferredDevices(rqt.AvailableDeviceIDs, rqt.MustIncludeDeviceIDs,
+			int(rqt.AllocationSize))
+		if err != nil {
+			hwlog.RunLog.Errorf("get preferred allocation failed, %v", err)
+			return nil, err
+		}
+		hwlog.RunLog.Infof("preferred allocation of %s is %v", ps.deviceType, preferDevices)
+		resps.ContainerResponses = append(resps.ContainerResponses,
+			&v1beta1.ContainerPreferredAllocationResponse{DeviceIDs: preferDevices})
+	}
+	return resps, nil
+}
+
+// getPreferredDevices choose devices by ring, must include devices first, then whole rings, then the ring fit best,
+// unhealthy devices and devices with PreSeparateNPU fault are chosen at last
+func (ps *PluginServer) getPreferredDevices(available, mustInclude []string, size int) ([]string, error) {
+	if size <= 0 || size > len(available) {
+		return nil, fmt.Errorf("allocation size %d is invalid, available device num is %d", size, len(available))
+	}
+	if len(mustInclude) > size {
+		return nil, fmt.Errorf("must include device num %d exceeds allocation size %d", len(mustInclude), size)
+	}
+	preferDevices := make([]string, 0, size)
+	chosen := sets.NewString()
+	for _, deviceName := range mustInclude {
+		if !chosen.Has(deviceName) {
+			preferDevices = append(preferDevices, deviceName)
+			chosen.Insert(deviceName)
+		}
+	}
+	topology := ps.getTopology()
+	cachedDevices := ps.getCachedDeviceMap()
+	rings, backupDevices := groupAvailableDevicesByRing(available, chosen, cachedDevices, topology)
+	touchedRings := sets.NewInt()
+	for _, deviceName := range preferDevices {
+		if dev, exist := cachedDevices[deviceName]; exist {
+			touchedRings.Insert(topology.GetRingIndex(dev.LogicID))
+		}
+	}
+	for _, ringIndex := range touchedRings.List() {
+		preferDevices = takeDevicesFromRing(rings, ringIndex, size-len(preferDevices), preferDevices)
+	}
+	for _, ringIndex := range getSortedRingIndex(rings) {
+		if len(rings[ringIndex]) == 0 || topology.RingSize() <= 0 {
+			continue
+		}
+		// the whole ring is all present chips on it, which is less than ring size on partially populated server
+		ringChipNum := len(topology.GetRing(rings[ringIndex][0].LogicID))
+		if len(rings[ringIndex]) == ringChipNum && size-len(preferDevices) >= ringChipNum {
+			preferDevices = takeDevicesFromRing(rings, ringIndex, ringChipNum, preferDevices)
+		}
+	}
+	for len(preferDevices) < size && len(rings) > 0 {
+		ringIndex := getBestFitRing(rings, size-len(preferDevices))
+		preferDevices = takeDevicesFromRing(rings, ringIndex, size-len(preferDevices), preferDevices)
+	}
+	for _, deviceName := range backupDevices {
+		if len(preferDevices) >= size {
+			break
+		}
+		preferDevices = append(preferDevices, deviceName)
+	}
+	return preferDevices, nil
+}
+
+// getTopology get the ring topology of chips, nil means the chips are not grouped by ring
+func (ps *PluginServer) getTopology() *device.ChipTopology {
+	if ps.deviceType != common.Ascend910 || ps.manager == nil {
+		return nil
+	}
+	return ps.manager.GetChipTopology()
+}
+
+func (ps *PluginServer) getCachedDeviceMap() map[string]common.NpuDevice {
+	ps.cachedLock.RLock()
+	defer ps.cachedLock.RUnlock()
+	cachedDevices := make(map[string]common.NpuDevice, len(ps.cachedDevices))
+	for _, dev := range ps.cachedDevices {
+		cachedDevices[dev.DeviceName] = dev
+	}
+	return cachedDevices
+}
+
+// groupAvailableDevicesByRing group the healthy devices without PreSeparateNPU fault by ring index, the others are
+// returned as backup devices
+func groupAvailableDevicesByRing(available []string, chosen sets.String, cachedDevices map[string]common.NpuDevice,
+	topology *device.ChipTopology) (map[int][]common.NpuDevice, []string) {
+	rings := make(map[int][]common.NpuDevice, common.MaxDevicesNum)
+	var backupDevices []string
+	for _, deviceName := range available {
+		if chosen.Has(deviceName) {
+			continue
+		}
+		chosen.Insert(deviceName)
+		dev, exist := cachedDevices[deviceName]
+		if !exist || dev.Health != v1beta1.Healthy ||
+			common.GetFaultType(dev.FaultCodes, dev.LogicID) == common.PreSeparateNPU {
+			backupDevices = append(backupDevices, deviceName)
+			continue
+		}
+		ringIndex := topology.GetRingIndex(dev.LogicID)
+		rings[ringIndex] = append(rings[ringIndex], dev)
+	}
+	for ringIndex := range rings {
+		sort.Slice(rings[ringIndex], func(i, j int) bool {
+			return rings[ringIndex][i].LogicID < rings[ringIndex][j].LogicID
+		})
+	}
+	return rings, backupDevices
+}
+
+func getSortedRingIndex(rings map[int][]common.NpuDevice) []int {
+	ringIndexes := make([]int, 0, len(rings))
+	for ringIndex := range rings {
+		ringIndexes = append(ringIndexes, ringIndex)
+	}
+	sort.Ints(ringIndexes)
+	return ringIndexes
+}
+
+// getBestFitRing return the ring which has the least devices but enough for need, otherwise the ring which has the
+// most devices
+func getBestFitRing(rings map[int][]common.NpuDevice, need int) int {
+	bestFit, most := -1, -1
+	for _, ringIndex := range getSortedRingIndex(rings) {
+		count := len(rings[ringIndex])
+		if count >= need && (bestFit == -1 || count < len(rings[bestFit])) {
+			bestFit = ringIndex
+		}
+		if most == -1 || count > len(rings[most]) {
+			most = ringIndex
+		}
+	}
+	if bestFit != -1 {
+		return bestFit
+	}
+	return most
+}
+
+func takeDevicesFromRing(rings map[int][]common.NpuDevice, ringIndex, need int, preferDevices []string) []string {
+	ring, exist := rings[ringIndex]
+	if !exist || need <= 0 {
+		return preferDevices
+	}
+	if need > len(ring) {
+		need = len(ring)
+	}
+	for _, dev := range ring[:need] {
+		preferDevices = append(preferDevices, dev.DeviceName)
+	}
+	if need == len(ring) {
+		delete(rings, ringIndex)
+	} else {
+		rings[ringIndex] = ring[need:]
+	}
+	return preferDevices
 }
 
 // GetDevicePluginOptions is Standard interface to kubelet.
 func (ps *PluginServer) GetDevicePluginOptions(ctx context.Context, e *v1beta1.Empty) (*v1beta1.DevicePluginOptions,
 	error) {
-	return &v1beta1.DevicePluginOptions{}, nil
+	return &v1beta1.DevicePluginOptions{GetPreferredAllocationAvailable: !common.ParamOption.UseVolcanoType}, nil
 }
 
 // PreStartContainer is Standard interface to kubelet with empty implement.
@@ -839,6 +1081,7 @@ func NewPluginServer(deviceType string, devices []*common.NpuDevice, defaultDevs
 		stop:           make(chan interface{}),
 		klt2RealDevMap: make(map[string]string, common.MaxDevicesNum),
 		isRunning:      common.NewAtomicBool(false),
+		lastSendStatus: common.NewAtomicBool(false),
 		manager:        manager,
 	}
 	ps.deepCopyDevice(devices)
@@ -19,6 +19,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
@@ -128,6 +129,49 @@ func TestUpdateAllocMap(t *testing.T) {
 	})
 }
 
+// TestUpdateDynamicAllocMap for test the updateDynamicAllocMap with chips of different ai core count
+func TestUpdateDynamicAllocMap(t *testing.T) {
+	option := common.ParamOption
+	defer func() {
+		common.ParamOption = option
+	}()
+	common.ParamOption.PresetVDevice = false
+	var aiCoreDevs []*common.NpuDevice
+	common.FakeAiCoreDevice(common.DavinCiDev{PhyID: 0, AICore: common.MinAICoreNum}, &aiCoreDevs)
+	common.FakeAiCoreDevice(common.DavinCiDev{LogicID: 1, PhyID: 1, AICore: common.MinAICoreNum / 2}, &aiCoreDevs)
+	ps := NewPluginServer(common.AiCoreResourceName, aiCoreDevs, nil, device.NewHwAscend310PManager())
+	kltAlloc := make([]string, 0, len(aiCoreDevs))
+	for _, dev := range aiCoreDevs {
+		kltAlloc = append(kltAlloc, dev.DeviceName)
+	}
+	convey.Convey("test updateDynamicAllocMap", t, func() {
+		convey.Convey("ai core of each chip is mapped to the chip", func() {
+			ps.updateDynamicAllocMap([]string{"Ascend310P-0", "Ascend310P-1"}, kltAlloc)
+			convey.So(len(ps.klt2RealDevMap), convey.ShouldEqual, len(kltAlloc))
+			convey.So(ps.klt2RealDevMap[kltAlloc[common.MinAICoreNum-1]], convey.ShouldEqual, "Ascend310P-0")
+			convey.So(ps.klt2RealDevMap[kltAlloc[common.MinAICoreNum]], convey.ShouldEqual, "Ascend310P-1")
+		})
+		convey.Convey("valid annotation requests the ai core of each chip", func() {
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				common.ResourceNamePrefix + common.AiCoreResourceName: "0,1"}}}
+			convey.So(checkAnnotationAllocateValid(kltAlloc, common.AiCoreResourceName, pod, ps.getChipAICore),
+				convey.ShouldBeTrue)
+			convey.So(checkAnnotationAllocateValid(kltAlloc[1:], common.AiCoreResourceName, pod,
+				ps.getChipAICore), convey.ShouldBeFalse)
+		})
+		convey.Convey("ai core of chip is read while cached devices are refreshed", func() {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				ps.deepCopyDevice(aiCoreDevs)
+			}()
+			convey.So(ps.getChipAICore(1), convey.ShouldEqual, common.MinAICoreNum/2)
+			<-done
+			convey.So(ps.getChipAICore(1), convey.ShouldEqual, common.MinAICoreNum/2)
+		})
+	})
+}
+
 // TestGenerateAllDeviceMap for test the generateAllDeviceMap
 func TestGenerateAllDeviceMap(t *testing.T) {
 	ps := NewPluginServer(common.Ascend910, devices, nil, nil)
@@ -411,7 +455,7 @@ func TestDestroyNotUsedVNPU(t *testing.T) {
 			return nil
 		})
 	mockAllocateDev := gomonkey.ApplyMethod(reflect.TypeOf(new(PluginServer)), "GetKltAndRealAllocateDev",
-		func(_ *PluginServer, _ []v1.Pod) ([]PodDeviceInfo, error) {
+		func(_ *PluginServer, _ []v1.Pod, _ map[string]PodDevice) ([]PodDeviceInfo, error) {
 			return []PodDeviceInfo{}, nil
 		})
 	mockPodList := gomonkey.ApplyMethod(reflect.TypeOf(new(kubeclient.ClientK8s)), "GetAllPodListCache",
@@ -424,7 +468,7 @@ func TestDestroyNotUsedVNPU(t *testing.T) {
 	defer mockGetNPUs.Reset()
 	convey.Convey("test DestroyNotUsedVNPU", t, func() {
 		convey.Convey("DestroyNotUsedVNPU success", func() {
-			err := ps.DestroyNotUsedVNPU()
+			err := ps.DestroyNotUsedVNPU(map[string]PodDevice{})
 			convey.So(err, convey.ShouldBeNil)
 		})
 	})
@@ -447,9 +491,14 @@ func TestDoWithVolcanoSchedule(t *testing.T) {
 			return nil
 		})
 	mockDestroy := gomonkey.ApplyMethod(reflect.TypeOf(new(PluginServer)), "DestroyNotUsedVNPU",
-		func(_ *PluginServer) error {
+		func(_ *PluginServer, _ map[string]PodDevice) error {
 			return nil
 		})
+	mockPodResource := gomonkey.ApplyMethod(reflect.TypeOf(new(PodResource)), "GetPodResource",
+		func(_ *PodResource) (map[string]PodDevice, error) {
+			return map[string]PodDevice{}, nil
+		})
+	defer mockPodResource.Reset()
 	mockCreate := gomonkey.ApplyMethod(reflect.TypeOf(new(device.AscendTools)),
 		"CreateVirtualDevice", func(_ *device.AscendTools, phyID int32, templateName string) (string, error) {
 			return "Ascend910-2c-100-0", nil
@@ -467,6 +516,66 @@ func TestDoWithVolcanoSchedule(t *testing.T) {
 	common.ParamOption.PresetVDevice = true
 }
 
+// TestGetPreferredAllocation for test the GetPreferredAllocation
+func TestGetPreferredAllocation(t *testing.T) {
+	var ringDevices []*common.NpuDevice
+	for logicID := int32(0); logicID < int32(len(devices)); logicID++ {
+		ringDevices = append(ringDevices, &common.NpuDevice{DevType: common.Ascend910, LogicID: logicID,
+			DeviceName: fmt.Sprintf("%s-%d", common.Ascend910, logicID), Health: v1beta1.Healthy})
+	}
+	ps := NewPluginServer(common.Ascend910, ringDevices, nil, device.NewHwAscend910Manager())
+	common.ParamOption.RealCardType = common.Ascend910
+	available := []string{"Ascend910-0", "Ascend910-1", "Ascend910-2", "Ascend910-4", "Ascend910-5",
+		"Ascend910-6", "Ascend910-7"}
+	getPreferred := func(size int32, mustInclude []string) ([]string, error) {
+		resp, err := ps.GetPreferredAllocation(context.Background(), &v1beta1.PreferredAllocationRequest{
+			ContainerRequests: []*v1beta1.ContainerPreferredAllocationRequest{{AvailableDeviceIDs: available,
+				MustIncludeDeviceIDs: mustInclude, AllocationSize: size}}})
+		if err != nil {
+			return nil, err
+		}
+		return resp.ContainerResponses[0].DeviceIDs, nil
+	}
+	convey.Convey("test GetPreferredAllocation", t, func() {
+		convey.Convey("invalid request", func() {
+			_, err := ps.GetPreferredAllocation(context.Background(), nil)
+			convey.So(err, convey.ShouldNotBeNil)
+			_, err = getPreferred(int32(len(available)+1), nil)
+			convey.So(err, convey.ShouldNotBeNil)
+			_, err = ps.GetPreferredAllocation(context.Background(), &v1beta1.PreferredAllocationRequest{
+				ContainerRequests: []*v1beta1.ContainerPreferredAllocationRequest{{AvailableDeviceIDs: make([]string,
+					common.MaxDevicesNum*common.MinAICoreNum+1)}}})
+			convey.So(err.Error(), convey.ShouldEndWith, strconv.Itoa(common.MaxDevicesNum*common.MinAICoreNum))
+		})
+		convey.Convey("prefer whole ring", func() {
+			preferDevices, err := getPreferred(common.Ascend910RingsNum, nil)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(preferDevices, convey.ShouldResemble,
+				[]string{"Ascend910-4", "Ascend910-5", "Ascend910-6", "Ascend910-7"})
+		})
+		convey.Convey("prefer the ring fit best", func() {
+			preferDevices, err := getPreferred(3, nil)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(preferDevices, convey.ShouldResemble, []string{"Ascend910-0", "Ascend910-1", "Ascend910-2"})
+		})
+		convey.Convey("must include device is honored and its ring is preferred", func() {
+			preferDevices, err := getPreferred(2, []string{"Ascend910-6"})
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(preferDevices, convey.ShouldResemble, []string{"Ascend910-6", "Ascend910-4"})
+		})
+		convey.Convey("unhealthy device is chosen at last", func() {
+			ringDevices[4].Health = v1beta1.Unhealthy
+			ps.deepCopyDevice(ringDevices)
+			preferDevices, err := getPreferred(common.Ascend910RingsNum, []string{"Ascend910-5"})
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(preferDevices, convey.ShouldResemble,
+				[]string{"Ascend910-5", "Ascend910-6", "Ascend910-7", "Ascend910-0"})
+			ringDevices[4].Health = v1beta1.Healthy
+			ps.deepCopyDevice(ringDevices)
+		})
+	})
+}
+
 func getMockPodList() []v1.Pod {
 	return []v1.Pod{
 		getMockPod(),
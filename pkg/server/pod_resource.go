@@ -18,10 +18,14 @@ package server
 import (
 	"context"
 	"fmt"
+	"os"
 	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"huawei.com/npu-exporter/v5/common-utils/hwlog"
 	"k8s.io/api/core/v1"
+	podResourcesV1 "k8s.io/kubelet/pkg/apis/podresources/v1"
 	"k8s.io/kubelet/pkg/apis/podresources/v1alpha1"
 	"k8s.io/kubernetes/pkg/kubelet/apis/podresources"
 
@@ -29,12 +33,20 @@ import (
 )
 
 const (
-	socketPath                 = "/var/lib/kubelet/pod-resources/kubelet.sock"
 	defaultPodResourcesMaxSize = 1024 * 1024 * 16
 	callTimeout                = 2 * time.Second
+	// podResourceCacheTTL is shorter than the min list and watch period. The cache is refreshed by the pod resource
+	// read once in each device update cycle, and it is only used by list and watch and device events
+	podResourceCacheTTL = 2 * time.Second
 )
 
-// start starts the gRPC server, registers the pod resource with the Kubelet
+// socketPath the pod resources socket of kubelet, replaced by the fake kubelet in end-to-end test
+var socketPath = "/var/lib/kubelet/pod-resources/kubelet.sock"
+
+// sharedPodResource is the long-lived pod resource client shared by all callers
+var sharedPodResource = NewPodResource()
+
+// start starts the gRPC client of the Kubelet pod resource service, v1 api is used first
 func (pr *PodResource) start() error {
 	pr.stop()
 	realKubeletSockPath, isOk := common.VerifyPathAndPermission(socketPath, 0)
@@ -42,16 +54,37 @@ func (pr *PodResource) start() error {
 		return fmt.Errorf("check kubelet socket file path failed")
 	}
 	var err error
-	if pr.client, pr.conn, err = podresources.GetV1alpha1Client("unix://"+realKubeletSockPath, callTimeout,
+	if pr.client, pr.conn, err = podresources.GetV1Client("unix://"+realKubeletSockPath, callTimeout,
 		defaultPodResourcesMaxSize); err != nil {
 		hwlog.RunLog.Errorf("get pod resource client failed, %v", err)
 		return err
 	}
+	if pr.conn != nil {
+		pr.alphaClient = v1alpha1.NewPodResourcesListerClient(pr.conn)
+	}
+	pr.useV1alpha1 = false
+	pr.sockInfo, err = os.Stat(realKubeletSockPath)
+	if err != nil {
+		hwlog.RunLog.Warnf("get kubelet socket file info failed, socket recreation can not be found, %v", err)
+	}
 	hwlog.RunLog.Debug("pod resource client init success.")
 	return nil
 }
 
-func (pr *PodResource) getContainerResource(containerResource *v1alpha1.ContainerResources) (string, []string, error) {
+// connect reuse the existing connection, reconnect when the kubelet socket is recreated
+func (pr *PodResource) connect() error {
+	if pr.conn != nil && pr.sockInfo != nil {
+		sockInfo, err := os.Stat(socketPath)
+		if err == nil && os.SameFile(pr.sockInfo, sockInfo) {
+			return nil
+		}
+		hwlog.RunLog.Info("kubelet socket file is recreated, reconnect pod resource client")
+	}
+	return pr.start()
+}
+
+func (pr *PodResource) getContainerResource(containerResource *podResourcesV1.ContainerResources) (string, []string,
+	error) {
 	if containerResource == nil {
 		return "", nil, fmt.Errorf("invalid container resource")
 	}
@@ -86,7 +119,7 @@ func (pr *PodResource) getContainerResource(containerResource *v1alpha1.Containe
 	return resourceName, deviceIds, nil
 }
 
-func (pr *PodResource) getDeviceFromPod(podResources *v1alpha1.PodResources) (string, []string, error) {
+func (pr *PodResource) getDeviceFromPod(podResources *podResourcesV1.PodResources) (string, []string, error) {
 	if podResources == nil {
 		return "", nil, fmt.Errorf("invalid podReousrces")
 	}
@@ -116,36 +149,174 @@ func (pr *PodResource) getDeviceFromPod(podResources *v1alpha1.PodResources) (st
 	return resourceName, podDevice, nil
 }
 
-// GetPodResource call pod resource List interface, get pod resource info
+// GetPodResource call pod resource List interface, get the latest pod resource info and refresh the cache
 func (pr *PodResource) GetPodResource() (map[string]PodDevice, error) {
-	if err := pr.start(); err != nil {
+	if pr == nil {
+		return nil, fmt.Errorf("invalid interface receiver")
+	}
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	return pr.getPodResource()
+}
+
+// GetPodResourceCache get pod resource info cached for a short time, it may not contain the latest allocation, so
+// it must not be used by allocation and reset
+func (pr *PodResource) GetPodResourceCache() (map[string]PodDevice, error) {
+	if pr == nil {
+		return nil, fmt.Errorf("invalid interface receiver")
+	}
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	if pr.cache != nil && time.Since(pr.cacheTime) < podResourceCacheTTL {
+		return copyPodDevice(pr.cache), nil
+	}
+	return pr.getPodResource()
+}
+
+// InvalidateCache drop the cached pod resource info, it is called when device is allocated
+func (pr *PodResource) InvalidateCache() {
+	if pr == nil {
+		return
+	}
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	pr.cache = nil
+}
+
+func (pr *PodResource) getPodResource() (map[string]PodDevice, error) {
+	if err := pr.connect(); err != nil {
 		return nil, err
 	}
-	defer pr.stop()
-	return pr.assemblePodResource()
+	device, err := pr.assemblePodResource()
+	if err != nil {
+		// the connection may be broken, rebuild it in next call
+		pr.stop()
+		return nil, err
+	}
+	pr.cache = device
+	pr.cacheTime = time.Now()
+	return copyPodDevice(device), nil
 }
 
-func (pr *PodResource) assemblePodResource() (map[string]PodDevice, error) {
+// GetAllocatableDevices call pod resource GetAllocatableResources interface, get allocatable devices of each
+// resource name, only supported by v1 api
+func (pr *PodResource) GetAllocatableDevices() (map[string][]string, error) {
 	if pr == nil {
 		return nil, fmt.Errorf("invalid interface receiver")
 	}
-	if pr.conn == nil || pr.client == nil {
-		return nil, fmt.Errorf("client not init")
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	if err := pr.connect(); err != nil {
+		return nil, err
+	}
+	if pr.useV1alpha1 {
+		return nil, fmt.Errorf("kubelet pod resource v1alpha1 api not support get allocatable resources")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	resp, err := pr.client.GetAllocatableResources(ctx, &podResourcesV1.AllocatableResourcesRequest{})
+	if err != nil {
+		if status.Code(err) != codes.Unimplemented {
+			pr.stop()
+		}
+		return nil, fmt.Errorf("get allocatable resources failed, err: %v", err)
+	}
+	if resp == nil || len(resp.Devices) > common.MaxDevicesNum*common.MaxAICoreNum {
+		return nil, fmt.Errorf("invalid allocatable resources response")
 	}
+	devices := make(map[string][]string, len(resp.Devices))
+	for _, containerDevice := range resp.Devices {
+		if containerDevice == nil {
+			continue
+		}
+		if _, exist := common.GetAllDeviceInfoTypeList()[containerDevice.ResourceName]; !exist {
+			continue
+		}
+		devices[containerDevice.ResourceName] = append(devices[containerDevice.ResourceName],
+			containerDevice.DeviceIds...)
+	}
+	return devices, nil
+}
+
+func (pr *PodResource) listPodResources() ([]*podResourcesV1.PodResources, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
 	defer cancel()
-	resp, err := pr.client.List(ctx, &v1alpha1.ListPodResourcesRequest{})
+	if !pr.useV1alpha1 {
+		resp, err := pr.client.List(ctx, &podResourcesV1.ListPodResourcesRequest{})
+		if err == nil {
+			if resp == nil {
+				return nil, fmt.Errorf("invalid list response")
+			}
+			return resp.PodResources, nil
+		}
+		if status.Code(err) != codes.Unimplemented || pr.alphaClient == nil {
+			return nil, fmt.Errorf("list pod resource failed, err: %v", err)
+		}
+		hwlog.RunLog.Warn("kubelet not support pod resource v1 api, fall back to v1alpha1 api")
+		pr.useV1alpha1 = true
+	}
+	resp, err := pr.alphaClient.List(ctx, &v1alpha1.ListPodResourcesRequest{})
 	if err != nil {
 		return nil, fmt.Errorf("list pod resource failed, err: %v", err)
 	}
 	if resp == nil {
 		return nil, fmt.Errorf("invalid list response")
 	}
-	if len(resp.PodResources) > common.MaxPodLimit {
-		return nil, fmt.Errorf("the number of pods %d exceeds the upper limit", len(resp.PodResources))
+	return convertV1alpha1PodResources(resp.PodResources), nil
+}
+
+func convertV1alpha1PodResources(alphaPodResources []*v1alpha1.PodResources) []*podResourcesV1.PodResources {
+	podResources := make([]*podResourcesV1.PodResources, 0, len(alphaPodResources))
+	for _, alphaPod := range alphaPodResources {
+		if alphaPod == nil {
+			continue
+		}
+		pod := &podResourcesV1.PodResources{Name: alphaPod.Name, Namespace: alphaPod.Namespace}
+		for _, alphaContainer := range alphaPod.Containers {
+			if alphaContainer == nil {
+				continue
+			}
+			container := &podResourcesV1.ContainerResources{Name: alphaContainer.Name}
+			for _, alphaDevice := range alphaContainer.Devices {
+				if alphaDevice == nil {
+					continue
+				}
+				container.Devices = append(container.Devices, &podResourcesV1.ContainerDevices{
+					ResourceName: alphaDevice.ResourceName,
+					DeviceIds:    alphaDevice.DeviceIds,
+				})
+			}
+			pod.Containers = append(pod.Containers, container)
+		}
+		podResources = append(podResources, pod)
+	}
+	return podResources
+}
+
+func copyPodDevice(source map[string]PodDevice) map[string]PodDevice {
+	dest := make(map[string]PodDevice, len(source))
+	for key, value := range source {
+		dest[key] = value
+	}
+	return dest
+}
+
+func (pr *PodResource) assemblePodResource() (map[string]PodDevice, error) {
+	if pr == nil {
+		return nil, fmt.Errorf("invalid interface receiver")
+	}
+	if pr.conn == nil || pr.client == nil {
+		return nil, fmt.Errorf("client not init")
+	}
+	podResources, err := pr.listPodResources()
+	if err != nil {
+		return nil, err
+	}
+	if len(podResources) > common.MaxPodLimit {
+		return nil, fmt.Errorf("the number of pods %d exceeds the upper limit", len(podResources))
 	}
 	device := make(map[string]PodDevice, 1)
-	for _, pod := range resp.PodResources {
+	for _, pod := range podResources {
 		if pod == nil {
 			hwlog.RunLog.Warn("invalid pod")
 			continue
@@ -182,13 +353,15 @@ func (pr *PodResource) stop() {
 		}
 		pr.conn = nil
 		pr.client = nil
+		pr.alphaClient = nil
 	}
 }
 
-// IsPodMoveComplete is UnHealthy Pod remove complete
-func (pr *PodResource) IsPodMoveComplete(deviceName string, podList []v1.Pod, ps *PluginServer) bool {
+// IsPodMoveComplete is UnHealthy Pod remove complete, podDevice is the pod resource read by caller
+func (pr *PodResource) IsPodMoveComplete(deviceName string, podList []v1.Pod, ps *PluginServer,
+	podDevice map[string]PodDevice) bool {
 	hwlog.RunLog.Infof("check is pod real use chip %s move complete or not", deviceName)
-	podResourceList, err := pr.getValidPodResources(podList)
+	podResourceList, err := pr.getValidPodResources(podList, podDevice)
 	if err != nil {
 		return false
 	}
@@ -222,14 +395,13 @@ func (pr *PodResource) getKltDev(ps *PluginServer, deviceName string) string {
 	return k8sDev
 }
 
-func (pr *PodResource) getValidPodResources(podList []v1.Pod) ([]PodDevice, error) {
-	var res []PodDevice
-	podResourceList, err := pr.GetPodResource()
-	if err != nil {
-		hwlog.RunLog.Errorf("get pod resource failed, err: %v", err)
-		return nil, err
+func (pr *PodResource) getValidPodResources(podList []v1.Pod, podDevice map[string]PodDevice) ([]PodDevice, error) {
+	if podDevice == nil {
+		hwlog.RunLog.Error("pod resource is not got")
+		return nil, fmt.Errorf("pod resource is not got")
 	}
-	for podNameAndNs, podResource := range podResourceList {
+	var res []PodDevice
+	for podNameAndNs, podResource := range podDevice {
 		if pr.isValidPod(podNameAndNs, podList) {
 			res = append(res, podResource)
 		}
@@ -26,6 +26,9 @@ import (
 	"github.com/agiledragon/gomonkey/v2"
 	"github.com/smartystreets/goconvey/convey"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	podResourcesV1 "k8s.io/kubelet/pkg/apis/podresources/v1"
 	"k8s.io/kubelet/pkg/apis/podresources/v1alpha1"
 	"k8s.io/kubernetes/pkg/kubelet/apis/podresources"
 
@@ -79,8 +82,8 @@ func TestPodResourceStart2(t *testing.T) {
 	pr := NewPodResource()
 	convey.Convey("test start", t, func() {
 		convey.Convey("GetClient failed", func() {
-			mockGetClient := gomonkey.ApplyFunc(podresources.GetV1alpha1Client, func(socket string,
-				connectionTimeout time.Duration, maxMsgSize int) (v1alpha1.PodResourcesListerClient,
+			mockGetClient := gomonkey.ApplyFunc(podresources.GetV1Client, func(socket string,
+				connectionTimeout time.Duration, maxMsgSize int) (podResourcesV1.PodResourcesListerClient,
 				*grpc.ClientConn, error) {
 				return nil, nil, fmt.Errorf("err")
 			})
@@ -88,8 +91,8 @@ func TestPodResourceStart2(t *testing.T) {
 			convey.So(pr.start(), convey.ShouldNotBeNil)
 		})
 		convey.Convey("start ok", func() {
-			mockGetClient := gomonkey.ApplyFunc(podresources.GetV1alpha1Client, func(socket string,
-				connectionTimeout time.Duration, maxMsgSize int) (v1alpha1.PodResourcesListerClient,
+			mockGetClient := gomonkey.ApplyFunc(podresources.GetV1Client, func(socket string,
+				connectionTimeout time.Duration, maxMsgSize int) (podResourcesV1.PodResourcesListerClient,
 				*grpc.ClientConn, error) {
 				return nil, nil, nil
 			})
@@ -134,3 +137,73 @@ func (c *FakeClient) List(ctx context.Context, in *v1alpha1.ListPodResourcesRequ
 	out := new(v1alpha1.ListPodResourcesResponse)
 	return out, nil
 }
+
+// FakeV1Client is a fake pod resource v1 client of old kubelet which not support v1 api
+type FakeV1Client struct {
+	listTimes int
+}
+
+// List is to get pod resource
+func (c *FakeV1Client) List(ctx context.Context, in *podResourcesV1.ListPodResourcesRequest,
+	opts ...grpc.CallOption) (*podResourcesV1.ListPodResourcesResponse, error) {
+	c.listTimes++
+	return nil, status.Error(codes.Unimplemented, "unknown service")
+}
+
+// GetAllocatableResources is to get allocatable resources
+func (c *FakeV1Client) GetAllocatableResources(ctx context.Context, in *podResourcesV1.AllocatableResourcesRequest,
+	opts ...grpc.CallOption) (*podResourcesV1.AllocatableResourcesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "unknown service")
+}
+
+// TestGetPodResource for test GetPodResource fall back to v1alpha1 and cache the result
+func TestGetPodResource(t *testing.T) {
+	v1Client := &FakeV1Client{}
+	pr := &PodResource{conn: &grpc.ClientConn{}, client: v1Client, alphaClient: &FakeClient{}}
+	mockConnect := gomonkey.ApplyPrivateMethod(reflect.TypeOf(new(PodResource)), "connect",
+		func(_ *PodResource) error { return nil })
+	defer mockConnect.Reset()
+	convey.Convey("test GetPodResource", t, func() {
+		convey.Convey("fall back to v1alpha1 when v1 is not supported", func() {
+			podDevice, err := pr.GetPodResource()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(len(podDevice), convey.ShouldEqual, 0)
+			convey.So(pr.useV1alpha1, convey.ShouldBeTrue)
+			convey.So(v1Client.listTimes, convey.ShouldEqual, 1)
+		})
+		convey.Convey("result is cached only for cache reader", func() {
+			pr.useV1alpha1 = false
+			_, err := pr.GetPodResourceCache()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(v1Client.listTimes, convey.ShouldEqual, 1)
+			pr.useV1alpha1 = false
+			_, err = pr.GetPodResource()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(v1Client.listTimes, convey.ShouldEqual, 2)
+		})
+		convey.Convey("cache is invalidated", func() {
+			pr.useV1alpha1 = false
+			pr.InvalidateCache()
+			_, err := pr.GetPodResourceCache()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(v1Client.listTimes, convey.ShouldEqual, 3)
+		})
+		convey.Convey("get allocatable devices is not supported by v1alpha1", func() {
+			pr.useV1alpha1 = true
+			_, err := pr.GetAllocatableDevices()
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+	})
+}
+
+// TestConvertV1alpha1PodResources for test convertV1alpha1PodResources
+func TestConvertV1alpha1PodResources(t *testing.T) {
+	convey.Convey("test convertV1alpha1PodResources", t, func() {
+		alphaPodResources := []*v1alpha1.PodResources{nil, {Name: "pod", Namespace: "default",
+			Containers: []*v1alpha1.ContainerResources{{Name: "container", Devices: []*v1alpha1.ContainerDevices{
+				{ResourceName: common.ResourceNamePrefix + common.Ascend910, DeviceIds: []string{"Ascend910-0"}}}}}}}
+		podResources := convertV1alpha1PodResources(alphaPodResources)
+		convey.So(len(podResources), convey.ShouldEqual, 1)
+		convey.So(podResources[0].Containers[0].Devices[0].DeviceIds, convey.ShouldResemble, []string{"Ascend910-0"})
+	})
+}
@@ -0,0 +1,156 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+// retireServerCycles the number of update cycles a vanished device type advertises no device before its plugin
+// server is stopped, a device type which vanishes for a short time during driver events keeps its socket
+const retireServerCycles = 3
+
+// getServer get the plugin server of the device type
+func (hdm *HwDevManager) getServer(deviceType string) (InterfaceServer, bool) {
+	hdm.serverLock.RLock()
+	defer hdm.serverLock.RUnlock()
+	server, exist := hdm.ServerMap[deviceType]
+	return server, exist
+}
+
+// getServers get the snapshot of ServerMap, so that the plugin servers can be started or stopped without lock
+func (hdm *HwDevManager) getServers() map[string]InterfaceServer {
+	hdm.serverLock.RLock()
+	defer hdm.serverLock.RUnlock()
+	servers := make(map[string]InterfaceServer, len(hdm.ServerMap))
+	for deviceType, server := range hdm.ServerMap {
+		servers[deviceType] = server
+	}
+	return servers
+}
+
+// getServerDevices get the devices advertised by each plugin server, all chips are advertised as ai core by one
+// server in dynamic vnpu mode
+func (hdm *HwDevManager) getServerDevices() map[string][]*common.NpuDevice {
+	if !common.ParamOption.PresetVDevice {
+		return map[string][]*common.NpuDevice{common.AiCoreResourceName: hdm.allInfo.AICoreDevs}
+	}
+	return hdm.groupDevice
+}
+
+// reconcilePluginServers add plugin servers for new device types, retire the servers of vanished device types and
+// record the servers whose devices are added or removed, which are notified by notifyToK8s. The servers are notified
+// and stopped after serverLock is released, as Notify blocks until ListAndWatch receives it
+func (hdm *HwDevManager) reconcilePluginServers(lastServerDevices map[string][]*common.NpuDevice) {
+	retiringServers, stoppedServers := hdm.reconcileServerMap(lastServerDevices)
+	for deviceType, element := range retiringServers {
+		if pluginServer, ok := element.(*PluginServer); ok && !pluginServer.Notify(nil) {
+			hwlog.RunLog.Warnf("deviceType(%s) notify failed, server may not start, please check", deviceType)
+		}
+	}
+	for _, element := range stoppedServers {
+		element.SetRestartFlag(false)
+		element.Stop()
+	}
+}
+
+// reconcileServerMap change ServerMap by the devices of each device type, and return the servers which start
+// retiring and the servers which should be stopped
+func (hdm *HwDevManager) reconcileServerMap(lastServerDevices map[string][]*common.NpuDevice) (
+	map[string]InterfaceServer, map[string]InterfaceServer) {
+	serverDevices := hdm.getServerDevices()
+	hdm.serverLock.Lock()
+	defer hdm.serverLock.Unlock()
+	if hdm.retiringServers == nil {
+		hdm.retiringServers = make(map[string]int, len(hdm.ServerMap))
+	}
+	serverAdded := false
+	for deviceType, devices := range serverDevices {
+		if len(devices) == 0 {
+			continue
+		}
+		delete(hdm.retiringServers, deviceType)
+		if _, exist := hdm.ServerMap[deviceType]; !exist {
+			hwlog.RunLog.Infof("new device type %s found, add plugin server", deviceType)
+			hdm.ServerMap[deviceType] = NewPluginServer(deviceType, devices, hdm.defaultDevs,
+				hdm.getManager(deviceType))
+			serverAdded = true
+			continue
+		}
+		if isSameDeviceNames(devices, lastServerDevices[deviceType]) {
+			continue
+		}
+		hwlog.RunLog.Infof("devices of %s changed, notify plugin server", deviceType)
+		if hdm.changedServers == nil {
+			hdm.changedServers = sets.NewString()
+		}
+		hdm.changedServers.Insert(deviceType)
+	}
+	retiringServers := make(map[string]InterfaceServer, len(hdm.retiringServers))
+	stoppedServers := make(map[string]InterfaceServer, len(hdm.retiringServers))
+	for deviceType, element := range hdm.ServerMap {
+		if len(serverDevices[deviceType]) != 0 {
+			continue
+		}
+		notify, stop := hdm.retireServer(deviceType)
+		if notify {
+			retiringServers[deviceType] = element
+		}
+		if stop {
+			stoppedServers[deviceType] = element
+		}
+	}
+	if serverAdded {
+		select {
+		case hdm.serverChange <- struct{}{}:
+		default:
+		}
+	}
+	return retiringServers, stoppedServers
+}
+
+// retireServer count the cycles since the device type vanished, the caller should hold serverLock. Return whether
+// the server should advertise no device, which is at the first cycle, and whether the server should be stopped,
+// which is after retireServerCycles
+func (hdm *HwDevManager) retireServer(deviceType string) (bool, bool) {
+	hdm.retiringServers[deviceType]++
+	if hdm.retiringServers[deviceType] < retireServerCycles {
+		if hdm.retiringServers[deviceType] == 1 {
+			hwlog.RunLog.Warnf("device type %s vanished, advertise no device", deviceType)
+			return true, false
+		}
+		return false, false
+	}
+	hwlog.RunLog.Warnf("device type %s vanished for %d cycles, stop plugin server", deviceType, retireServerCycles)
+	delete(hdm.ServerMap, deviceType)
+	delete(hdm.retiringServers, deviceType)
+	return false, true
+}
+
+func isSameDeviceNames(devices, lastDevices []*common.NpuDevice) bool {
+	if len(devices) != len(lastDevices) {
+		return false
+	}
+	for index, device := range devices {
+		if device.DeviceName != lastDevices[index].DeviceName {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package server holds the implementation of registration to kubelet, k8s device plugin interface and grpc service.
+package server
+
+import (
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+const vir02DevType = "Ascend910-2c"
+
+func newReconcileDevices(devType string, names ...string) []*common.NpuDevice {
+	devices := make([]*common.NpuDevice, 0, len(names))
+	for _, name := range names {
+		devices = append(devices, &common.NpuDevice{DevType: devType, DeviceName: name})
+	}
+	return devices
+}
+
+// TestReconcilePluginServers for test reconcilePluginServers
+func TestReconcilePluginServers(t *testing.T) {
+	option := common.ParamOption
+	defer func() {
+		common.ParamOption = option
+	}()
+	convey.Convey("test reconcilePluginServers", t, func() {
+		common.ParamOption.PresetVDevice = true
+		chips := newReconcileDevices(common.Ascend910, "Ascend910-0", "Ascend910-1")
+		hdm := &HwDevManager{serverChange: make(chan struct{}, 1),
+			ServerMap:   map[string]InterfaceServer{common.Ascend910: NewPluginServer(common.Ascend910, chips, nil, nil)},
+			groupDevice: map[string][]*common.NpuDevice{common.Ascend910: chips}}
+		convey.Convey("server of new device type is added", func() {
+			last := hdm.getServerDevices()
+			hdm.groupDevice = map[string][]*common.NpuDevice{common.Ascend910: chips,
+				vir02DevType: newReconcileDevices(vir02DevType, "Ascend910-2c-100-0")}
+			hdm.reconcilePluginServers(last)
+			convey.So(len(hdm.ServerMap), convey.ShouldEqual, serverNum)
+			convey.So(hdm.ServerMap[vir02DevType].GetRestartFlag(), convey.ShouldBeTrue)
+			convey.So(len(hdm.serverChange), convey.ShouldEqual, 1)
+		})
+		convey.Convey("server of changed devices is recorded for notifyToK8s", func() {
+			last := hdm.getServerDevices()
+			hdm.groupDevice = map[string][]*common.NpuDevice{common.Ascend910: chips[:1]}
+			hdm.reconcilePluginServers(last)
+			convey.So(hdm.changedServers.List(), convey.ShouldResemble, []string{common.Ascend910})
+			convey.So(len(hdm.serverChange), convey.ShouldEqual, 0)
+		})
+		convey.Convey("server of vanished device type is retired", func() {
+			last := hdm.getServerDevices()
+			hdm.groupDevice = map[string][]*common.NpuDevice{}
+			for i := 1; i < retireServerCycles; i++ {
+				hdm.reconcilePluginServers(last)
+				convey.So(hdm.retiringServers[common.Ascend910], convey.ShouldEqual, i)
+			}
+			hdm.reconcilePluginServers(last)
+			convey.So(len(hdm.ServerMap), convey.ShouldEqual, 0)
+			convey.So(len(hdm.retiringServers), convey.ShouldEqual, 0)
+		})
+		convey.Convey("retiring server is kept when device type appears again", func() {
+			last := hdm.getServerDevices()
+			hdm.groupDevice = map[string][]*common.NpuDevice{}
+			hdm.reconcilePluginServers(last)
+			hdm.groupDevice = map[string][]*common.NpuDevice{common.Ascend910: chips[:1]}
+			hdm.reconcilePluginServers(map[string][]*common.NpuDevice{})
+			convey.So(len(hdm.ServerMap), convey.ShouldEqual, 1)
+			convey.So(len(hdm.retiringServers), convey.ShouldEqual, 0)
+			convey.So(len(hdm.serverChange), convey.ShouldEqual, 0)
+		})
+		convey.Convey("ai core server is kept in dynamic vnpu mode", func() {
+			common.ParamOption.PresetVDevice = false
+			hdm.ServerMap = map[string]InterfaceServer{common.AiCoreResourceName: NewPluginServer(
+				common.AiCoreResourceName, nil, nil, nil)}
+			hdm.allInfo.AICoreDevs = newReconcileDevices(common.AiCoreResourceName, "npu-core-0")
+			hdm.reconcilePluginServers(map[string][]*common.NpuDevice{})
+			convey.So(len(hdm.ServerMap), convey.ShouldEqual, 1)
+			convey.So(len(hdm.retiringServers), convey.ShouldEqual, 0)
+		})
+	})
+}
+
+// TestIsSameDeviceNames for test isSameDeviceNames
+func TestIsSameDeviceNames(t *testing.T) {
+	convey.Convey("test isSameDeviceNames", t, func() {
+		devices := newReconcileDevices(common.Ascend910, "Ascend910-0", "Ascend910-1")
+		convey.So(isSameDeviceNames(devices, newReconcileDevices(common.Ascend910, "Ascend910-0",
+			"Ascend910-1")), convey.ShouldBeTrue)
+		convey.So(isSameDeviceNames(devices, devices[:1]), convey.ShouldBeFalse)
+		convey.So(isSameDeviceNames(devices, newReconcileDevices(common.Ascend910, "Ascend910-0",
+			"Ascend910-2")), convey.ShouldBeFalse)
+	})
+}
@@ -33,6 +33,13 @@ import (
 
 const waitKubectlSockCreateTime = 5 * 60
 
+var (
+	// devicePluginPath the dir of device plugin sockets, replaced by the fake kubelet in end-to-end test
+	devicePluginPath = v1beta1.DevicePluginPath
+	// kubeletSocket the registration socket of kubelet, replaced by the fake kubelet in end-to-end test
+	kubeletSocket = v1beta1.KubeletSocket
+)
+
 // Start starts the gRPC server, registers the device plugin with the Kubelet
 func (ps *PluginServer) Start(socketWatcher *common.FileWatch) error {
 	if socketWatcher == nil {
@@ -81,6 +88,12 @@ func (ps *PluginServer) SetRestartFlag(flag bool) {
 	ps.restart = flag
 }
 
+// IsReady return whether the server is registered to kubelet, its ListAndWatch stream is running and the last
+// device info is sent to kubelet successfully
+func (ps *PluginServer) IsReady() bool {
+	return !ps.GetRestartFlag() && ps.isRunning.Load() && ps.lastSendStatus.Load()
+}
+
 // serve starts the gRPC server of the device plugin.
 func (ps *PluginServer) serve(socketWatcher *common.FileWatch) error {
 	netListener, err := createNetListener(socketWatcher, ps.deviceType)
@@ -109,7 +122,7 @@ func (ps *PluginServer) serve(socketWatcher *common.FileWatch) error {
 
 // register function is use to register k8s devicePlugin to kubelet.
 func (ps *PluginServer) register() error {
-	realKubeletSockPath, ok := common.VerifyPathAndPermission(v1beta1.KubeletSocket, 0)
+	realKubeletSockPath, ok := common.VerifyPathAndPermission(kubeletSocket, 0)
 	if !ok {
 		return fmt.Errorf("check kubelet socket file path failed")
 	}
@@ -147,7 +160,7 @@ func (ps *PluginServer) register() error {
 
 // need privilege
 func createNetListener(socketWatcher *common.FileWatch, deviceType string) (net.Listener, error) {
-	realSocketPath, ok := common.VerifyPathAndPermission(v1beta1.DevicePluginPath, waitKubectlSockCreateTime)
+	realSocketPath, ok := common.VerifyPathAndPermission(devicePluginPath, waitKubectlSockCreateTime)
 	if !ok {
 		hwlog.RunLog.Error("socket path verify failed!")
 		return nil, fmt.Errorf("socket path verify failed")
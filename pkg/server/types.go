@@ -16,10 +16,13 @@
 package server
 
 import (
+	"os"
 	"sync"
+	"time"
 
 	"google.golang.org/grpc"
 	"k8s.io/api/core/v1"
+	podResourcesV1 "k8s.io/kubelet/pkg/apis/podresources/v1"
 	"k8s.io/kubelet/pkg/apis/podresources/v1alpha1"
 
 	"Ascend-device-plugin/pkg/common"
@@ -32,6 +35,7 @@ type InterfaceServer interface {
 	Stop()
 	GetRestartFlag() bool
 	SetRestartFlag(bool)
+	IsReady() bool
 }
 
 // PluginServer implements the interface of DevicePluginServer; manages the registration and lifecycle of grpc server
@@ -39,6 +43,7 @@ type PluginServer struct {
 	manager              device.DevManager
 	grpcServer           *grpc.Server
 	isRunning            *common.AtomicBool
+	lastSendStatus       *common.AtomicBool
 	cachedDevices        []common.NpuDevice
 	deviceType           string
 	ascendRuntimeOptions string
@@ -49,6 +54,8 @@ type PluginServer struct {
 	stop                 chan interface{}
 	klt2RealDevMap       map[string]string
 	restart              bool
+	// chipAICore the ai core count of each chip of cachedDevices by physical id, it is protected by cachedLock
+	chipAICore map[int32]int32
 }
 
 // PodDevice define device info in pod
@@ -59,8 +66,14 @@ type PodDevice struct {
 
 // PodResource implements the get pod resource info
 type PodResource struct {
-	conn   *grpc.ClientConn
-	client v1alpha1.PodResourcesListerClient
+	conn        *grpc.ClientConn
+	client      podResourcesV1.PodResourcesListerClient
+	alphaClient v1alpha1.PodResourcesListerClient
+	useV1alpha1 bool
+	sockInfo    os.FileInfo
+	lock        sync.Mutex
+	cache       map[string]PodDevice
+	cacheTime   time.Time
 }
 
 // PodDeviceInfo define device info of pod, include kubelet allocate and real allocate device
@@ -69,3 +82,67 @@ type PodDeviceInfo struct {
 	KltDevice  []string
 	RealDevice []string
 }
+
+// CDISpec define the cdi spec of npu devices, see https://github.com/cncf-tags/container-device-interface
+type CDISpec struct {
+	CDIVersion     string            `json:"cdiVersion"`
+	Kind           string            `json:"kind"`
+	Devices        []CDIDevice       `json:"devices"`
+	ContainerEdits CDIContainerEdits `json:"containerEdits,omitempty"`
+}
+
+// CDIDevice define a device in cdi spec
+type CDIDevice struct {
+	Name           string            `json:"name"`
+	ContainerEdits CDIContainerEdits `json:"containerEdits"`
+}
+
+// CDIContainerEdits define the edits applied to container when device injected
+type CDIContainerEdits struct {
+	DeviceNodes []*CDIDeviceNode `json:"deviceNodes,omitempty"`
+}
+
+// CDIDeviceNode define a device node in cdi spec
+type CDIDeviceNode struct {
+	Path        string `json:"path"`
+	HostPath    string `json:"hostPath,omitempty"`
+	Permissions string `json:"permissions,omitempty"`
+}
+
+// KubeletCheckpoint define the content of kubelet device manager checkpoint which is used to recover allocation
+type KubeletCheckpoint struct {
+	Data KubeletCheckpointData `json:"Data"`
+}
+
+// KubeletCheckpointData define the data of kubelet device manager checkpoint
+type KubeletCheckpointData struct {
+	PodDeviceEntries  []PodDeviceEntry    `json:"PodDeviceEntries"`
+	RegisteredDevices map[string][]string `json:"RegisteredDevices"`
+}
+
+// PodDeviceEntry define the devices allocated by kubelet to a container, DeviceIDs is grouped by numa node
+type PodDeviceEntry struct {
+	PodUID        string             `json:"PodUID"`
+	ContainerName string             `json:"ContainerName"`
+	ResourceName  string             `json:"ResourceName"`
+	DeviceIDs     map[int64][]string `json:"DeviceIDs"`
+	AllocResp     []byte             `json:"AllocResp"`
+}
+
+// DebugInfo define the internal caches dumped by the debug http server, the key of HotReset and GraceTolerancePlan is
+// the chip name of device manager
+type DebugInfo struct {
+	Servers             map[string]ServerDebugInfo            `json:"servers"`
+	GroupDevice         map[string][]*common.NpuDevice        `json:"groupDevice"`
+	HotReset            map[string]*common.HotResetCacheInfo  `json:"hotReset,omitempty"`
+	GraceTolerancePlan  map[string]*common.GraceTolerancePlan `json:"graceTolerancePlan,omitempty"`
+	ManuallySeparateNPU []common.ManuallyFaultInfo            `json:"manuallySeparateNPU"`
+	FaultFrequency      map[string]common.FaultFrequencyCache `json:"faultFrequency"`
+	FaultTypeCode       common.FaultTypeCode                  `json:"faultTypeCode"`
+}
+
+// ServerDebugInfo define the caches of a PluginServer dumped by the debug http server
+type ServerDebugInfo struct {
+	CachedDevices  []common.NpuDevice `json:"cachedDevices"`
+	Klt2RealDevMap map[string]string  `json:"klt2RealDevMap"`
+}
@@ -0,0 +1,245 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package simulator implements a hardware-free devmanager.DeviceInterface driven by a scenario file
+package simulator
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+const (
+	// ActionFaultOccur raise the fault code on the chip
+	ActionFaultOccur = "faultOccur"
+	// ActionFaultRecover recover the fault code on the chip
+	ActionFaultRecover = "faultRecover"
+	// ActionFaultOnce report a fault code which recovers by itself
+	ActionFaultOnce = "faultOnce"
+	// ActionLinkDown raise the linkdown fault and make the network of the chip unhealthy
+	ActionLinkDown = "linkDown"
+	// ActionLinkUp recover the linkdown fault and make the network of the chip healthy
+	ActionLinkUp = "linkUp"
+	// ActionLinkFlap link down the chip and link up after duration seconds
+	ActionLinkFlap = "linkFlap"
+	// ActionReset reset the chip out of band, the faults are cleared and the chip boots for resetBootTime seconds
+	ActionReset = "reset"
+
+	defaultWorkMode = "SMP"
+	chipNamePrefix  = "Ascend"
+	maxScenarioSize = 1024 * 1024
+)
+
+// Scenario describes the simulated chips of the node and the timeline of events happened on them
+type Scenario struct {
+	// DevType the chip type, one of Ascend910, Ascend910B, Ascend310, Ascend310B and Ascend310P
+	DevType string `json:"devType"`
+	// WorkMode the npu work mode of 910, SMP or AMP
+	WorkMode string `json:"workMode"`
+	// ChipName the name returned by chip info, such as 910B3, default is the devType without Ascend
+	ChipName string `json:"chipName"`
+	// BoardID the board id of all chips
+	BoardID uint32 `json:"boardId"`
+	// AiCore the ai core count of each chip, default 32 for Ascend910 and 8 for others
+	AiCore float32 `json:"aiCore"`
+	// ResetBootTime the seconds a chip stays in booting after it is reset
+	ResetBootTime int64 `json:"resetBootTime"`
+	// DisableSubscribe make fault event subscription fail, so that faults are only found by polling
+	DisableSubscribe bool `json:"disableSubscribe"`
+	// Chips the simulated chips
+	Chips []ChipSpec `json:"chips"`
+	// Timeline the events, sorted by time when loaded
+	Timeline []Event `json:"timeline"`
+}
+
+// ChipSpec describes the initial state of a simulated chip
+type ChipSpec struct {
+	LogicID     int32  `json:"logicId"`
+	PhyID       int32  `json:"phyId"`
+	CardID      int32  `json:"cardId"`
+	DeviceID    int32  `json:"deviceId"`
+	ProductType string `json:"productType"`
+	// ChipName the chip name of this chip, default is the chipName of scenario, set it to simulate mixed chip families
+	ChipName string `json:"chipName"`
+	// AiCore the ai core count of this chip, default is the aiCore of scenario, set it to simulate binned chips
+	AiCore float32 `json:"aiCore"`
+	// IP the ipv4 or ipv6 address of the chip, empty means the chip has no ip
+	IP string `json:"ip"`
+	// FaultCodes the fault codes already raised when the plugin starts
+	FaultCodes []int64 `json:"faultCodes"`
+	// NetworkHealth the health code of network detection, 0 means ok
+	NetworkHealth uint32 `json:"networkHealth"`
+	// ResetFailTimes the times of reset fail before a reset succeeds
+	ResetFailTimes int `json:"resetFailTimes"`
+	// VNPUs the virtual devices already created when the plugin starts
+	VNPUs []VNPUSpec `json:"vnpus"`
+}
+
+// VNPUSpec describes a virtual device created on a chip
+type VNPUSpec struct {
+	VDevID   uint32 `json:"vdevId"`
+	Template string `json:"template"`
+}
+
+// Event is an event of timeline happened on a chip
+type Event struct {
+	// After the seconds after the simulator starts
+	After int64 `json:"after"`
+	// Action one of the Action constants
+	Action  string `json:"action"`
+	LogicID int32  `json:"logicId"`
+	// FaultCode the fault code of fault actions
+	FaultCode int64 `json:"faultCode"`
+	// Duration the seconds between link down and link up of linkFlap
+	Duration int64 `json:"duration"`
+}
+
+// LoadScenario load and check the scenario from the yaml file
+func LoadScenario(path string) (*Scenario, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat scenario file failed, %v", err)
+	}
+	if fileInfo.Size() > maxScenarioSize {
+		return nil, fmt.Errorf("scenario file is larger than %d bytes", maxScenarioSize)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read scenario file failed, %v", err)
+	}
+	return ParseScenario(data)
+}
+
+// ParseScenario parse and check the scenario in yaml or json
+func ParseScenario(data []byte) (*Scenario, error) {
+	var scenario Scenario
+	if err := yaml.UnmarshalStrict(data, &scenario); err != nil {
+		return nil, fmt.Errorf("unmarshal scenario failed, %v", err)
+	}
+	if err := scenario.check(); err != nil {
+		return nil, err
+	}
+	scenario.expandTimeline()
+	return &scenario, nil
+}
+
+func (s *Scenario) check() error {
+	switch s.DevType {
+	case common.Ascend910, common.Ascend910B, common.Ascend310, common.Ascend310B, common.Ascend310P:
+	default:
+		return fmt.Errorf("unsupported devType %s", s.DevType)
+	}
+	s.setDefault()
+	if s.AiCore < 0 || s.ResetBootTime < 0 {
+		return fmt.Errorf("aiCore and resetBootTime should not be negative")
+	}
+	if len(s.Chips) == 0 || len(s.Chips) > common.MaxDevicesNum {
+		return fmt.Errorf("the number of chips should be in [1, %d]", common.MaxDevicesNum)
+	}
+	logicIDs := make(map[int32]struct{}, len(s.Chips))
+	phyIDs := make(map[int32]struct{}, len(s.Chips))
+	for _, chip := range s.Chips {
+		if err := checkChip(chip); err != nil {
+			return err
+		}
+		if _, ok := logicIDs[chip.LogicID]; ok {
+			return fmt.Errorf("duplicate logicId %d", chip.LogicID)
+		}
+		if _, ok := phyIDs[chip.PhyID]; ok {
+			return fmt.Errorf("duplicate phyId %d", chip.PhyID)
+		}
+		logicIDs[chip.LogicID], phyIDs[chip.PhyID] = struct{}{}, struct{}{}
+	}
+	for _, event := range s.Timeline {
+		if _, ok := logicIDs[event.LogicID]; !ok {
+			return fmt.Errorf("event %s at %ds refers to unknown logicId %d", event.Action, event.After,
+				event.LogicID)
+		}
+		if err := checkEvent(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Scenario) setDefault() {
+	if s.WorkMode == "" {
+		s.WorkMode = defaultWorkMode
+	}
+	if s.ChipName == "" {
+		s.ChipName = strings.TrimPrefix(s.DevType, chipNamePrefix)
+	}
+	if s.AiCore != 0 {
+		return
+	}
+	s.AiCore = common.MinAICoreNum
+	if s.DevType == common.Ascend910 {
+		s.AiCore = common.MaxAICoreNum
+	}
+}
+
+func checkChip(chip ChipSpec) error {
+	if chip.LogicID < 0 || chip.PhyID < 0 || chip.CardID < 0 || chip.DeviceID < 0 {
+		return fmt.Errorf("ids of chip %d should not be negative", chip.LogicID)
+	}
+	if chip.AiCore < 0 {
+		return fmt.Errorf("aiCore of chip %d should not be negative", chip.LogicID)
+	}
+	if chip.IP != "" && net.ParseIP(chip.IP) == nil {
+		return fmt.Errorf("ip %s of chip %d is invalid", chip.IP, chip.LogicID)
+	}
+	return nil
+}
+
+func checkEvent(event Event) error {
+	if event.After < 0 || event.Duration < 0 {
+		return fmt.Errorf("after and duration of event %s should not be negative", event.Action)
+	}
+	switch event.Action {
+	case ActionFaultOccur, ActionFaultRecover, ActionFaultOnce:
+		if event.FaultCode <= 0 {
+			return fmt.Errorf("event %s at %ds requires faultCode", event.Action, event.After)
+		}
+	case ActionLinkDown, ActionLinkUp, ActionLinkFlap, ActionReset:
+	default:
+		return fmt.Errorf("unsupported action %s", event.Action)
+	}
+	return nil
+}
+
+// expandTimeline split each link flap into link down and link up, then sort events by time
+func (s *Scenario) expandTimeline() {
+	timeline := make([]Event, 0, len(s.Timeline))
+	for _, event := range s.Timeline {
+		if event.Action != ActionLinkFlap {
+			timeline = append(timeline, event)
+			continue
+		}
+		linkDown, linkUp := event, event
+		linkDown.Action = ActionLinkDown
+		linkUp.Action, linkUp.After = ActionLinkUp, event.After+event.Duration
+		timeline = append(timeline, linkDown, linkUp)
+	}
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].After < timeline[j].After
+	})
+	s.Timeline = timeline
+}
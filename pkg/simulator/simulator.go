@@ -0,0 +1,532 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package simulator implements a hardware-free devmanager.DeviceInterface driven by a scenario file
+package simulator
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"huawei.com/npu-exporter/v5/common-utils/hwlog"
+	"huawei.com/npu-exporter/v5/devmanager"
+	npuCommon "huawei.com/npu-exporter/v5/devmanager/common"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+const (
+	ipAddrTypeV4 = 0
+	ipAddrTypeV6 = 1
+	// bootStarting the boot status of a chip which is in reset
+	bootStarting = 0
+	// networkLinkDown the network health code of a chip whose link is down
+	networkLinkDown = uint32(1)
+	// vDevIDBase the id of virtual device created on chip starts from it
+	vDevIDBase = 100
+	// invalidID the id returned with error
+	invalidID      = -1
+	templatePrefix = "vir"
+	simChipType    = "Ascend"
+	simChipVer     = "V1"
+)
+
+// Simulator is a fake devmanager.DeviceInterface which keeps the chips of scenario in memory, the methods not
+// used by device plugin are served by the embedded DeviceManagerMock
+type Simulator struct {
+	devmanager.DeviceManagerMock
+	scenario    *Scenario
+	lock        sync.Mutex
+	chips       map[int32]*chipState
+	nextVDevID  uint32
+	faultFunc   func(npuCommon.DevFaultInfo)
+	subscribed  bool
+	stopCh      chan struct{}
+	stopOnce    sync.Once
+	startOnce   sync.Once
+	timeNowFunc func() time.Time
+}
+
+type chipState struct {
+	spec           ChipSpec
+	faultCodes     []int64
+	networkHealth  uint32
+	bootFinishTime time.Time
+	resetFailTimes int
+	vDevs          []npuCommon.CgoVDevQueryStru
+}
+
+// NewSimulator create the simulator of chips in scenario
+func NewSimulator(scenario *Scenario) (*Simulator, error) {
+	sim := &Simulator{
+		scenario:    scenario,
+		chips:       make(map[int32]*chipState, len(scenario.Chips)),
+		nextVDevID:  vDevIDBase,
+		stopCh:      make(chan struct{}),
+		timeNowFunc: time.Now,
+	}
+	for _, spec := range scenario.Chips {
+		chip := &chipState{
+			spec:           spec,
+			faultCodes:     append([]int64{}, spec.FaultCodes...),
+			networkHealth:  spec.NetworkHealth,
+			resetFailTimes: spec.ResetFailTimes,
+		}
+		for _, vnpu := range spec.VNPUs {
+			if err := sim.addVDev(chip, vnpu.VDevID, vnpu.Template); err != nil {
+				return nil, err
+			}
+		}
+		sim.chips[spec.LogicID] = chip
+	}
+	return sim, nil
+}
+
+// Start play the timeline of scenario in background until ShutDown is called
+func (s *Simulator) Start() {
+	s.startOnce.Do(func() {
+		hwlog.RunLog.Infof("simulator of %d %s chips starts, %d events in timeline", len(s.chips),
+			s.scenario.DevType, len(s.scenario.Timeline))
+		go s.runTimeline()
+	})
+}
+
+func (s *Simulator) runTimeline() {
+	startTime := s.timeNowFunc()
+	for _, event := range s.scenario.Timeline {
+		wait := startTime.Add(time.Duration(event.After) * time.Second).Sub(s.timeNowFunc())
+		timer := time.NewTimer(wait)
+		select {
+		case <-s.stopCh:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := s.ApplyEvent(event); err != nil {
+			hwlog.RunLog.Warnf("apply simulated event failed, %v", err)
+		}
+	}
+	hwlog.RunLog.Info("timeline of simulator is finished")
+}
+
+// ApplyEvent change the state of chip by the event and report the fault event to subscriber
+func (s *Simulator) ApplyEvent(event Event) error {
+	s.lock.Lock()
+	chip, ok := s.chips[event.LogicID]
+	if !ok {
+		s.lock.Unlock()
+		return fmt.Errorf("logicID %d not found", event.LogicID)
+	}
+	var faultInfos []npuCommon.DevFaultInfo
+	switch event.Action {
+	case ActionFaultOccur:
+		chip.addFaultCode(event.FaultCode)
+		faultInfos = append(faultInfos, s.newFaultInfo(event.LogicID, event.FaultCode, npuCommon.FaultOccur))
+	case ActionFaultRecover:
+		chip.removeFaultCode(event.FaultCode)
+		faultInfos = append(faultInfos, s.newFaultInfo(event.LogicID, event.FaultCode, npuCommon.FaultRecover))
+	case ActionFaultOnce:
+		faultInfos = append(faultInfos, s.newFaultInfo(event.LogicID, event.FaultCode, npuCommon.FaultOnce))
+	case ActionLinkDown:
+		chip.addFaultCode(common.LinkDownFaultCode)
+		chip.networkHealth = networkLinkDown
+		faultInfos = append(faultInfos, s.newFaultInfo(event.LogicID, common.LinkDownFaultCode,
+			npuCommon.FaultOccur))
+	case ActionLinkUp:
+		chip.removeFaultCode(common.LinkDownFaultCode)
+		chip.networkHealth = 0
+		faultInfos = append(faultInfos, s.newFaultInfo(event.LogicID, common.LinkDownFaultCode,
+			npuCommon.FaultRecover))
+	case ActionReset:
+		s.resetChip(chip)
+	default:
+		s.lock.Unlock()
+		return fmt.Errorf("unsupported action %s", event.Action)
+	}
+	faultFunc := s.faultFunc
+	if !s.subscribed {
+		faultFunc = nil
+	}
+	s.lock.Unlock()
+	hwlog.RunLog.Infof("simulated event %s happened on logicID %d, fault code %x", event.Action, event.LogicID,
+		event.FaultCode)
+	if faultFunc == nil {
+		return nil
+	}
+	for _, faultInfo := range faultInfos {
+		faultFunc(faultInfo)
+	}
+	return nil
+}
+
+func (s *Simulator) newFaultInfo(logicID int32, faultCode int64, assertion int8) npuCommon.DevFaultInfo {
+	return npuCommon.DevFaultInfo{
+		EventID:         faultCode,
+		LogicID:         logicID,
+		Assertion:       assertion,
+		AlarmRaisedTime: s.timeNowFunc().UnixMilli(),
+	}
+}
+
+// resetChip clear the faults of the chip and start booting, the caller should hold the lock
+func (s *Simulator) resetChip(chip *chipState) {
+	chip.faultCodes = nil
+	chip.networkHealth = 0
+	chip.bootFinishTime = s.timeNowFunc().Add(time.Duration(s.scenario.ResetBootTime) * time.Second)
+}
+
+func (c *chipState) addFaultCode(faultCode int64) {
+	for _, code := range c.faultCodes {
+		if code == faultCode {
+			return
+		}
+	}
+	c.faultCodes = append(c.faultCodes, faultCode)
+}
+
+func (c *chipState) removeFaultCode(faultCode int64) {
+	for i, code := range c.faultCodes {
+		if code == faultCode {
+			c.faultCodes = append(c.faultCodes[:i], c.faultCodes[i+1:]...)
+			return
+		}
+	}
+}
+
+// addVDev create the virtual device on chip, the caller should hold the lock or own the chip
+func (s *Simulator) addVDev(chip *chipState, vDevID uint32, template string) error {
+	aiCore, err := getTemplateAiCore(template)
+	if err != nil {
+		return err
+	}
+	var usedAiCore float32
+	for _, vDev := range chip.vDevs {
+		if vDev.VDevID == vDevID {
+			return fmt.Errorf("virtual device %d already exists on chip %d", vDevID, chip.spec.LogicID)
+		}
+		usedAiCore += vDev.QueryInfo.Computing.Aic
+	}
+	if usedAiCore+aiCore > s.getChipAiCore(chip) {
+		return fmt.Errorf("ai core of chip %d is not enough, used %v, total %v, required %v",
+			chip.spec.LogicID, usedAiCore, s.getChipAiCore(chip), aiCore)
+	}
+	if vDevID >= s.nextVDevID {
+		s.nextVDevID = vDevID + 1
+	}
+	vDev := npuCommon.CgoVDevQueryStru{VDevID: vDevID}
+	vDev.QueryInfo.Name = template
+	vDev.QueryInfo.Base.VfgID = uint32(len(chip.vDevs))
+	vDev.QueryInfo.Computing.Aic = aiCore
+	chip.vDevs = append(chip.vDevs, vDev)
+	return nil
+}
+
+// getChipAiCore get the ai core count of chip, the ai core count of chip overrides the one of scenario
+func (s *Simulator) getChipAiCore(chip *chipState) float32 {
+	if chip.spec.AiCore != 0 {
+		return chip.spec.AiCore
+	}
+	return s.scenario.AiCore
+}
+
+// getTemplateAiCore get the ai core count of template, such as 4 of vir04_3c
+func getTemplateAiCore(template string) (float32, error) {
+	if _, ok := common.GetTemplateName2DeviceTypeMap()[template]; !ok {
+		return 0, fmt.Errorf("unsupported vnpu template %s", template)
+	}
+	aiCoreStr := strings.SplitN(strings.TrimPrefix(template, templatePrefix), common.UnderLine, 2)[0]
+	aiCore, err := strconv.Atoi(aiCoreStr)
+	if err != nil {
+		return 0, fmt.Errorf("parse ai core of template %s failed, %v", template, err)
+	}
+	return float32(aiCore), nil
+}
+
+func (s *Simulator) getChip(logicID int32) (*chipState, error) {
+	chip, ok := s.chips[logicID]
+	if !ok {
+		return nil, fmt.Errorf("logicID %d not found", logicID)
+	}
+	return chip, nil
+}
+
+// GetDevType return the chip type of scenario
+func (s *Simulator) GetDevType() string {
+	return s.scenario.DevType
+}
+
+// GetNpuWorkMode return the work mode of scenario
+func (s *Simulator) GetNpuWorkMode() string {
+	return s.scenario.WorkMode
+}
+
+// GetAllProductType return the product types of all chips
+func (s *Simulator) GetAllProductType() ([]string, error) {
+	productTypeMap := make(map[string]struct{}, len(s.chips))
+	productTypes := make([]string, 0, len(s.chips))
+	for _, spec := range s.scenario.Chips {
+		if _, ok := productTypeMap[spec.ProductType]; ok || spec.ProductType == "" {
+			continue
+		}
+		productTypeMap[spec.ProductType] = struct{}{}
+		productTypes = append(productTypes, spec.ProductType)
+	}
+	return productTypes, nil
+}
+
+// GetDeviceList return the count and sorted logic ids of chips
+func (s *Simulator) GetDeviceList() (int32, []int32, error) {
+	logicIDs := make([]int32, 0, len(s.chips))
+	for logicID := range s.chips {
+		logicIDs = append(logicIDs, logicID)
+	}
+	sort.Slice(logicIDs, func(i, j int) bool {
+		return logicIDs[i] < logicIDs[j]
+	})
+	return int32(len(logicIDs)), logicIDs, nil
+}
+
+// GetPhysicIDFromLogicID return the phy id of chip
+func (s *Simulator) GetPhysicIDFromLogicID(logicID int32) (int32, error) {
+	chip, err := s.getChip(logicID)
+	if err != nil {
+		return invalidID, err
+	}
+	return chip.spec.PhyID, nil
+}
+
+// GetLogicIDFromPhysicID return the logic id of chip
+func (s *Simulator) GetLogicIDFromPhysicID(phyID int32) (int32, error) {
+	for logicID, chip := range s.chips {
+		if chip.spec.PhyID == phyID {
+			return logicID, nil
+		}
+	}
+	return invalidID, fmt.Errorf("phyID %d not found", phyID)
+}
+
+// GetCardIDDeviceID return the card id and device id of chip
+func (s *Simulator) GetCardIDDeviceID(logicID int32) (int32, int32, error) {
+	chip, err := s.getChip(logicID)
+	if err != nil {
+		return invalidID, invalidID, err
+	}
+	return chip.spec.CardID, chip.spec.DeviceID, nil
+}
+
+// GetProductType return the product type of chip
+func (s *Simulator) GetProductType(cardID, deviceID int32) (string, error) {
+	for _, chip := range s.chips {
+		if chip.spec.CardID == cardID && chip.spec.DeviceID == deviceID {
+			return chip.spec.ProductType, nil
+		}
+	}
+	return "", fmt.Errorf("cardID %d deviceID %d not found", cardID, deviceID)
+}
+
+// GetVirtualDeviceInfo return the ai core and virtual devices of chip
+func (s *Simulator) GetVirtualDeviceInfo(logicID int32) (npuCommon.VirtualDevInfo, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	chip, err := s.getChip(logicID)
+	if err != nil {
+		return npuCommon.VirtualDevInfo{}, err
+	}
+	var vDevInfo npuCommon.VirtualDevInfo
+	vDevInfo.TotalResource.VDevNum = uint32(len(chip.vDevs))
+	vDevInfo.TotalResource.Computing.Aic = s.getChipAiCore(chip)
+	vDevInfo.VDevInfo = append([]npuCommon.CgoVDevQueryStru{}, chip.vDevs...)
+	return vDevInfo, nil
+}
+
+// GetChipInfo return the chip info of scenario, the chip name of chip overrides the one of scenario
+func (s *Simulator) GetChipInfo(logicID int32) (*npuCommon.ChipInfo, error) {
+	chip, err := s.getChip(logicID)
+	if err != nil {
+		return nil, err
+	}
+	chipName := s.scenario.ChipName
+	if chip.spec.ChipName != "" {
+		chipName = chip.spec.ChipName
+	}
+	return &npuCommon.ChipInfo{Type: simChipType, Name: chipName, Version: simChipVer}, nil
+}
+
+// GetDeviceIPAddress return the ip of chip if it matches the ip type
+func (s *Simulator) GetDeviceIPAddress(logicID, ipType int32) (string, error) {
+	chip, err := s.getChip(logicID)
+	if err != nil {
+		return "", err
+	}
+	ip := net.ParseIP(chip.spec.IP)
+	if ip == nil {
+		return "", fmt.Errorf("logicID %d has no ip", logicID)
+	}
+	isV4 := ip.To4() != nil
+	if (ipType == ipAddrTypeV4 && isV4) || (ipType == ipAddrTypeV6 && !isV4) {
+		return chip.spec.IP, nil
+	}
+	return "", fmt.Errorf("logicID %d has no ip of type %d", logicID, ipType)
+}
+
+// GetDeviceErrorCode return the count of fault codes and the first fault code of chip
+func (s *Simulator) GetDeviceErrorCode(logicID int32) (int32, int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	chip, err := s.getChip(logicID)
+	if err != nil {
+		return invalidID, invalidID, err
+	}
+	if len(chip.faultCodes) == 0 {
+		return 0, 0, nil
+	}
+	return int32(len(chip.faultCodes)), chip.faultCodes[0], nil
+}
+
+// GetDeviceAllErrorCode return all fault codes of chip
+func (s *Simulator) GetDeviceAllErrorCode(logicID int32) (int32, []int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	chip, err := s.getChip(logicID)
+	if err != nil {
+		return invalidID, nil, err
+	}
+	return int32(len(chip.faultCodes)), append([]int64{}, chip.faultCodes...), nil
+}
+
+// CreateVirtualDevice create the virtual device if the ai core of chip is enough
+func (s *Simulator) CreateVirtualDevice(logicID int32, vDevInfo npuCommon.CgoCreateVDevRes) (
+	npuCommon.CgoCreateVDevOut, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	chip, err := s.getChip(logicID)
+	if err != nil {
+		return npuCommon.CgoCreateVDevOut{}, err
+	}
+	vDevID := vDevInfo.VDevID
+	if vDevID == common.DefaultIDForCreateVNPU {
+		vDevID = s.nextVDevID
+	}
+	if err = s.addVDev(chip, vDevID, vDevInfo.TemplateName); err != nil {
+		return npuCommon.CgoCreateVDevOut{}, err
+	}
+	return npuCommon.CgoCreateVDevOut{VDevID: vDevID}, nil
+}
+
+// DestroyVirtualDevice destroy the virtual device of chip
+func (s *Simulator) DestroyVirtualDevice(logicID int32, vDevID uint32) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	chip, err := s.getChip(logicID)
+	if err != nil {
+		return err
+	}
+	for i, vDev := range chip.vDevs {
+		if vDev.VDevID == vDevID {
+			chip.vDevs = append(chip.vDevs[:i], chip.vDevs[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("virtual device %d not found on logicID %d", vDevID, logicID)
+}
+
+// GetBoardInfo return the board id of scenario
+func (s *Simulator) GetBoardInfo(logicID int32) (npuCommon.BoardInfo, error) {
+	if _, err := s.getChip(logicID); err != nil {
+		return npuCommon.BoardInfo{}, err
+	}
+	return npuCommon.BoardInfo{BoardId: s.scenario.BoardID}, nil
+}
+
+// GetDeviceNetWorkHealth return the network health code of chip
+func (s *Simulator) GetDeviceNetWorkHealth(logicID int32) (uint32, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	chip, err := s.getChip(logicID)
+	if err != nil {
+		return 0, err
+	}
+	return chip.networkHealth, nil
+}
+
+// GetDeviceBootStatus return whether the chip finishes booting after reset
+func (s *Simulator) GetDeviceBootStatus(logicID int32) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	chip, err := s.getChip(logicID)
+	if err != nil {
+		return invalidID, err
+	}
+	if s.timeNowFunc().Before(chip.bootFinishTime) {
+		return bootStarting, nil
+	}
+	return common.BootStartFinish, nil
+}
+
+// SetDeviceReset reset the chips on the card, it fails resetFailTimes times first as the chip spec says
+func (s *Simulator) SetDeviceReset(cardID, deviceID int32) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var resetChips []*chipState
+	for _, chip := range s.chips {
+		if chip.spec.CardID == cardID && chip.spec.DeviceID == deviceID {
+			resetChips = append(resetChips, chip)
+		}
+	}
+	if len(resetChips) == 0 {
+		return fmt.Errorf("cardID %d deviceID %d not found", cardID, deviceID)
+	}
+	for _, chip := range resetChips {
+		if chip.resetFailTimes > 0 {
+			chip.resetFailTimes--
+			return fmt.Errorf("simulated reset failure of cardID %d deviceID %d", cardID, deviceID)
+		}
+	}
+	for _, chip := range resetChips {
+		s.resetChip(chip)
+	}
+	hwlog.RunLog.Infof("simulated chip of cardID %d deviceID %d is reset", cardID, deviceID)
+	return nil
+}
+
+// SetFaultEventCallFunc set the function called when fault event happens
+func (s *Simulator) SetFaultEventCallFunc(faultFunc func(npuCommon.DevFaultInfo)) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faultFunc = faultFunc
+	return nil
+}
+
+// SubscribeDeviceFaultEvent start reporting fault events, it fails if the scenario disables subscription
+func (s *Simulator) SubscribeDeviceFaultEvent(logicID int32) error {
+	if s.scenario.DisableSubscribe {
+		return fmt.Errorf("subscription is disabled by scenario")
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.subscribed = true
+	return nil
+}
+
+// ShutDown stop the timeline of scenario
+func (s *Simulator) ShutDown() {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+}
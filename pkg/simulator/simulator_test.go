@@ -0,0 +1,181 @@
+/* Copyright(C) 2023. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package simulator implements a hardware-free devmanager.DeviceInterface driven by a scenario file
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+	"huawei.com/npu-exporter/v5/devmanager"
+	npuCommon "huawei.com/npu-exporter/v5/devmanager/common"
+
+	"Ascend-device-plugin/pkg/common"
+)
+
+const (
+	testScenario = `
+devType: Ascend310P
+resetBootTime: 10
+chips:
+- {logicId: 0, phyId: 0, cardId: 0, deviceId: 0, productType: Atlas 300V Pro, ip: 192.168.1.10,
+   vnpus: [{vdevId: 100, template: vir04}]}
+- {logicId: 1, phyId: 1, cardId: 1, deviceId: 0, productType: Atlas 300V Pro, resetFailTimes: 1,
+   faultCodes: [0x80E01801]}
+timeline:
+- {after: 20, action: faultOccur, logicId: 0, faultCode: 0x80C98008}
+- {after: 5, action: linkFlap, logicId: 1, duration: 30}
+`
+	testFaultCode = 0x80C98008
+)
+
+// TestParseScenario for test ParseScenario
+func TestParseScenario(t *testing.T) {
+	convey.Convey("test ParseScenario", t, func() {
+		convey.Convey("valid scenario is parsed with default value", func() {
+			scenario, err := ParseScenario([]byte(testScenario))
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(scenario.ChipName, convey.ShouldEqual, "310P")
+			convey.So(scenario.AiCore, convey.ShouldEqual, common.MinAICoreNum)
+			convey.So(scenario.Chips[1].FaultCodes, convey.ShouldResemble, []int64{0x80E01801})
+			convey.So(len(scenario.Timeline), convey.ShouldEqual, 3)
+			convey.So(scenario.Timeline[0].Action, convey.ShouldEqual, ActionLinkDown)
+			convey.So(scenario.Timeline[1].Action, convey.ShouldEqual, ActionFaultOccur)
+			convey.So(scenario.Timeline[2].Action, convey.ShouldEqual, ActionLinkUp)
+			convey.So(scenario.Timeline[2].After, convey.ShouldEqual, 35)
+		})
+		convey.Convey("unsupported dev type", func() {
+			_, err := ParseScenario([]byte("devType: Ascend999\nchips: [{logicId: 0}]"))
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+		convey.Convey("duplicate phy id", func() {
+			_, err := ParseScenario([]byte("devType: Ascend910\nchips: [{logicId: 0}, {logicId: 1}]"))
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+		convey.Convey("event of unknown chip", func() {
+			_, err := ParseScenario([]byte("devType: Ascend910\nchips: [{logicId: 0}]\n" +
+				"timeline: [{action: linkDown, logicId: 1}]"))
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+		convey.Convey("unknown field", func() {
+			_, err := ParseScenario([]byte("devType: Ascend910\nchip: [{logicId: 0}]"))
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+	})
+}
+
+func newTestSimulator() *Simulator {
+	scenario, err := ParseScenario([]byte(testScenario))
+	convey.So(err, convey.ShouldBeNil)
+	sim, err := NewSimulator(scenario)
+	convey.So(err, convey.ShouldBeNil)
+	return sim
+}
+
+// TestSimulatorDevice for test the device info of Simulator
+func TestSimulatorDevice(t *testing.T) {
+	var _ devmanager.DeviceInterface = &Simulator{}
+	convey.Convey("test device info of simulator", t, func() {
+		sim := newTestSimulator()
+		num, logicIDs, err := sim.GetDeviceList()
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(num, convey.ShouldEqual, 2)
+		convey.So(logicIDs, convey.ShouldResemble, []int32{0, 1})
+		productTypes, err := sim.GetAllProductType()
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(productTypes, convey.ShouldResemble, []string{"Atlas 300V Pro"})
+		ip, err := sim.GetDeviceIPAddress(0, ipAddrTypeV4)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(ip, convey.ShouldEqual, "192.168.1.10")
+		_, err = sim.GetDeviceIPAddress(0, ipAddrTypeV6)
+		convey.So(err, convey.ShouldNotBeNil)
+		logicID, err := sim.GetLogicIDFromPhysicID(1)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(logicID, convey.ShouldEqual, 1)
+		_, err = sim.GetPhysicIDFromLogicID(2)
+		convey.So(err, convey.ShouldNotBeNil)
+	})
+}
+
+// TestSimulatorVirtualDevice for test the ai core accounting of virtual device
+func TestSimulatorVirtualDevice(t *testing.T) {
+	convey.Convey("test virtual device of simulator", t, func() {
+		sim := newTestSimulator()
+		createInfo := npuCommon.CgoCreateVDevRes{VDevID: common.DefaultIDForCreateVNPU,
+			VfgID: common.DefaultIDForCreateVNPU, TemplateName: common.Vir02}
+		out, err := sim.CreateVirtualDevice(0, createInfo)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(out.VDevID, convey.ShouldEqual, vDevIDBase+1)
+		createInfo.TemplateName = common.Vir04
+		_, err = sim.CreateVirtualDevice(0, createInfo)
+		convey.So(err, convey.ShouldNotBeNil)
+		vDevInfo, err := sim.GetVirtualDeviceInfo(0)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(vDevInfo.TotalResource.Computing.Aic, convey.ShouldEqual, common.MinAICoreNum)
+		convey.So(len(vDevInfo.VDevInfo), convey.ShouldEqual, 2)
+		convey.So(sim.DestroyVirtualDevice(0, vDevIDBase), convey.ShouldBeNil)
+		convey.So(sim.DestroyVirtualDevice(0, vDevIDBase), convey.ShouldNotBeNil)
+		_, err = sim.CreateVirtualDevice(0, createInfo)
+		convey.So(err, convey.ShouldBeNil)
+	})
+	convey.Convey("test ai core of binned chip", t, func() {
+		sim := newTestSimulator()
+		sim.chips[0].spec.AiCore = common.MinAICoreNum / 2
+		vDevInfo, err := sim.GetVirtualDeviceInfo(0)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(vDevInfo.TotalResource.Computing.Aic, convey.ShouldEqual, common.MinAICoreNum/2)
+	})
+}
+
+// TestSimulatorEvent for test ApplyEvent and reset of Simulator
+func TestSimulatorEvent(t *testing.T) {
+	convey.Convey("test event of simulator", t, func() {
+		sim := newTestSimulator()
+		now := time.Now()
+		sim.timeNowFunc = func() time.Time {
+			return now
+		}
+		var faultInfos []npuCommon.DevFaultInfo
+		convey.So(sim.SetFaultEventCallFunc(func(faultInfo npuCommon.DevFaultInfo) {
+			faultInfos = append(faultInfos, faultInfo)
+		}), convey.ShouldBeNil)
+		convey.So(sim.SubscribeDeviceFaultEvent(npuCommon.SubscribeAllDevice), convey.ShouldBeNil)
+		convey.So(sim.ApplyEvent(Event{Action: ActionFaultOccur, LogicID: 0, FaultCode: testFaultCode}),
+			convey.ShouldBeNil)
+		convey.So(sim.ApplyEvent(Event{Action: ActionLinkDown, LogicID: 0}), convey.ShouldBeNil)
+		convey.So(len(faultInfos), convey.ShouldEqual, 2)
+		convey.So(faultInfos[1].EventID, convey.ShouldEqual, common.LinkDownFaultCode)
+		_, codes, err := sim.GetDeviceAllErrorCode(0)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(codes, convey.ShouldResemble, []int64{testFaultCode, common.LinkDownFaultCode})
+		health, err := sim.GetDeviceNetWorkHealth(0)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(health, convey.ShouldEqual, networkLinkDown)
+
+		convey.So(sim.SetDeviceReset(1, 0), convey.ShouldNotBeNil)
+		convey.So(sim.SetDeviceReset(1, 0), convey.ShouldBeNil)
+		count, _, err := sim.GetDeviceAllErrorCode(1)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(count, convey.ShouldEqual, 0)
+		bootState, err := sim.GetDeviceBootStatus(1)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(bootState, convey.ShouldEqual, bootStarting)
+		now = now.Add(time.Minute)
+		bootState, err = sim.GetDeviceBootStatus(1)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(bootState, convey.ShouldEqual, common.BootStartFinish)
+	})
+}